@@ -0,0 +1,460 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// The v1alpha1 versions are converted to and from the v1beta1 storage version by the conversion webhook.
+// The shapes are the same, except that v1beta1 declares the type of a HubbleDatabase with an enum instead of the developer flag,
+// and that v1beta1 role assignments may expire.
+
+// v1alpha1 can't express when a role assignment expires, so the expiry times are kept in this annotation when a v1beta1 object is converted to v1alpha1 and restored when it is converted back
+const RoleExpiryAnnotation = "hubble.lunar.tech/role-expiry"
+
+type roleExpiries map[string]map[string]metav1.Time //the expiry times of the role assignments by user name and role name
+
+// Reads the expiry times from the annotation and removes it from the object
+func readRoleExpiries(meta *metav1.ObjectMeta) (roleExpiries, error) {
+	expiries := make(roleExpiries)
+
+	value, ok := meta.Annotations[RoleExpiryAnnotation]
+	if !ok {
+		return expiries, nil
+	}
+
+	err := json.Unmarshal([]byte(value), &expiries)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", RoleExpiryAnnotation, err)
+	}
+
+	//the annotations are shared with the object being converted, so they are copied before they are changed
+	annotations := make(map[string]string)
+	for k, v := range meta.Annotations {
+		if k != RoleExpiryAnnotation {
+			annotations[k] = v
+		}
+	}
+	meta.Annotations = annotations
+
+	return expiries, nil
+}
+
+// Writes the expiry times to the annotation, if any of the role assignments expire
+func writeRoleExpiries(meta *metav1.ObjectMeta, expiries roleExpiries) error {
+	if len(expiries) == 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(expiries)
+	if err != nil {
+		return fmt.Errorf("unable to marshal role expiry times: %w", err)
+	}
+
+	annotations := make(map[string]string)
+	for k, v := range meta.Annotations {
+		annotations[k] = v
+	}
+	annotations[RoleExpiryAnnotation] = string(value)
+	meta.Annotations = annotations
+
+	return nil
+}
+
+func convertRoleAssignmentsTo(user string, roles []string, expiries roleExpiries) []v1beta1.RoleAssignment {
+	var result []v1beta1.RoleAssignment
+	for _, role := range roles {
+		assignment := v1beta1.RoleAssignment{Name: role}
+		if expiresAt, ok := expiries[user][role]; ok {
+			assignment.ExpiresAt = &expiresAt
+		}
+		result = append(result, assignment)
+	}
+	return result
+}
+
+func convertRoleAssignmentsFrom(user string, roles []v1beta1.RoleAssignment, expiries roleExpiries) []string {
+	//the roles are required in v1alpha1, so they are never left nil
+	result := []string{}
+	for _, assignment := range roles {
+		result = append(result, assignment.Name)
+		if assignment.ExpiresAt != nil {
+			if expiries[user] == nil {
+				expiries[user] = make(map[string]metav1.Time)
+			}
+			expiries[user][assignment.Name] = *assignment.ExpiresAt
+		}
+	}
+	return result
+}
+
+func convertTableGrantsTo(grants []TableGrant) []v1beta1.TableGrant {
+	if grants == nil {
+		return nil
+	}
+	result := []v1beta1.TableGrant{}
+	for _, grant := range grants {
+		result = append(result, v1beta1.TableGrant(grant))
+	}
+	return result
+}
+
+func convertTableGrantsFrom(grants []v1beta1.TableGrant) []TableGrant {
+	if grants == nil {
+		return nil
+	}
+	result := []TableGrant{}
+	for _, grant := range grants {
+		result = append(result, TableGrant(grant))
+	}
+	return result
+}
+
+func convertDatawarehousePrivilegesTo(privileges map[string]DatawarehousePrivilege) map[string]v1beta1.DatawarehousePrivilege {
+	if privileges == nil {
+		return nil
+	}
+	result := make(map[string]v1beta1.DatawarehousePrivilege)
+	for schema, privilege := range privileges {
+		result[schema] = v1beta1.DatawarehousePrivilege(privilege)
+	}
+	return result
+}
+
+func convertDatawarehousePrivilegesFrom(privileges map[string]v1beta1.DatawarehousePrivilege) map[string]DatawarehousePrivilege {
+	if privileges == nil {
+		return nil
+	}
+	result := make(map[string]DatawarehousePrivilege)
+	for schema, privilege := range privileges {
+		result[schema] = DatawarehousePrivilege(privilege)
+	}
+	return result
+}
+
+// The roles have the same fields in both versions, but the table grants and privileges are of different types, so the roles are converted field by field
+func convertRoleTo(role Role) v1beta1.Role {
+	return v1beta1.Role{
+		Name:                    role.Name,
+		Databases:               role.Databases,
+		DevDatabases:            role.DevDatabases,
+		DatalakeGrants:          role.DatalakeGrants,
+		DatawarehouseGrants:     role.DatawarehouseGrants,
+		DatawarehousePrivileges: convertDatawarehousePrivilegesTo(role.DatawarehousePrivileges),
+		Policies:                role.Policies,
+		Extends:                 role.Extends,
+		GoogleGroups:            role.GoogleGroups,
+		SessionDuration:         role.SessionDuration,
+		TrustedPrincipals:       role.TrustedPrincipals,
+		TableGrants:             convertTableGrantsTo(role.TableGrants),
+	}
+}
+
+func convertRoleFrom(role v1beta1.Role) Role {
+	return Role{
+		Name:                    role.Name,
+		Databases:               role.Databases,
+		DevDatabases:            role.DevDatabases,
+		DatalakeGrants:          role.DatalakeGrants,
+		DatawarehouseGrants:     role.DatawarehouseGrants,
+		DatawarehousePrivileges: convertDatawarehousePrivilegesFrom(role.DatawarehousePrivileges),
+		Policies:                role.Policies,
+		Extends:                 role.Extends,
+		GoogleGroups:            role.GoogleGroups,
+		SessionDuration:         role.SessionDuration,
+		TrustedPrincipals:       role.TrustedPrincipals,
+		TableGrants:             convertTableGrantsFrom(role.TableGrants),
+	}
+}
+
+func convertConditionsTo(conditions []Condition) []v1beta1.Condition {
+	var result []v1beta1.Condition
+	for _, c := range conditions {
+		result = append(result, v1beta1.Condition{
+			Type:               v1beta1.ConditionType(c.Type),
+			Status:             v1beta1.ConditionStatus(c.Status),
+			ObservedGeneration: c.ObservedGeneration,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+	return result
+}
+
+func convertConditionsFrom(conditions []v1beta1.Condition) []Condition {
+	var result []Condition
+	for _, c := range conditions {
+		result = append(result, Condition{
+			Type:               ConditionType(c.Type),
+			Status:             ConditionStatus(c.Status),
+			ObservedGeneration: c.ObservedGeneration,
+			LastTransitionTime: c.LastTransitionTime,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		})
+	}
+	return result
+}
+
+func convertObjectStatusTo(status ObjectStatus) v1beta1.ObjectStatus {
+	return v1beta1.ObjectStatus{
+		Error:              status.Error,
+		ObservedGeneration: status.ObservedGeneration,
+		Conditions:         convertConditionsTo(status.Conditions),
+	}
+}
+
+func convertObjectStatusFrom(status v1beta1.ObjectStatus) ObjectStatus {
+	return ObjectStatus{
+		Error:              status.Error,
+		ObservedGeneration: status.ObservedGeneration,
+		Conditions:         convertConditionsFrom(status.Conditions),
+	}
+}
+
+func convertBackendReportsTo(reports []BackendReport) []v1beta1.BackendReport {
+	var result []v1beta1.BackendReport
+	for _, r := range reports {
+		result = append(result, v1beta1.BackendReport(r))
+	}
+	return result
+}
+
+func convertBackendReportsFrom(reports []v1beta1.BackendReport) []BackendReport {
+	var result []BackendReport
+	for _, r := range reports {
+		result = append(result, BackendReport(r))
+	}
+	return result
+}
+
+func (src *HubbleRbac) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.HubbleRbac)
+	dst.ObjectMeta = src.ObjectMeta
+
+	expiries, err := readRoleExpiries(&dst.ObjectMeta)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range src.Spec.Users {
+		dst.Spec.Users = append(dst.Spec.Users, v1beta1.User{
+			Name:  user.Name,
+			Email: user.Email,
+			Roles: convertRoleAssignmentsTo(user.Name, user.Roles, expiries),
+		})
+	}
+	for _, role := range src.Spec.Roles {
+		dst.Spec.Roles = append(dst.Spec.Roles, convertRoleTo(role))
+	}
+	for _, policy := range src.Spec.Policies {
+		dst.Spec.Policies = append(dst.Spec.Policies, v1beta1.PolicyReference(policy))
+	}
+	for _, database := range src.Spec.Databases {
+		dst.Spec.Databases = append(dst.Spec.Databases, v1beta1.Database(database))
+	}
+	for _, database := range src.Spec.DevDatabases {
+		dst.Spec.DevDatabases = append(dst.Spec.DevDatabases, v1beta1.DeveloperDatabase(database))
+	}
+	for _, team := range src.Spec.Teams {
+		dst.Spec.Teams = append(dst.Spec.Teams, v1beta1.Team(team))
+	}
+	for _, schema := range src.Spec.ExternalSchemas {
+		dst.Spec.ExternalSchemas = append(dst.Spec.ExternalSchemas, v1beta1.ExternalSchema(schema))
+	}
+
+	dst.Status = v1beta1.HubbleRbacStatus{
+		Error:              src.Status.Error,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         convertConditionsTo(src.Status.Conditions),
+		LastApply:          convertBackendReportsTo(src.Status.LastApply),
+		SyncStatus: v1beta1.SyncStatus{
+			LastSyncTime:       src.Status.LastSyncTime,
+			ManagedUsers:       src.Status.ManagedUsers,
+			ManagedRoles:       src.Status.ManagedRoles,
+			ManagedDatabases:   src.Status.ManagedDatabases,
+			LastDriftCheckTime: src.Status.LastDriftCheckTime,
+			Drift:              convertBackendReportsTo(src.Status.Drift),
+			GroupMembers:       src.Status.GroupMembers,
+		},
+	}
+	return nil
+}
+
+func (dst *HubbleRbac) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.HubbleRbac)
+	dst.ObjectMeta = src.ObjectMeta
+
+	//the lists are required in v1alpha1, so they are never left nil
+	dst.Spec = HubbleRbacSpec{
+		Users:        []User{},
+		Roles:        []Role{},
+		Policies:     []PolicyReference{},
+		Databases:    []Database{},
+		DevDatabases: []DeveloperDatabase{},
+	}
+	expiries := make(roleExpiries)
+	for _, user := range src.Spec.Users {
+		dst.Spec.Users = append(dst.Spec.Users, User{
+			Name:  user.Name,
+			Email: user.Email,
+			Roles: convertRoleAssignmentsFrom(user.Name, user.Roles, expiries),
+		})
+	}
+	for _, role := range src.Spec.Roles {
+		dst.Spec.Roles = append(dst.Spec.Roles, convertRoleFrom(role))
+	}
+	for _, policy := range src.Spec.Policies {
+		dst.Spec.Policies = append(dst.Spec.Policies, PolicyReference(policy))
+	}
+	for _, database := range src.Spec.Databases {
+		dst.Spec.Databases = append(dst.Spec.Databases, Database(database))
+	}
+	for _, database := range src.Spec.DevDatabases {
+		dst.Spec.DevDatabases = append(dst.Spec.DevDatabases, DeveloperDatabase(database))
+	}
+	for _, team := range src.Spec.Teams {
+		dst.Spec.Teams = append(dst.Spec.Teams, Team(team))
+	}
+	for _, schema := range src.Spec.ExternalSchemas {
+		dst.Spec.ExternalSchemas = append(dst.Spec.ExternalSchemas, ExternalSchema(schema))
+	}
+
+	dst.Status = HubbleRbacStatus{
+		Error:              src.Status.Error,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		LastSyncTime:       src.Status.LastSyncTime,
+		ManagedUsers:       src.Status.ManagedUsers,
+		ManagedRoles:       src.Status.ManagedRoles,
+		ManagedDatabases:   src.Status.ManagedDatabases,
+		Conditions:         convertConditionsFrom(src.Status.Conditions),
+		LastApply:          convertBackendReportsFrom(src.Status.LastApply),
+		LastDriftCheckTime: src.Status.LastDriftCheckTime,
+		Drift:              convertBackendReportsFrom(src.Status.Drift),
+		GroupMembers:       src.Status.GroupMembers,
+	}
+	return writeRoleExpiries(&dst.ObjectMeta, expiries)
+}
+
+func (src *HubbleUser) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.HubbleUser)
+	dst.ObjectMeta = src.ObjectMeta
+
+	expiries, err := readRoleExpiries(&dst.ObjectMeta)
+	if err != nil {
+		return err
+	}
+
+	dst.Spec = v1beta1.HubbleUserSpec{
+		Name:  src.Spec.Name,
+		Email: src.Spec.Email,
+		Roles: convertRoleAssignmentsTo(src.Spec.Name, src.Spec.Roles, expiries),
+	}
+	dst.Status = convertObjectStatusTo(src.Status)
+	return nil
+}
+
+func (dst *HubbleUser) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.HubbleUser)
+	dst.ObjectMeta = src.ObjectMeta
+
+	expiries := make(roleExpiries)
+	dst.Spec = HubbleUserSpec{
+		Name:  src.Spec.Name,
+		Email: src.Spec.Email,
+		Roles: convertRoleAssignmentsFrom(src.Spec.Name, src.Spec.Roles, expiries),
+	}
+	dst.Status = convertObjectStatusFrom(src.Status)
+	return writeRoleExpiries(&dst.ObjectMeta, expiries)
+}
+
+func (src *HubbleRole) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.HubbleRole)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.HubbleRoleSpec{
+		Name:                    src.Spec.Name,
+		Databases:               src.Spec.Databases,
+		DevDatabases:            src.Spec.DevDatabases,
+		DatalakeGrants:          src.Spec.DatalakeGrants,
+		DatawarehouseGrants:     src.Spec.DatawarehouseGrants,
+		DatawarehousePrivileges: convertDatawarehousePrivilegesTo(src.Spec.DatawarehousePrivileges),
+		Policies:                src.Spec.Policies,
+		Extends:                 src.Spec.Extends,
+		GoogleGroups:            src.Spec.GoogleGroups,
+		SessionDuration:         src.Spec.SessionDuration,
+		TrustedPrincipals:       src.Spec.TrustedPrincipals,
+		TableGrants:             convertTableGrantsTo(src.Spec.TableGrants),
+	}
+	dst.Status = convertObjectStatusTo(src.Status)
+	return nil
+}
+
+func (dst *HubbleRole) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.HubbleRole)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = HubbleRoleSpec{
+		Name:                    src.Spec.Name,
+		Databases:               src.Spec.Databases,
+		DevDatabases:            src.Spec.DevDatabases,
+		DatalakeGrants:          src.Spec.DatalakeGrants,
+		DatawarehouseGrants:     src.Spec.DatawarehouseGrants,
+		DatawarehousePrivileges: convertDatawarehousePrivilegesFrom(src.Spec.DatawarehousePrivileges),
+		Policies:                src.Spec.Policies,
+		Extends:                 src.Spec.Extends,
+		GoogleGroups:            src.Spec.GoogleGroups,
+		SessionDuration:         src.Spec.SessionDuration,
+		TrustedPrincipals:       src.Spec.TrustedPrincipals,
+		TableGrants:             convertTableGrantsFrom(src.Spec.TableGrants),
+	}
+	dst.Status = convertObjectStatusFrom(src.Status)
+	return nil
+}
+
+func (src *HubbleDatabase) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.HubbleDatabase)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.HubbleDatabaseSpec{
+		Name:     src.Spec.Name,
+		Cluster:  src.Spec.Cluster,
+		Database: src.Spec.Database,
+		Type:     v1beta1.DatabaseTypeShared,
+	}
+	if src.Spec.Developer {
+		dst.Spec.Type = v1beta1.DatabaseTypeDeveloper
+	}
+	dst.Status = convertObjectStatusTo(src.Status)
+	return nil
+}
+
+func (dst *HubbleDatabase) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.HubbleDatabase)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = HubbleDatabaseSpec{
+		Name:      src.Spec.Name,
+		Cluster:   src.Spec.Cluster,
+		Database:  src.Spec.Database,
+		Developer: src.Spec.Type == v1beta1.DatabaseTypeDeveloper,
+	}
+	dst.Status = convertObjectStatusFrom(src.Status)
+	return nil
+}
+
+func (src *HubblePolicy) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.HubblePolicy)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.HubblePolicySpec(src.Spec)
+	dst.Status = convertObjectStatusTo(src.Status)
+	return nil
+}
+
+func (dst *HubblePolicy) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.HubblePolicy)
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = HubblePolicySpec(src.Spec)
+	dst.Status = convertObjectStatusFrom(src.Status)
+	return nil
+}
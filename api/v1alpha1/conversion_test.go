@@ -0,0 +1,227 @@
+package v1alpha1
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"testing"
+	"time"
+)
+
+func meta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: "default", Generation: 3, Annotations: map[string]string{"team": "bi"}}
+}
+
+var conditions = []Condition{{Type: ConditionReady, Status: ConditionTrue, ObservedGeneration: 3, Reason: "Synced", Message: "the model has been applied to all backends"}}
+
+// Converts the object to v1beta1 and back into dst
+func roundTrip(t *testing.T, src conversion.Convertible, hub conversion.Hub, dst conversion.Convertible) {
+	assert.NoError(t, src.ConvertTo(hub))
+	assert.NoError(t, dst.ConvertFrom(hub))
+}
+
+func Test_RoundTrip(t *testing.T) {
+
+	sessionDuration := metav1.Duration{Duration: 8 * time.Hour}
+	lastSync := metav1.NewTime(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+
+	role := Role{
+		Name:                    "bianalyst",
+		Databases:               []string{"prod"},
+		DevDatabases:            []string{},
+		DatalakeGrants:          []string{"lake"},
+		DatawarehouseGrants:     []string{"bi"},
+		DatawarehousePrivileges: map[string]DatawarehousePrivilege{"bi": "write"},
+		Policies:                []string{},
+		Extends:                 []string{"analyst"},
+		GoogleGroups:            []string{"bi@lunar.app"},
+		SessionDuration:         &sessionDuration,
+		TrustedPrincipals:       []string{"arn:aws:iam::123456789012:role/ci"},
+		TableGrants:             []TableGrant{{Schema: "core", Table: "users", Columns: []string{"id"}}},
+	}
+
+	testCases := []struct {
+		name string
+		src  conversion.Convertible
+		hub  conversion.Hub
+		dst  conversion.Convertible
+	}{
+		{
+			name: "HubbleRbac",
+			src: &HubbleRbac{
+				ObjectMeta: meta("analysts"),
+				Spec: HubbleRbacSpec{
+					Users:           []User{{Name: "jwr", Email: "jwr@lunar.app", Roles: []string{"bianalyst"}}, {Name: "kni", Email: "kni@lunar.app", Roles: []string{}}},
+					Roles:           []Role{role, {Name: "analyst", Databases: []string{}, DevDatabases: []string{}, DatalakeGrants: []string{}, DatawarehouseGrants: []string{}, Policies: []string{}}},
+					Policies:        []PolicyReference{{Name: "s3", Arn: "arn:aws:iam::123456789012:policy/s3"}},
+					Databases:       []Database{{Name: "prod", Cluster: "hubble", Database: "prod"}},
+					DevDatabases:    []DeveloperDatabase{},
+					Teams:           []Team{{Name: "bi", Members: []string{"jwr"}, Roles: []string{}}},
+					ExternalSchemas: []ExternalSchema{{Name: "lake", GlueDatabase: "lake", IamRoles: []string{"arn:aws:iam::123456789012:role/redshift"}, CatalogRegion: "eu-west-1"}},
+				},
+				Status: HubbleRbacStatus{
+					ObservedGeneration: 3,
+					LastSyncTime:       &lastSync,
+					ManagedUsers:       2,
+					ManagedRoles:       2,
+					ManagedDatabases:   1,
+					Conditions:         conditions,
+					LastApply:          []BackendReport{{Backend: "Redshift", Executed: 2}},
+					GroupMembers:       map[string][]string{"bi@lunar.app": {"jwr@lunar.app"}},
+				},
+			},
+			hub: &v1beta1.HubbleRbac{},
+			dst: &HubbleRbac{},
+		},
+		{
+			name: "HubbleRbac with empty lists",
+			src: &HubbleRbac{
+				ObjectMeta: meta("empty"),
+				Spec:       HubbleRbacSpec{Users: []User{}, Roles: []Role{}, Policies: []PolicyReference{}, Databases: []Database{}, DevDatabases: []DeveloperDatabase{}},
+			},
+			hub: &v1beta1.HubbleRbac{},
+			dst: &HubbleRbac{},
+		},
+		{
+			name: "HubbleUser",
+			src:  &HubbleUser{ObjectMeta: meta("jwr"), Spec: HubbleUserSpec{Email: "jwr@lunar.app", Roles: []string{"bianalyst"}}, Status: ObjectStatus{ObservedGeneration: 3, Conditions: conditions}},
+			hub:  &v1beta1.HubbleUser{},
+			dst:  &HubbleUser{},
+		},
+		{
+			name: "HubbleUser without roles",
+			src:  &HubbleUser{ObjectMeta: meta("jwr"), Spec: HubbleUserSpec{Name: "JWR", Email: "jwr@lunar.app", Roles: []string{}}},
+			hub:  &v1beta1.HubbleUser{},
+			dst:  &HubbleUser{},
+		},
+		{
+			name: "HubbleRole",
+			src: &HubbleRole{ObjectMeta: meta("bianalyst"), Status: ObjectStatus{Error: "no such database: prod"}, Spec: HubbleRoleSpec{
+				Name:                    role.Name,
+				Databases:               role.Databases,
+				DevDatabases:            role.DevDatabases,
+				DatalakeGrants:          role.DatalakeGrants,
+				DatawarehouseGrants:     role.DatawarehouseGrants,
+				DatawarehousePrivileges: role.DatawarehousePrivileges,
+				Policies:                role.Policies,
+				Extends:                 role.Extends,
+				GoogleGroups:            role.GoogleGroups,
+				SessionDuration:         role.SessionDuration,
+				TrustedPrincipals:       role.TrustedPrincipals,
+				TableGrants:             role.TableGrants,
+			}},
+			hub: &v1beta1.HubbleRole{},
+			dst: &HubbleRole{},
+		},
+		{
+			name: "HubbleDatabase",
+			src:  &HubbleDatabase{ObjectMeta: meta("prod"), Spec: HubbleDatabaseSpec{Cluster: "hubble", Database: "prod"}, Status: ObjectStatus{Conditions: conditions}},
+			hub:  &v1beta1.HubbleDatabase{},
+			dst:  &HubbleDatabase{},
+		},
+		{
+			name: "developer HubbleDatabase",
+			src:  &HubbleDatabase{ObjectMeta: meta("dev"), Spec: HubbleDatabaseSpec{Name: "Dev", Cluster: "hubble", Developer: true}},
+			hub:  &v1beta1.HubbleDatabase{},
+			dst:  &HubbleDatabase{},
+		},
+		{
+			name: "HubblePolicy",
+			src:  &HubblePolicy{ObjectMeta: meta("s3"), Spec: HubblePolicySpec{Arn: "arn:aws:iam::123456789012:policy/s3"}, Status: ObjectStatus{Conditions: conditions}},
+			hub:  &v1beta1.HubblePolicy{},
+			dst:  &HubblePolicy{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			roundTrip(t, tc.src, tc.hub, tc.dst)
+			assert.Equal(t, tc.src, tc.dst)
+		})
+	}
+}
+
+func Test_ConvertDatabaseType(t *testing.T) {
+
+	testCases := []struct {
+		developer bool
+		expected  v1beta1.DatabaseType
+	}{
+		{developer: false, expected: v1beta1.DatabaseTypeShared},
+		{developer: true, expected: v1beta1.DatabaseTypeDeveloper},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.expected), func(t *testing.T) {
+			src := &HubbleDatabase{Spec: HubbleDatabaseSpec{Cluster: "hubble", Developer: tc.developer}}
+			hub := &v1beta1.HubbleDatabase{}
+			assert.NoError(t, src.ConvertTo(hub))
+			assert.Equal(t, tc.expected, hub.Spec.Type)
+
+			dst := &HubbleDatabase{}
+			assert.NoError(t, dst.ConvertFrom(hub))
+			assert.Equal(t, tc.developer, dst.Spec.Developer)
+		})
+	}
+}
+
+func Test_ConvertFrom_RequiredListsAreNeverNil(t *testing.T) {
+
+	dst := &HubbleRbac{}
+	assert.NoError(t, dst.ConvertFrom(&v1beta1.HubbleRbac{Spec: v1beta1.HubbleRbacSpec{
+		Users: []v1beta1.User{{Name: "jwr", Email: "jwr@lunar.app"}},
+	}}))
+
+	assert.Equal(t, []string{}, dst.Spec.Users[0].Roles)
+	assert.Equal(t, []Role{}, dst.Spec.Roles)
+	assert.Equal(t, []PolicyReference{}, dst.Spec.Policies)
+	assert.Equal(t, []Database{}, dst.Spec.Databases)
+	assert.Equal(t, []DeveloperDatabase{}, dst.Spec.DevDatabases)
+	assert.Nil(t, dst.Spec.Teams, "the optional lists are left out")
+}
+
+func Test_RoleExpiry(t *testing.T) {
+
+	expiresAt := metav1.NewTime(time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC).Local())
+	roles := []v1beta1.RoleAssignment{{Name: "bianalyst", ExpiresAt: &expiresAt}, {Name: "analyst"}}
+
+	testCases := []struct {
+		name  string
+		src   conversion.Hub
+		spoke conversion.Convertible
+		dst   conversion.Hub
+	}{
+		{
+			name:  "HubbleRbac",
+			src:   &v1beta1.HubbleRbac{ObjectMeta: meta("analysts"), Spec: v1beta1.HubbleRbacSpec{Users: []v1beta1.User{{Name: "jwr", Email: "jwr@lunar.app", Roles: roles}}}},
+			spoke: &HubbleRbac{},
+			dst:   &v1beta1.HubbleRbac{},
+		},
+		{
+			name:  "HubbleUser",
+			src:   &v1beta1.HubbleUser{ObjectMeta: meta("jwr"), Spec: v1beta1.HubbleUserSpec{Email: "jwr@lunar.app", Roles: roles}},
+			spoke: &HubbleUser{},
+			dst:   &v1beta1.HubbleUser{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, tc.spoke.ConvertFrom(tc.src))
+
+			spokeMeta := tc.spoke.(metav1.Object)
+			assert.Contains(t, spokeMeta.GetAnnotations()[RoleExpiryAnnotation], "2026-11-01T12:00:00Z", "v1alpha1 keeps the expiry times in the annotation")
+			assert.Equal(t, "bi", spokeMeta.GetAnnotations()["team"])
+
+			assert.NoError(t, tc.spoke.ConvertTo(tc.dst))
+			assert.Equal(t, tc.src, tc.dst, "the expiry times are restored and the annotation is stripped")
+		})
+	}
+}
+
+func Test_RoleExpiry_InvalidAnnotation(t *testing.T) {
+
+	src := &HubbleUser{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{RoleExpiryAnnotation: "{"}}}
+	assert.Error(t, src.ConvertTo(&v1beta1.HubbleUser{}))
+}
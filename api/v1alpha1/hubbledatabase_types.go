@@ -0,0 +1,48 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HubbleDatabaseSpec defines the desired state of HubbleDatabase
+type HubbleDatabaseSpec struct {
+	Name      string `json:"name,omitempty"` //the name of the database, defaults to the name of the CR. Set it if the database name is not a valid Kubernetes name, e.g. contains upper case letters
+	Cluster   string `json:"cluster"`
+	Database  string `json:"database,omitempty"`  //the name of the database on the cluster, not used for developer databases
+	Developer bool   `json:"developer,omitempty"` //a developer database gives every user with access a personal database on the cluster
+}
+
+// +kubebuilder:object:root=true
+
+// HubbleDatabase declares a single database, the databases are merged with the HubbleRbac CRs and the other dedicated CRs into a single desired state
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=hubbledatabases,scope=Namespaced
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.cluster"
+// +kubebuilder:printcolumn:name="Database",type="string",JSONPath=".spec.database"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type HubbleDatabase struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HubbleDatabaseSpec `json:"spec,omitempty"`
+	Status ObjectStatus       `json:"status,omitempty"`
+}
+
+func (o *HubbleDatabase) GetObjectStatus() *ObjectStatus {
+	return &o.Status
+}
+
+// +kubebuilder:object:root=true
+
+// HubbleDatabaseList contains a list of HubbleDatabase
+type HubbleDatabaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HubbleDatabase `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HubbleDatabase{}, &HubbleDatabaseList{})
+}
@@ -0,0 +1,45 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HubblePolicySpec defines the desired state of HubblePolicy
+type HubblePolicySpec struct {
+	Name string `json:"name,omitempty"` //the name of the policy, defaults to the name of the CR. Set it if the policy name is not a valid Kubernetes name, e.g. contains upper case letters
+	Arn  string `json:"arn"`
+}
+
+// +kubebuilder:object:root=true
+
+// HubblePolicy declares a single policy, the policies are merged with the HubbleRbac CRs and the other dedicated CRs into a single desired state
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=hubblepolicies,scope=Namespaced
+// +kubebuilder:printcolumn:name="Arn",type="string",JSONPath=".spec.arn"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type HubblePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HubblePolicySpec `json:"spec,omitempty"`
+	Status ObjectStatus     `json:"status,omitempty"`
+}
+
+func (o *HubblePolicy) GetObjectStatus() *ObjectStatus {
+	return &o.Status
+}
+
+// +kubebuilder:object:root=true
+
+// HubblePolicyList contains a list of HubblePolicy
+type HubblePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HubblePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HubblePolicy{}, &HubblePolicyList{})
+}
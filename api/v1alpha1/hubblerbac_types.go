@@ -9,11 +9,13 @@ import (
 
 // HubbleRbacSpec defines the desired state of HubbleRbac
 type HubbleRbacSpec struct {
-	Users        []User              `json:"users"`
-	Roles        []Role              `json:"roles"`
-	Policies     []PolicyReference   `json:"policies"`
-	Databases    []Database          `json:"databases"`
-	DevDatabases []DeveloperDatabase `json:"devDatabases"`
+	Users           []User              `json:"users"`
+	Roles           []Role              `json:"roles"`
+	Policies        []PolicyReference   `json:"policies"`
+	Databases       []Database          `json:"databases"`
+	DevDatabases    []DeveloperDatabase `json:"devDatabases"`
+	Teams           []Team              `json:"teams,omitempty"`
+	ExternalSchemas []ExternalSchema    `json:"externalSchemas,omitempty"`
 }
 
 type User struct {
@@ -22,13 +24,36 @@ type User struct {
 	Roles []string `json:"roles"`
 }
 
+// A team assigns its roles to all its members
+type Team struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"` //the names of the users in the team
+	Roles   []string `json:"roles"`
+}
+
+// The privilege level on a datawarehouse grant: read, write (insert, update and delete) or owner (also create tables)
+type DatawarehousePrivilege string
+
+// A single table, or some of its columns, granted without granting the whole schema it resides in
+type TableGrant struct {
+	Schema  string   `json:"schema"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns,omitempty"` //the granted columns, the whole table is granted if none are given
+}
+
 type Role struct {
-	Name                string   `json:"name"`
-	Databases           []string `json:"databases"`
-	DevDatabases        []string `json:"devDatabases"`
-	DatalakeGrants      []string `json:"datalakeGrants"`
-	DatawarehouseGrants []string `json:"datawarehouseGrants"`
-	Policies            []string `json:"policies"`
+	Name                    string                            `json:"name"`
+	Databases               []string                          `json:"databases"`
+	DevDatabases            []string                          `json:"devDatabases"`
+	DatalakeGrants          []string                          `json:"datalakeGrants"`
+	DatawarehouseGrants     []string                          `json:"datawarehouseGrants"`
+	DatawarehousePrivileges map[string]DatawarehousePrivilege `json:"datawarehousePrivileges,omitempty"` //the privilege level on the datawarehouse grants by schema, the grants that are not listed are read only
+	Policies                []string                          `json:"policies"`
+	Extends                 []string                          `json:"extends,omitempty"`           //the names of the roles whose grants this role inherits
+	GoogleGroups            []string                          `json:"googleGroups,omitempty"`      //the emails of the google groups whose members are assigned the role
+	SessionDuration         *metav1.Duration                  `json:"sessionDuration,omitempty"`   //the maximum duration of a login session with the role, e.g. 8h. Defaults to 4h
+	TrustedPrincipals       []string                          `json:"trustedPrincipals,omitempty"` //the ARNs of the IAM principals, e.g. CI roles, that may assume the role besides the users logging in with SAML
+	TableGrants             []TableGrant                      `json:"tableGrants,omitempty"`       //the single tables, or columns of tables, granted in schemas that are not part of the datawarehouse grants
 }
 
 type PolicyReference struct {
@@ -47,9 +72,36 @@ type Database struct {
 	Database string `json:"database"`
 }
 
+type ExternalSchema struct {
+	Name          string   `json:"name"`
+	GlueDatabase  string   `json:"glueDatabase"`
+	IamRoles      []string `json:"iamRoles,omitempty"`
+	CatalogRegion string   `json:"catalogRegion,omitempty"`
+}
+
+// BackendReport summarizes the actions applied to a single backend (Redshift, IAM or Google)
+type BackendReport struct {
+	Backend  string   `json:"backend"`
+	Planned  int      `json:"planned"`
+	Executed int      `json:"executed"`
+	Skipped  int      `json:"skipped"`
+	Failed   int      `json:"failed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
 // HubbleRbacStatus defines the observed state of HubbleRbac
 type HubbleRbacStatus struct {
-	Error string `json:"error,omitempty"`
+	Error              string              `json:"error,omitempty"`
+	ObservedGeneration int64               `json:"observedGeneration,omitempty"`
+	LastSyncTime       *metav1.Time        `json:"lastSyncTime,omitempty"` //the last time the model was successfully applied to all backends
+	ManagedUsers       int                 `json:"managedUsers"`           //the managed counts cover the merged state of all CRs
+	ManagedRoles       int                 `json:"managedRoles"`
+	ManagedDatabases   int                 `json:"managedDatabases"`
+	Conditions         []Condition         `json:"conditions,omitempty"`
+	LastApply          []BackendReport     `json:"lastApply,omitempty"`
+	LastDriftCheckTime *metav1.Time        `json:"lastDriftCheckTime,omitempty"`
+	Drift              []BackendReport     `json:"drift,omitempty"`        //the changes found by the last drift check, i.e. the changes needed to bring the backends back in line with the spec
+	GroupMembers       map[string][]string `json:"groupMembers,omitempty"` //the members of the google groups referenced by the roles when the CRs were last applied, by the email of the group. A change of the members is applied like a change of the spec
 }
 
 // +kubebuilder:object:root=true
@@ -57,6 +109,13 @@ type HubbleRbacStatus struct {
 // HubbleRbac is the Schema for the hubblerbacs API
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=hubblerbacs,scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Users",type="integer",JSONPath=".status.managedUsers"
+// +kubebuilder:printcolumn:name="Roles",type="integer",JSONPath=".status.managedRoles"
+// +kubebuilder:printcolumn:name="Databases",type="integer",JSONPath=".status.managedDatabases"
+// +kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type HubbleRbac struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -21,9 +21,46 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackendReport) DeepCopyInto(out *BackendReport) {
+	*out = *in
+	if in.Failures != nil {
+		in, out := &in.Failures, &out.Failures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackendReport.
+func (in *BackendReport) DeepCopy() *BackendReport {
+	if in == nil {
+		return nil
+	}
+	out := new(BackendReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Condition) DeepCopyInto(out *Condition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
+	if in == nil {
+		return nil
+	}
+	out := new(Condition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Database) DeepCopyInto(out *Database) {
 	*out = *in
@@ -54,13 +91,181 @@ func (in *DeveloperDatabase) DeepCopy() *DeveloperDatabase {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalSchema) DeepCopyInto(out *ExternalSchema) {
+	*out = *in
+	if in.IamRoles != nil {
+		in, out := &in.IamRoles, &out.IamRoles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalSchema.
+func (in *ExternalSchema) DeepCopy() *ExternalSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleDatabase) DeepCopyInto(out *HubbleDatabase) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleDatabase.
+func (in *HubbleDatabase) DeepCopy() *HubbleDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubbleDatabase) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleDatabaseList) DeepCopyInto(out *HubbleDatabaseList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HubbleDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleDatabaseList.
+func (in *HubbleDatabaseList) DeepCopy() *HubbleDatabaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleDatabaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubbleDatabaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleDatabaseSpec) DeepCopyInto(out *HubbleDatabaseSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleDatabaseSpec.
+func (in *HubbleDatabaseSpec) DeepCopy() *HubbleDatabaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleDatabaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubblePolicy) DeepCopyInto(out *HubblePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubblePolicy.
+func (in *HubblePolicy) DeepCopy() *HubblePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HubblePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubblePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubblePolicyList) DeepCopyInto(out *HubblePolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HubblePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubblePolicyList.
+func (in *HubblePolicyList) DeepCopy() *HubblePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(HubblePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubblePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubblePolicySpec) DeepCopyInto(out *HubblePolicySpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubblePolicySpec.
+func (in *HubblePolicySpec) DeepCopy() *HubblePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HubblePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HubbleRbac) DeepCopyInto(out *HubbleRbac) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleRbac.
@@ -145,6 +350,20 @@ func (in *HubbleRbacSpec) DeepCopyInto(out *HubbleRbacSpec) {
 		*out = make([]DeveloperDatabase, len(*in))
 		copy(*out, *in)
 	}
+	if in.Teams != nil {
+		in, out := &in.Teams, &out.Teams
+		*out = make([]Team, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExternalSchemas != nil {
+		in, out := &in.ExternalSchemas, &out.ExternalSchemas
+		*out = make([]ExternalSchema, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleRbacSpec.
@@ -160,6 +379,50 @@ func (in *HubbleRbacSpec) DeepCopy() *HubbleRbacSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HubbleRbacStatus) DeepCopyInto(out *HubbleRbacStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastApply != nil {
+		in, out := &in.LastApply, &out.LastApply
+		*out = make([]BackendReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastDriftCheckTime != nil {
+		in, out := &in.LastDriftCheckTime, &out.LastDriftCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = make([]BackendReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GroupMembers != nil {
+		in, out := &in.GroupMembers, &out.GroupMembers
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleRbacStatus.
@@ -172,6 +435,240 @@ func (in *HubbleRbacStatus) DeepCopy() *HubbleRbacStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleRole) DeepCopyInto(out *HubbleRole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleRole.
+func (in *HubbleRole) DeepCopy() *HubbleRole {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubbleRole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleRoleList) DeepCopyInto(out *HubbleRoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HubbleRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleRoleList.
+func (in *HubbleRoleList) DeepCopy() *HubbleRoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleRoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubbleRoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleRoleSpec) DeepCopyInto(out *HubbleRoleSpec) {
+	*out = *in
+	if in.Databases != nil {
+		in, out := &in.Databases, &out.Databases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DevDatabases != nil {
+		in, out := &in.DevDatabases, &out.DevDatabases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DatalakeGrants != nil {
+		in, out := &in.DatalakeGrants, &out.DatalakeGrants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DatawarehouseGrants != nil {
+		in, out := &in.DatawarehouseGrants, &out.DatawarehouseGrants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DatawarehousePrivileges != nil {
+		in, out := &in.DatawarehousePrivileges, &out.DatawarehousePrivileges
+		*out = make(map[string]DatawarehousePrivilege, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Extends != nil {
+		in, out := &in.Extends, &out.Extends
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GoogleGroups != nil {
+		in, out := &in.GoogleGroups, &out.GoogleGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SessionDuration != nil {
+		in, out := &in.SessionDuration, &out.SessionDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TrustedPrincipals != nil {
+		in, out := &in.TrustedPrincipals, &out.TrustedPrincipals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TableGrants != nil {
+		in, out := &in.TableGrants, &out.TableGrants
+		*out = make([]TableGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleRoleSpec.
+func (in *HubbleRoleSpec) DeepCopy() *HubbleRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleUser) DeepCopyInto(out *HubbleUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleUser.
+func (in *HubbleUser) DeepCopy() *HubbleUser {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubbleUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleUserList) DeepCopyInto(out *HubbleUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HubbleUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleUserList.
+func (in *HubbleUserList) DeepCopy() *HubbleUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HubbleUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HubbleUserSpec) DeepCopyInto(out *HubbleUserSpec) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HubbleUserSpec.
+func (in *HubbleUserSpec) DeepCopy() *HubbleUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HubbleUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStatus) DeepCopyInto(out *ObjectStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStatus.
+func (in *ObjectStatus) DeepCopy() *ObjectStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PolicyReference) DeepCopyInto(out *PolicyReference) {
 	*out = *in
@@ -210,11 +707,45 @@ func (in *Role) DeepCopyInto(out *Role) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.DatawarehousePrivileges != nil {
+		in, out := &in.DatawarehousePrivileges, &out.DatawarehousePrivileges
+		*out = make(map[string]DatawarehousePrivilege, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.Policies != nil {
 		in, out := &in.Policies, &out.Policies
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Extends != nil {
+		in, out := &in.Extends, &out.Extends
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GoogleGroups != nil {
+		in, out := &in.GoogleGroups, &out.GoogleGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SessionDuration != nil {
+		in, out := &in.SessionDuration, &out.SessionDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TrustedPrincipals != nil {
+		in, out := &in.TrustedPrincipals, &out.TrustedPrincipals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TableGrants != nil {
+		in, out := &in.TableGrants, &out.TableGrants
+		*out = make([]TableGrant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Role.
@@ -227,6 +758,51 @@ func (in *Role) DeepCopy() *Role {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TableGrant) DeepCopyInto(out *TableGrant) {
+	*out = *in
+	if in.Columns != nil {
+		in, out := &in.Columns, &out.Columns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TableGrant.
+func (in *TableGrant) DeepCopy() *TableGrant {
+	if in == nil {
+		return nil
+	}
+	out := new(TableGrant)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Team) DeepCopyInto(out *Team) {
+	*out = *in
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Team.
+func (in *Team) DeepCopy() *Team {
+	if in == nil {
+		return nil
+	}
+	out := new(Team)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *User) DeepCopyInto(out *User) {
 	*out = *in
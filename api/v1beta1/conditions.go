@@ -0,0 +1,91 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=Ready;RedshiftSynced;IAMSynced;GoogleSynced;Drifted
+type ConditionType string
+
+const (
+	ConditionReady          ConditionType = "Ready" //the model has been applied to all backends
+	ConditionRedshiftSynced ConditionType = "RedshiftSynced"
+	ConditionIAMSynced      ConditionType = "IAMSynced"
+	ConditionGoogleSynced   ConditionType = "GoogleSynced"
+	ConditionDrifted        ConditionType = "Drifted" //changes have been made to the backends outside of the controller
+)
+
+// +kubebuilder:validation:Enum=True;False;Unknown
+type ConditionStatus string
+
+const (
+	ConditionTrue    ConditionStatus = "True"
+	ConditionFalse   ConditionStatus = "False"
+	ConditionUnknown ConditionStatus = "Unknown"
+)
+
+// Condition follows the standard Kubernetes condition conventions
+type Condition struct {
+	Type               ConditionType   `json:"type"`
+	Status             ConditionStatus `json:"status"`
+	ObservedGeneration int64           `json:"observedGeneration,omitempty"`
+	LastTransitionTime metav1.Time     `json:"lastTransitionTime"`
+	Reason             string          `json:"reason,omitempty"`
+	Message            string          `json:"message,omitempty"`
+}
+
+// ObjectStatus is the status of the dedicated HubbleUser, HubbleRole, HubbleDatabase and HubblePolicy CRs
+type ObjectStatus struct {
+	Error              string      `json:"error,omitempty"`
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+	SyncStatus         `json:",inline"`
+}
+
+func lookupCondition(conditions []Condition, conditionType ConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// Adds or updates the condition. The transition time is only changed if the status of the condition changes.
+func setCondition(conditions *[]Condition, condition Condition) {
+	existing := lookupCondition(*conditions, condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, condition)
+		return
+	}
+
+	if existing.Status != condition.Status {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = condition.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.ObservedGeneration = condition.ObservedGeneration
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+}
+
+func (s *HubbleRbacStatus) LookupCondition(conditionType ConditionType) *Condition {
+	return lookupCondition(s.Conditions, conditionType)
+}
+
+func (s *HubbleRbacStatus) SetCondition(condition Condition) {
+	setCondition(&s.Conditions, condition)
+}
+
+func (s *ObjectStatus) LookupCondition(conditionType ConditionType) *Condition {
+	return lookupCondition(s.Conditions, conditionType)
+}
+
+func (s *ObjectStatus) SetCondition(condition Condition) {
+	setCondition(&s.Conditions, condition)
+}
@@ -0,0 +1,25 @@
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// v1beta1 is the storage version, the older versions are converted to and from it by the conversion webhook
+
+func (*HubbleRbac) Hub()     {}
+func (*HubbleUser) Hub()     {}
+func (*HubbleRole) Hub()     {}
+func (*HubbleDatabase) Hub() {}
+func (*HubblePolicy) Hub()   {}
+
+// Registers the conversion webhook for all the kinds
+func SetupWebhookWithManager(mgr ctrl.Manager) error {
+	for _, object := range []runtime.Object{&HubbleRbac{}, &HubbleUser{}, &HubbleRole{}, &HubbleDatabase{}, &HubblePolicy{}} {
+		err := ctrl.NewWebhookManagedBy(mgr).For(object).Complete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseType is the kind of database declared by a HubbleDatabase
+// +kubebuilder:validation:Enum=Shared;Developer
+type DatabaseType string
+
+const (
+	DatabaseTypeShared    DatabaseType = "Shared"    //an existing database on the cluster that the users with access share
+	DatabaseTypeDeveloper DatabaseType = "Developer" //every user with access gets a personal database on the cluster
+)
+
+// HubbleDatabaseSpec defines the desired state of HubbleDatabase
+type HubbleDatabaseSpec struct {
+	Name string `json:"name,omitempty"` //the name of the database, defaults to the name of the CR. Set it if the database name is not a valid Kubernetes name, e.g. contains upper case letters
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9-]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Cluster string `json:"cluster"` //the identifier of the redshift cluster
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_$]*$`
+	// +kubebuilder:validation:MaxLength=127
+	Database string       `json:"database,omitempty"` //the name of the database on the cluster, not used for developer databases
+	Type     DatabaseType `json:"type,omitempty"`     //defaults to Shared
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+
+// HubbleDatabase declares a single database, the databases are merged with the HubbleRbac CRs and the other dedicated CRs into a single desired state
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=hubbledatabases,scope=Namespaced
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.cluster"
+// +kubebuilder:printcolumn:name="Database",type="string",JSONPath=".spec.database"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type HubbleDatabase struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HubbleDatabaseSpec `json:"spec,omitempty"`
+	Status ObjectStatus       `json:"status,omitempty"`
+}
+
+func (o *HubbleDatabase) GetObjectStatus() *ObjectStatus {
+	return &o.Status
+}
+
+// +kubebuilder:object:root=true
+
+// HubbleDatabaseList contains a list of HubbleDatabase
+type HubbleDatabaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HubbleDatabase `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HubbleDatabase{}, &HubbleDatabaseList{})
+}
@@ -0,0 +1,176 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// HubbleRbacSpec defines the desired state of HubbleRbac
+type HubbleRbacSpec struct {
+	Users           []User              `json:"users,omitempty"`
+	Roles           []Role              `json:"roles,omitempty"`
+	Policies        []PolicyReference   `json:"policies,omitempty"`
+	Databases       []Database          `json:"databases,omitempty"`
+	DevDatabases    []DeveloperDatabase `json:"devDatabases,omitempty"`
+	Teams           []Team              `json:"teams,omitempty"`
+	ExternalSchemas []ExternalSchema    `json:"externalSchemas,omitempty"` //the external schemas the datalake grants of the roles reference
+}
+
+// User names and role names end up in the names of redshift users and groups (e.g. jwr_bianalyst), so they must be redshift identifiers short enough to be combined
+type User struct {
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+	// +kubebuilder:validation:Format=email
+	Email string           `json:"email"`
+	Roles []RoleAssignment `json:"roles,omitempty"`
+}
+
+// A role assigned to a user, optionally only until a given time
+type RoleAssignment struct {
+	// +kubebuilder:validation:MinLength=1
+	Name      string       `json:"name"`                //the name of the role
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"` //the time the assignment is revoked, the assignment never expires if it isn't set
+}
+
+// A team assigns its roles to all its members
+type Team struct {
+	// +kubebuilder:validation:MinLength=1
+	Name    string   `json:"name"`
+	Members []string `json:"members,omitempty"` //the names of the users in the team
+	Roles   []string `json:"roles,omitempty"`
+}
+
+// The privilege level on a datawarehouse grant: read, write (insert, update and delete) or owner (also create tables)
+// +kubebuilder:validation:Enum=read;write;owner
+type DatawarehousePrivilege string
+
+// A single table, or some of its columns, granted without granting the whole schema it resides in
+type TableGrant struct {
+	// +kubebuilder:validation:MinLength=1
+	Schema string `json:"schema"`
+	// +kubebuilder:validation:MinLength=1
+	Table   string   `json:"table"`
+	Columns []string `json:"columns,omitempty"` //the granted columns, the whole table is granted if none are given
+}
+
+type Role struct {
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name                    string                            `json:"name"`
+	Databases               []string                          `json:"databases,omitempty"`
+	DevDatabases            []string                          `json:"devDatabases,omitempty"`
+	DatalakeGrants          []string                          `json:"datalakeGrants,omitempty"`
+	DatawarehouseGrants     []string                          `json:"datawarehouseGrants,omitempty"`
+	DatawarehousePrivileges map[string]DatawarehousePrivilege `json:"datawarehousePrivileges,omitempty"` //the privilege level on the datawarehouse grants by schema, the grants that are not listed are read only
+	Policies                []string                          `json:"policies,omitempty"`
+	Extends                 []string                          `json:"extends,omitempty"`           //the names of the roles whose grants this role inherits
+	GoogleGroups            []string                          `json:"googleGroups,omitempty"`      //the emails of the google groups whose members are assigned the role
+	SessionDuration         *metav1.Duration                  `json:"sessionDuration,omitempty"`   //the maximum duration of a login session with the role, e.g. 8h. Defaults to 4h
+	TrustedPrincipals       []string                          `json:"trustedPrincipals,omitempty"` //the ARNs of the IAM principals, e.g. CI roles, that may assume the role besides the users logging in with SAML
+	TableGrants             []TableGrant                      `json:"tableGrants,omitempty"`       //the single tables, or columns of tables, granted in schemas that are not part of the datawarehouse grants
+}
+
+type PolicyReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:validation:Pattern=`^arn:aws:iam::(\d{12}|aws):policy/.+$`
+	Arn string `json:"arn"`
+}
+
+type DeveloperDatabase struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9-]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Cluster string `json:"cluster"` //the identifier of the redshift cluster
+}
+
+type Database struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"` //the name the database is referenced by in the roles
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9-]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Cluster string `json:"cluster"` //the identifier of the redshift cluster
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_$]*$`
+	// +kubebuilder:validation:MaxLength=127
+	Database string `json:"database"` //the name of the database on the cluster
+}
+
+// An external schema exposes a glue database of the S3 data lake in redshift
+type ExternalSchema struct {
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=127
+	Name string `json:"name"` //the name of the schema in redshift, the datalake grants reference the schema by this name
+	// +kubebuilder:validation:MinLength=1
+	GlueDatabase  string   `json:"glueDatabase"`            //the name of the database in the AWS Glue data catalog
+	IamRoles      []string `json:"iamRoles,omitempty"`      //the ARNs of the IAM roles redshift assumes to access the catalog and the data, chained in the given order. Defaults to the redshift-datalake role of the account
+	CatalogRegion string   `json:"catalogRegion,omitempty"` //the AWS region of the data catalog, defaults to the region of the cluster
+}
+
+// BackendReport summarizes the actions applied to a single backend (Redshift, IAM or Google)
+type BackendReport struct {
+	// +kubebuilder:validation:Enum=Redshift;IAM;Google
+	Backend  string   `json:"backend"`
+	Planned  int      `json:"planned"`
+	Executed int      `json:"executed"`
+	Skipped  int      `json:"skipped"`
+	Failed   int      `json:"failed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// SyncStatus keeps track of the syncs of the merged state of all CRs and of the drift checks, the HubbleRbac CRs and the dedicated kinds all keep track of it
+type SyncStatus struct {
+	LastSyncTime       *metav1.Time        `json:"lastSyncTime,omitempty"` //the last time the model was successfully applied to all backends
+	ManagedUsers       int                 `json:"managedUsers"`           //the managed counts cover the merged state of all CRs
+	ManagedRoles       int                 `json:"managedRoles"`
+	ManagedDatabases   int                 `json:"managedDatabases"`
+	LastDriftCheckTime *metav1.Time        `json:"lastDriftCheckTime,omitempty"`
+	Drift              []BackendReport     `json:"drift,omitempty"`        //the changes found by the last drift check, i.e. the changes needed to bring the backends back in line with the spec
+	GroupMembers       map[string][]string `json:"groupMembers,omitempty"` //the members of the google groups referenced by the roles when the CRs were last applied, by the email of the group. A change of the members is applied like a change of the spec
+}
+
+// HubbleRbacStatus defines the observed state of HubbleRbac
+type HubbleRbacStatus struct {
+	Error              string          `json:"error,omitempty"`
+	ObservedGeneration int64           `json:"observedGeneration,omitempty"`
+	Conditions         []Condition     `json:"conditions,omitempty"`
+	LastApply          []BackendReport `json:"lastApply,omitempty"`
+	SyncStatus         `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+
+// HubbleRbac is the Schema for the hubblerbacs API
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=hubblerbacs,scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Users",type="integer",JSONPath=".status.managedUsers"
+// +kubebuilder:printcolumn:name="Roles",type="integer",JSONPath=".status.managedRoles"
+// +kubebuilder:printcolumn:name="Databases",type="integer",JSONPath=".status.managedDatabases"
+// +kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type HubbleRbac struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HubbleRbacSpec   `json:"spec,omitempty"`
+	Status HubbleRbacStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HubbleRbacList contains a list of HubbleRbac
+type HubbleRbacList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HubbleRbac `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HubbleRbac{}, &HubbleRbacList{})
+}
@@ -0,0 +1,57 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HubbleRoleSpec defines the desired state of HubbleRole
+type HubbleRoleSpec struct {
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name                    string                            `json:"name,omitempty"` //the name of the role, defaults to the name of the CR. Set it if the role name is not a valid Kubernetes name, e.g. contains upper case letters
+	Databases               []string                          `json:"databases,omitempty"`
+	DevDatabases            []string                          `json:"devDatabases,omitempty"`
+	DatalakeGrants          []string                          `json:"datalakeGrants,omitempty"`
+	DatawarehouseGrants     []string                          `json:"datawarehouseGrants,omitempty"`
+	DatawarehousePrivileges map[string]DatawarehousePrivilege `json:"datawarehousePrivileges,omitempty"` //the privilege level on the datawarehouse grants by schema, the grants that are not listed are read only
+	Policies                []string                          `json:"policies,omitempty"`
+	Extends                 []string                          `json:"extends,omitempty"`           //the names of the roles whose grants this role inherits
+	GoogleGroups            []string                          `json:"googleGroups,omitempty"`      //the emails of the google groups whose members are assigned the role
+	SessionDuration         *metav1.Duration                  `json:"sessionDuration,omitempty"`   //the maximum duration of a login session with the role, e.g. 8h. Defaults to 4h
+	TrustedPrincipals       []string                          `json:"trustedPrincipals,omitempty"` //the ARNs of the IAM principals, e.g. CI roles, that may assume the role besides the users logging in with SAML
+	TableGrants             []TableGrant                      `json:"tableGrants,omitempty"`       //the single tables, or columns of tables, granted in schemas that are not part of the datawarehouse grants
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+
+// HubbleRole declares a single role, the roles are merged with the HubbleRbac CRs and the other dedicated CRs into a single desired state
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=hubbleroles,scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type HubbleRole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HubbleRoleSpec `json:"spec,omitempty"`
+	Status ObjectStatus   `json:"status,omitempty"`
+}
+
+func (o *HubbleRole) GetObjectStatus() *ObjectStatus {
+	return &o.Status
+}
+
+// +kubebuilder:object:root=true
+
+// HubbleRoleList contains a list of HubbleRole
+type HubbleRoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HubbleRole `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HubbleRole{}, &HubbleRoleList{})
+}
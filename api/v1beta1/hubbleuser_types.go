@@ -0,0 +1,50 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HubbleUserSpec defines the desired state of HubbleUser
+type HubbleUserSpec struct {
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name,omitempty"` //the name of the user, defaults to the name of the CR. Set it if the user name is not a valid Kubernetes name, e.g. contains upper case letters
+	// +kubebuilder:validation:Format=email
+	Email string           `json:"email"`
+	Roles []RoleAssignment `json:"roles,omitempty"` //the roles assigned to the user
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+
+// HubbleUser declares a single user, the users are merged with the HubbleRbac CRs and the other dedicated CRs into a single desired state
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=hubbleusers,scope=Namespaced
+// +kubebuilder:printcolumn:name="Email",type="string",JSONPath=".spec.email"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].reason"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type HubbleUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HubbleUserSpec `json:"spec,omitempty"`
+	Status ObjectStatus   `json:"status,omitempty"`
+}
+
+func (o *HubbleUser) GetObjectStatus() *ObjectStatus {
+	return &o.Status
+}
+
+// +kubebuilder:object:root=true
+
+// HubbleUserList contains a list of HubbleUser
+type HubbleUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HubbleUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HubbleUser{}, &HubbleUserList{})
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lunarway/hubble-rbac-controller/controllers"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/diff"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"github.com/lunarway/hubble-rbac-controller/pkg/configuration"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const usage = `Usage:
+  hubble-rbac plan -f <manifest> [-f <manifest>...] [-against <manifest>...] [-o text|json]
+
+Shows the changes that applying the manifests would result in. A manifest may contain several
+HubbleRbac, HubbleUser, HubbleRole, HubbleDatabase and HubblePolicy CRs separated by ---.
+The CRs are merged like the controller merges all the CRs in the cluster.
+Without -against the manifest is compared with the live Redshift, IAM and Google state,
+which requires the same environment variables as the controller.
+With -against the manifests are compared with other manifests and no live state is read,
+only REDSHIFT_ROLE_BASED_ACCESS_CONTROL is used to plan redshift roles instead of groups.
+`
+
+// the -f and -against flags can be given several times
+type manifests []string
+
+func (m *manifests) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *manifests) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+// Loads the CRs of a manifest, which may contain several YAML documents with HubbleRbac, HubbleUser, HubbleRole, HubbleDatabase and HubblePolicy CRs
+func loadFragments(path string) ([]controllers.Fragment, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open manifest: %w", err)
+	}
+	defer file.Close()
+
+	var fragments []controllers.Fragment
+
+	reader := yaml.NewYAMLReader(bufio.NewReader(file))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read manifest %s: %w", path, err)
+		}
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+
+		object, err := controllers.DecodeObject(document)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode manifest %s: %w", path, err)
+		}
+
+		fragment, err := controllers.FragmentOf(object)
+		if err != nil {
+			return nil, err
+		}
+
+		//CRs without a name are told apart by the path of the manifest in the errors
+		if fragment.Name == "" {
+			fragment.Name = path
+		}
+		fragments = append(fragments, fragment)
+	}
+
+	return fragments, nil
+}
+
+func loadModel(paths []string) (hubble.Model, error) {
+
+	var fragments []controllers.Fragment
+	for _, path := range paths {
+		loaded, err := loadFragments(path)
+		if err != nil {
+			return hubble.Model{}, err
+		}
+		fragments = append(fragments, loaded...)
+	}
+
+	model, err := controllers.BuildAggregatedHubbleModel(fragments, time.Now())
+	if err != nil {
+		return hubble.Model{}, fmt.Errorf("invalid manifests %s: %w", strings.Join(paths, ","), err)
+	}
+	return model, nil
+}
+
+func planAgainstLiveState(model hubble.Model) (*report.ApplyReport, error) {
+
+	conf, err := configuration.LoadConfiguration()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load configuration: %w", err)
+	}
+
+	logger := zap.New(zap.UseDevMode(true), zap.WriteTo(os.Stderr))
+
+	applier, err := service.CreateApplier(conf, logger)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create applier: %w", err)
+	}
+
+	return applier.Apply(model, true)
+}
+
+func printText(out io.Writer, applyReport *report.ApplyReport) {
+
+	for _, backend := range report.Backends {
+		actions := applyReport.ForBackend(backend)
+
+		if len(actions) == 0 {
+			fmt.Fprintf(out, "%s: no changes\n", backend)
+			continue
+		}
+
+		fmt.Fprintf(out, "%s:\n", backend)
+		for _, action := range actions {
+			if action.Err != nil {
+				fmt.Fprintf(out, "  %s %s (%s: %v)\n", action.Type, action.Name, action.State, action.Err)
+			} else {
+				fmt.Fprintf(out, "  %s %s\n", action.Type, action.Name)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\nPlan: %d changes (Redshift %d, IAM %d, Google %d)\n",
+		len(applyReport.InState(report.Planned)),
+		applyReport.Count(report.Redshift, report.Planned),
+		applyReport.Count(report.IAM, report.Planned),
+		applyReport.Count(report.Google, report.Planned))
+}
+
+func printJSON(out io.Writer, applyReport *report.ApplyReport) error {
+	serialized, err := json.MarshalIndent(applyReport, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(serialized))
+	return err
+}
+
+func plan(args []string) error {
+
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	var manifest, against manifests
+	flags.Var(&manifest, "f", "the HubbleRbac manifest to plan, can be given several times")
+	flags.Var(&against, "against", "compare with this HubbleRbac manifest instead of the live state, can be given several times")
+	output := flags.String("o", "text", "the output format, text or json")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	_ = flags.Parse(args)
+
+	if len(manifest) == 0 {
+		flags.Usage()
+		return fmt.Errorf("a manifest must be given with -f")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("unknown output format: %s", *output)
+	}
+
+	desired, err := loadModel(manifest)
+	if err != nil {
+		return err
+	}
+
+	var applyReport *report.ApplyReport
+	var planErr error
+
+	if len(against) > 0 {
+		current, err := loadModel(against)
+		if err != nil {
+			return err
+		}
+		roleBasedAccessControl, err := configuration.LoadRoleBasedAccessControl()
+		if err != nil {
+			return fmt.Errorf("unable to load configuration: %w", err)
+		}
+		applyReport = diff.Diff(current, desired, resolver.Resolver{RoleBasedAccessControl: roleBasedAccessControl}, service.ReconcilerConfig())
+	} else {
+		applyReport, planErr = planAgainstLiveState(desired)
+		if applyReport == nil {
+			return planErr
+		}
+	}
+
+	if *output == "json" {
+		err = printJSON(os.Stdout, applyReport)
+	} else {
+		printText(os.Stdout, applyReport)
+	}
+
+	if planErr != nil {
+		return planErr
+	}
+	return err
+}
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "plan":
+		err = plan(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,484 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/validation"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A Fragment is the part of the desired state declared by a single CR, either a HubbleRbac CR or one of the dedicated HubbleUser, HubbleRole, HubbleDatabase and HubblePolicy CRs
+type Fragment struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Spec      hubblev1beta1.HubbleRbacSpec
+	single    bool              //a dedicated CR declares a single entry, so its errors point into the spec of the CR rather than into a list
+	ready     bool              //the Ready condition of the CR is true
+	deleting  bool              //the CR has been deleted and is waiting for its finalizer
+	errors    validation.Errors //errors found while mapping the CR to a fragment
+}
+
+// Identifies the CR the fragment was declared in, e.g. HubbleUser/default/jwr
+func (f *Fragment) Source() string {
+	if f.Namespace == "" {
+		return fmt.Sprintf("%s/%s", f.Kind, f.Name)
+	}
+	return fmt.Sprintf("%s/%s/%s", f.Kind, f.Namespace, f.Name)
+}
+
+// The path of the entry with the given index in the CR the fragment was declared in
+func (f *Fragment) path(kind string, index int) string {
+	if f.single {
+		return validation.Join(f.Source(), "spec")
+	}
+	return validation.Join(f.Source(), validation.Index("spec."+kind, index))
+}
+
+func isReady(status interface {
+	LookupCondition(hubblev1beta1.ConditionType) *hubblev1beta1.Condition
+}) bool {
+	ready := status.LookupCondition(hubblev1beta1.ConditionReady)
+	return ready != nil && ready.Status == hubblev1beta1.ConditionTrue
+}
+
+// the name of the entry declared by a dedicated CR defaults to the name of the CR
+func entryName(name string, object metav1.Object) string {
+	if name != "" {
+		return name
+	}
+	return object.GetName()
+}
+
+// Returns an empty CR of the given kind, e.g. HubbleUser
+func NewObject(kind string) (runtime.Object, error) {
+	switch kind {
+	case "HubbleRbac":
+		return &hubblev1beta1.HubbleRbac{}, nil
+	case "HubbleUser":
+		return &hubblev1beta1.HubbleUser{}, nil
+	case "HubbleRole":
+		return &hubblev1beta1.HubbleRole{}, nil
+	case "HubbleDatabase":
+		return &hubblev1beta1.HubbleDatabase{}, nil
+	case "HubblePolicy":
+		return &hubblev1beta1.HubblePolicy{}, nil
+	}
+	return nil, fmt.Errorf("unsupported kind: %s", kind)
+}
+
+// Maps a HubbleRbac, HubbleUser, HubbleRole, HubbleDatabase or HubblePolicy CR to the fragment of the desired state it declares
+func FragmentOf(object runtime.Object) (Fragment, error) {
+
+	meta, err := apimeta.Accessor(object)
+	if err != nil {
+		return Fragment{}, err
+	}
+
+	fragment := Fragment{
+		Namespace: meta.GetNamespace(),
+		Name:      meta.GetName(),
+		single:    true,
+		deleting:  meta.GetDeletionTimestamp() != nil,
+	}
+
+	switch o := object.(type) {
+	case *hubblev1beta1.HubbleRbac:
+		fragment.Kind = "HubbleRbac"
+		fragment.single = false
+		fragment.ready = isReady(&o.Status)
+		fragment.Spec = o.Spec
+	case *hubblev1beta1.HubbleUser:
+		fragment.Kind = "HubbleUser"
+		fragment.ready = isReady(&o.Status)
+		fragment.Spec.Users = []hubblev1beta1.User{{Name: entryName(o.Spec.Name, o), Email: o.Spec.Email, Roles: o.Spec.Roles}}
+	case *hubblev1beta1.HubbleRole:
+		fragment.Kind = "HubbleRole"
+		fragment.ready = isReady(&o.Status)
+		fragment.Spec.Roles = []hubblev1beta1.Role{{
+			Name:                    entryName(o.Spec.Name, o),
+			Databases:               o.Spec.Databases,
+			DevDatabases:            o.Spec.DevDatabases,
+			DatalakeGrants:          o.Spec.DatalakeGrants,
+			DatawarehouseGrants:     o.Spec.DatawarehouseGrants,
+			DatawarehousePrivileges: o.Spec.DatawarehousePrivileges,
+			Policies:                o.Spec.Policies,
+			Extends:                 o.Spec.Extends,
+			GoogleGroups:            o.Spec.GoogleGroups,
+			SessionDuration:         o.Spec.SessionDuration,
+			TrustedPrincipals:       o.Spec.TrustedPrincipals,
+			TableGrants:             o.Spec.TableGrants,
+		}}
+	case *hubblev1beta1.HubbleDatabase:
+		fragment.Kind = "HubbleDatabase"
+		fragment.ready = isReady(&o.Status)
+		name := entryName(o.Spec.Name, o)
+		path := validation.Join(fragment.Source(), "spec.database")
+		if o.Spec.Type == hubblev1beta1.DatabaseTypeDeveloper {
+			if o.Spec.Database != "" {
+				fragment.errors.Add(path, "a developer database can't have a database name")
+			}
+			fragment.Spec.DevDatabases = []hubblev1beta1.DeveloperDatabase{{Name: name, Cluster: o.Spec.Cluster}}
+		} else {
+			if o.Spec.Database == "" {
+				fragment.errors.Add(path, "the database name is required")
+			}
+			fragment.Spec.Databases = []hubblev1beta1.Database{{Name: name, Cluster: o.Spec.Cluster, Database: o.Spec.Database}}
+		}
+	case *hubblev1beta1.HubblePolicy:
+		fragment.Kind = "HubblePolicy"
+		fragment.ready = isReady(&o.Status)
+		fragment.Spec.Policies = []hubblev1beta1.PolicyReference{{Name: entryName(o.Spec.Name, o), Arn: o.Spec.Arn}}
+	default:
+		return fragment, fmt.Errorf("unsupported object: %T", object)
+	}
+
+	return fragment, nil
+}
+
+// All the CRs in the cluster are merged into a single spec, so teams can own their own fragments.
+// An entry (e.g. a role) may be declared in several CRs as long as the declarations are identical, users declared in several CRs get the union of their roles.
+type aggregate struct {
+	spec    hubblev1beta1.HubbleRbacSpec
+	origins map[string]string //maps the path of a merged entry, e.g. spec.roles[2], to the path of the entry in the CR it came from
+	errors  validation.Errors
+}
+
+type mergedEntry struct {
+	index  int
+	source string //the CR the entry was first declared in
+}
+
+func sortedCopy(values []string) []string {
+	result := append([]string{}, values...)
+	sort.Strings(result)
+	return result
+}
+
+func sameElements(a []string, b []string) bool {
+	return strings.Join(sortedCopy(a), ",") == strings.Join(sortedCopy(b), ",")
+}
+
+func sameRole(a hubblev1beta1.Role, b hubblev1beta1.Role) bool {
+	return sameElements(a.Databases, b.Databases) &&
+		sameElements(a.DevDatabases, b.DevDatabases) &&
+		sameElements(a.DatalakeGrants, b.DatalakeGrants) &&
+		sameElements(a.DatawarehouseGrants, b.DatawarehouseGrants) &&
+		sameElements(privilegeKeys(a.DatawarehousePrivileges), privilegeKeys(b.DatawarehousePrivileges)) &&
+		sameElements(a.Policies, b.Policies) &&
+		sameElements(a.Extends, b.Extends) &&
+		sameElements(a.GoogleGroups, b.GoogleGroups) &&
+		sessionDuration(a) == sessionDuration(b) &&
+		sameElements(a.TrustedPrincipals, b.TrustedPrincipals) &&
+		sameElements(tableGrantKeys(a.TableGrants), tableGrantKeys(b.TableGrants))
+}
+
+// Returns a key per schema and its privilege level
+func privilegeKeys(privileges map[string]hubblev1beta1.DatawarehousePrivilege) []string {
+	var keys []string
+	for schema, privilege := range privileges {
+		keys = append(keys, fmt.Sprintf("%s=%s", schema, privilege))
+	}
+	return keys
+}
+
+// Returns a key per table grant that identifies the table and the granted columns, regardless of their order
+func tableGrantKeys(grants []hubblev1beta1.TableGrant) []string {
+	var keys []string
+	for _, grant := range grants {
+		keys = append(keys, fmt.Sprintf("%s.%s(%s)", grant.Schema, grant.Table, strings.Join(sortedCopy(grant.Columns), ",")))
+	}
+	return keys
+}
+
+// The IAM roles of an external schema are chained in the given order, so the order matters
+func sameExternalSchema(a hubblev1beta1.ExternalSchema, b hubblev1beta1.ExternalSchema) bool {
+	return a.GlueDatabase == b.GlueDatabase &&
+		strings.Join(a.IamRoles, ",") == strings.Join(b.IamRoles, ",") &&
+		a.CatalogRegion == b.CatalogRegion
+}
+
+func sameTeam(a hubblev1beta1.Team, b hubblev1beta1.Team) bool {
+	return sameElements(a.Members, b.Members) &&
+		sameElements(a.Roles, b.Roles)
+}
+
+// Looks up an entry with the same name declared in another CR. Entries declared twice in the same CR are both kept, so they are reported as duplicates by the validation.
+func (a *aggregate) lookup(entries map[string]mergedEntry, name string, source string) (mergedEntry, bool) {
+	entry, ok := entries[name]
+	if !ok || entry.source == source {
+		return mergedEntry{}, false
+	}
+	return entry, true
+}
+
+func (a *aggregate) declare(entries map[string]mergedEntry, kind string, name string, index int, source string, originPath string) {
+	if _, ok := entries[name]; !ok {
+		entries[name] = mergedEntry{index: index, source: source}
+	}
+	a.origins[validation.Index("spec."+kind, index)] = originPath
+}
+
+func (a *aggregate) conflict(originPath string, kind string, name string, entry mergedEntry) {
+	a.errors.Add(originPath, "%s %s is also declared in %s with a different definition", kind, name, entry.source)
+}
+
+func (a *aggregate) add(fragment *Fragment, databases, devDatabases, policies, externalSchemas, roles, users, teams map[string]mergedEntry) {
+
+	source := fragment.Source()
+	a.errors = append(a.errors, fragment.errors...)
+
+	for i, database := range fragment.Spec.Databases {
+		path := fragment.path("databases", i)
+		if entry, ok := a.lookup(databases, database.Name, source); ok {
+			if a.spec.Databases[entry.index] != database {
+				a.conflict(path, "database", database.Name, entry)
+			}
+			continue
+		}
+		a.spec.Databases = append(a.spec.Databases, database)
+		a.declare(databases, "databases", database.Name, len(a.spec.Databases)-1, source, path)
+	}
+
+	for i, database := range fragment.Spec.DevDatabases {
+		path := fragment.path("devDatabases", i)
+		if entry, ok := a.lookup(devDatabases, database.Name, source); ok {
+			if a.spec.DevDatabases[entry.index] != database {
+				a.conflict(path, "developer database", database.Name, entry)
+			}
+			continue
+		}
+		a.spec.DevDatabases = append(a.spec.DevDatabases, database)
+		a.declare(devDatabases, "devDatabases", database.Name, len(a.spec.DevDatabases)-1, source, path)
+	}
+
+	for i, policy := range fragment.Spec.Policies {
+		path := fragment.path("policies", i)
+		if entry, ok := a.lookup(policies, policy.Name, source); ok {
+			if a.spec.Policies[entry.index] != policy {
+				a.conflict(path, "policy", policy.Name, entry)
+			}
+			continue
+		}
+		a.spec.Policies = append(a.spec.Policies, policy)
+		a.declare(policies, "policies", policy.Name, len(a.spec.Policies)-1, source, path)
+	}
+
+	for i, schema := range fragment.Spec.ExternalSchemas {
+		path := fragment.path("externalSchemas", i)
+		if entry, ok := a.lookup(externalSchemas, schema.Name, source); ok {
+			if !sameExternalSchema(a.spec.ExternalSchemas[entry.index], schema) {
+				a.conflict(path, "external schema", schema.Name, entry)
+			}
+			continue
+		}
+		a.spec.ExternalSchemas = append(a.spec.ExternalSchemas, schema)
+		a.declare(externalSchemas, "externalSchemas", schema.Name, len(a.spec.ExternalSchemas)-1, source, path)
+	}
+
+	for i, role := range fragment.Spec.Roles {
+		path := fragment.path("roles", i)
+		if entry, ok := a.lookup(roles, role.Name, source); ok {
+			if !sameRole(a.spec.Roles[entry.index], role) {
+				a.conflict(path, "role", role.Name, entry)
+			}
+			continue
+		}
+		a.spec.Roles = append(a.spec.Roles, role)
+		a.declare(roles, "roles", role.Name, len(a.spec.Roles)-1, source, path)
+	}
+
+	for i, user := range fragment.Spec.Users {
+		path := fragment.path("users", i)
+		if entry, ok := a.lookup(users, user.Name, source); ok {
+			merged := &a.spec.Users[entry.index]
+			if merged.Email != user.Email {
+				a.errors.Add(path+".email", "user %s is also declared in %s with the email %s", user.Name, entry.source, merged.Email)
+				continue
+			}
+			for _, role := range user.Roles {
+				merged.Roles = mergeRoleAssignment(merged.Roles, role)
+			}
+			continue
+		}
+		user.Roles = append([]hubblev1beta1.RoleAssignment{}, user.Roles...)
+		a.spec.Users = append(a.spec.Users, user)
+		a.declare(users, "users", user.Name, len(a.spec.Users)-1, source, path)
+	}
+
+	for i, team := range fragment.Spec.Teams {
+		path := fragment.path("teams", i)
+		if entry, ok := a.lookup(teams, team.Name, source); ok {
+			if !sameTeam(a.spec.Teams[entry.index], team) {
+				a.conflict(path, "team", team.Name, entry)
+			}
+			continue
+		}
+		a.spec.Teams = append(a.spec.Teams, team)
+		a.declare(teams, "teams", team.Name, len(a.spec.Teams)-1, source, path)
+	}
+}
+
+// Translates the path of a validation error in the merged spec to the path in the CR the invalid entry came from
+func (a *aggregate) translate(errors validation.Errors) validation.Errors {
+	var result validation.Errors
+	for _, err := range errors {
+		path := err.Path
+		if end := strings.Index(path, "]"); end >= 0 {
+			if origin, ok := a.origins[path[:end+1]]; ok {
+				path = origin + path[end+1:]
+			}
+		}
+		result = append(result, &validation.FieldError{Path: path, Message: err.Message})
+	}
+	return result
+}
+
+func mergeFragments(fragments []Fragment) *aggregate {
+
+	a := &aggregate{origins: make(map[string]string)}
+
+	//the fragments are merged in a stable order, so the same set of CRs always results in the same model
+	sorted := append([]Fragment{}, fragments...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Source() < sorted[j].Source()
+	})
+
+	databases := make(map[string]mergedEntry)
+	devDatabases := make(map[string]mergedEntry)
+	policies := make(map[string]mergedEntry)
+	externalSchemas := make(map[string]mergedEntry)
+	roles := make(map[string]mergedEntry)
+	users := make(map[string]mergedEntry)
+	teams := make(map[string]mergedEntry)
+
+	for i := range sorted {
+		a.add(&sorted[i], databases, devDatabases, policies, externalSchemas, roles, users, teams)
+	}
+
+	return a
+}
+
+// Merges the fragments of all the CRs into a single hubble model. If the CRs conflict or are invalid, all the errors found are returned with the paths pointing into the CRs.
+func BuildAggregatedHubbleModel(fragments []Fragment, now time.Time) (hubble.Model, error) {
+
+	a := mergeFragments(fragments)
+
+	errors := a.errors
+	errors = append(errors, a.translate(validateSpec(&a.spec))...)
+	if len(errors) > 0 {
+		return hubble.Model{}, errors
+	}
+
+	//the merged spec has just been validated, so it isn't validated again when it is mapped
+	return buildModel(&hubblev1beta1.HubbleRbac{Spec: a.spec}, now)
+}
+
+// Replaces the fragment of the same CR in the list with the given one, or adds it if it isn't in the list yet. The list may be served from a cache that hasn't seen the latest version of the CR yet.
+func replaceFragment(fragments []Fragment, fragment Fragment) []Fragment {
+	result := removeFragment(fragments, fragment)
+	return append(result, fragment)
+}
+
+func removeFragment(fragments []Fragment, fragment Fragment) []Fragment {
+	var result []Fragment
+	for _, other := range fragments {
+		if other.Source() != fragment.Source() {
+			result = append(result, other)
+		}
+	}
+	return result
+}
+
+// Lists the fragments of all the CRs that are part of the desired state, i.e. all CRs except the ones being deleted
+func listFragments(ctx context.Context, reader client.Reader) ([]Fragment, error) {
+
+	lists := []runtime.Object{
+		&hubblev1beta1.HubbleRbacList{},
+		&hubblev1beta1.HubbleUserList{},
+		&hubblev1beta1.HubbleRoleList{},
+		&hubblev1beta1.HubbleDatabaseList{},
+		&hubblev1beta1.HubblePolicyList{},
+	}
+
+	var result []Fragment
+	for _, list := range lists {
+		err := reader.List(ctx, list)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %T: %w", list, err)
+		}
+
+		items, err := apimeta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			fragment, err := FragmentOf(item)
+			if err != nil {
+				return nil, err
+			}
+			if !fragment.deleting {
+				result = append(result, fragment)
+			}
+		}
+	}
+	return result, nil
+}
+
+// Returns the requests for the CRs of the given kind that are not ready, except the CR that triggered the request.
+// A change to one CR may resolve the errors or conflicts of the others, so they are reconciled again whenever any CR changes.
+func notReady(reader client.Reader, kind string, logger logr.Logger) handler.ToRequestsFunc {
+	return func(object handler.MapObject) []reconcile.Request {
+
+		fragments, err := listFragments(context.TODO(), reader)
+		if err != nil {
+			logger.Error(err, "unable to enqueue CRs that are not ready")
+			return nil
+		}
+
+		trigger, err := FragmentOf(object.Object)
+		if err != nil {
+			logger.Error(err, "unable to enqueue CRs that are not ready")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, fragment := range fragments {
+			if fragment.Kind != kind || fragment.ready || fragment.Source() == trigger.Source() {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: fragment.Name, Namespace: fragment.Namespace}})
+		}
+		return requests
+	}
+}
+
+// Watches all the kinds of CRs that are merged into the desired state, enqueueing the CRs of the given kind that are not ready
+func watchFragments(builder *ctrl.Builder, reader client.Reader, kind string, logger logr.Logger) *ctrl.Builder {
+	objects := []runtime.Object{
+		&hubblev1beta1.HubbleRbac{},
+		&hubblev1beta1.HubbleUser{},
+		&hubblev1beta1.HubbleRole{},
+		&hubblev1beta1.HubbleDatabase{},
+		&hubblev1beta1.HubblePolicy{},
+	}
+	for _, object := range objects {
+		builder = builder.Watches(&source.Kind{Type: object}, &handler.EnqueueRequestsFromMapFunc{ToRequests: notReady(reader, kind, logger)})
+	}
+	return builder
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/validation"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func hubbleRbac(name string, spec string) string {
+	return `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleRbac
+metadata:
+  name: ` + name + `
+  namespace: default
+spec:
+` + spec
+}
+
+const prodDatabase = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleDatabase
+metadata:
+  name: prod
+  namespace: default
+spec:
+  cluster: hubble
+  database: prod
+`
+
+const s3Policy = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubblePolicy
+metadata:
+  name: s3
+  namespace: default
+spec:
+  arn: arn:aws:iam::123456789012:policy/s3
+`
+
+const jwrUser = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleUser
+metadata:
+  name: jwr
+  namespace: default
+spec:
+  email: jwr@lunar.app
+  roles: [{name: analyst}]
+`
+
+func fragmentsOf(t *testing.T, crs ...string) []Fragment {
+	var result []Fragment
+	for _, cr := range crs {
+		object, err := DecodeObject([]byte(cr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fragment, err := FragmentOf(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, fragment)
+	}
+	return result
+}
+
+func errorMessages(errors validation.Errors) map[string]string {
+	result := make(map[string]string)
+	for _, err := range errors {
+		result[err.Path] = err.Message
+	}
+	return result
+}
+
+func Test_MergeFragments(t *testing.T) {
+
+	testCases := []struct {
+		name   string
+		crs    []string
+		errors map[string]string
+		roles  int
+	}{
+		{
+			name: "identical declarations",
+			crs: []string{
+				hubbleRbac("a", "  roles: [{name: bianalyst, databases: [prod, staging]}]\n  databases: [{name: prod, cluster: hubble, database: prod}]"),
+				hubbleRbac("b", "  roles: [{name: bianalyst, databases: [staging, prod]}]"),
+				prodDatabase,
+			},
+			errors: map[string]string{},
+			roles:  1,
+		},
+		{
+			name: "conflicting roles",
+			crs: []string{
+				hubbleRbac("a", "  roles: [{name: bianalyst, databases: [prod]}]"),
+				hubbleRbac("b", "  roles: [{name: analyst}, {name: bianalyst, databases: [staging]}]"),
+			},
+			errors: map[string]string{
+				"HubbleRbac/default/b.spec.roles[1]": "role bianalyst is also declared in HubbleRbac/default/a with a different definition",
+			},
+			roles: 2,
+		},
+		{
+			name: "conflicting databases",
+			crs: []string{
+				hubbleRbac("a", "  databases: [{name: prod, cluster: hubble-unstable, database: prod}]"),
+				prodDatabase,
+			},
+			errors: map[string]string{
+				"HubbleRbac/default/a.spec.databases[0]": "database prod is also declared in HubbleDatabase/default/prod with a different definition",
+			},
+		},
+		{
+			name: "conflicting policies",
+			crs: []string{
+				hubbleRbac("a", "  policies: [{name: s3, arn: 'arn:aws:iam::123456789012:policy/s3-read'}]"),
+				s3Policy,
+			},
+			errors: map[string]string{
+				"HubbleRbac/default/a.spec.policies[0]": "policy s3 is also declared in HubblePolicy/default/s3 with a different definition",
+			},
+		},
+		{
+			name: "conflicting emails",
+			crs: []string{
+				hubbleRbac("a", "  users: [{name: jwr, email: jwr@lunarway.com}]"),
+				jwrUser,
+			},
+			errors: map[string]string{
+				"HubbleUser/default/jwr.spec.email": "user jwr is also declared in HubbleRbac/default/a with the email jwr@lunarway.com",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := mergeFragments(fragmentsOf(t, tc.crs...))
+			assert.Equal(t, tc.errors, errorMessages(a.errors))
+			assert.Len(t, a.spec.Roles, tc.roles)
+		})
+	}
+}
+
+func Test_MergeFragments_UserRolesAreUnioned(t *testing.T) {
+
+	a := mergeFragments(fragmentsOf(t,
+		hubbleRbac("a", "  users: [{name: jwr, email: jwr@lunar.app, roles: [{name: bianalyst}, {name: analyst}]}]"),
+		jwrUser,
+	))
+
+	assert.Empty(t, a.errors)
+	assert.Equal(t, []hubblev1beta1.User{{
+		Name:  "jwr",
+		Email: "jwr@lunar.app",
+		Roles: []hubblev1beta1.RoleAssignment{{Name: "bianalyst"}, {Name: "analyst"}},
+	}}, a.spec.Users)
+}
+
+func Test_BuildAggregatedHubbleModel(t *testing.T) {
+
+	testCases := []struct {
+		name   string
+		crs    []string
+		errors map[string]string
+	}{
+		{
+			name: "merged",
+			crs: []string{
+				hubbleRbac("a", "  roles: [{name: analyst, databases: [prod]}]"),
+				jwrUser,
+				prodDatabase,
+			},
+		},
+		{
+			name: "errors point into the CRs the entries came from",
+			crs: []string{
+				hubbleRbac("a", "  roles: [{name: analyst, databases: [prod]}, {name: bianalyst, databases: [prod]}]"),
+				hubbleRbac("b", "  roles: [{name: analyst, databases: [prod]}, {name: reader, databases: [staging]}]"),
+				hubbleRbac("c", "  users: [{name: kni, email: kni@lunar.app, roles: [{name: writer}]}]"),
+				jwrUser,
+				prodDatabase,
+			},
+			errors: map[string]string{
+				"HubbleRbac/default/b.spec.roles[1].databases[0]":  "no such database: staging",
+				"HubbleRbac/default/c.spec.users[0].roles[0].name": "no such role: writer",
+			},
+		},
+		{
+			name: "errors of dedicated CRs point into their spec",
+			crs: []string{
+				jwrUser,
+			},
+			errors: map[string]string{
+				"HubbleUser/default/jwr.spec.roles[0].name": "no such role: analyst",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, err := BuildAggregatedHubbleModel(fragmentsOf(t, tc.crs...), fixedNow)
+			if tc.errors == nil {
+				assert.NoError(t, err)
+				assert.Len(t, model.Users, 1)
+				return
+			}
+			assert.Equal(t, tc.errors, errorMessages(err.(validation.Errors)))
+		})
+	}
+}
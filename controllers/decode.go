@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var decodeScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(hubblev1alpha1.AddToScheme(decodeScheme))
+	utilruntime.Must(hubblev1beta1.AddToScheme(decodeScheme))
+}
+
+// Decodes a YAML or JSON CR of any of the served versions and converts it to the v1beta1 version the controllers work with
+func DecodeObject(data []byte) (runtime.Object, error) {
+
+	object, gvk, err := serializer.NewCodecFactory(decodeScheme).UniversalDeserializer().Decode(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := object.(conversion.Hub); ok {
+		return object, nil
+	}
+
+	convertible, ok := object.(conversion.Convertible)
+	if !ok {
+		return nil, fmt.Errorf("unable to convert %s to %s", gvk, hubblev1beta1.GroupVersion)
+	}
+
+	hub, err := NewObject(gvk.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	err = convertible.ConvertTo(hub.(conversion.Hub))
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %s to %s: %w", gvk, hubblev1beta1.GroupVersion, err)
+	}
+	return hub, nil
+}
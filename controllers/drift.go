@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/go-logr/logr"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"reflect"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"strings"
+	"time"
+)
+
+const (
+	ReasonDriftDetected    = "DriftDetected"
+	ReasonNoDrift          = "NoDrift"
+	ReasonDriftCorrected   = "DriftCorrected"
+	ReasonDriftCheckFailed = "DriftCheckFailed"
+)
+
+// the maximum length of the drift event message, the api server rejects events with very long messages
+const maxDriftMessageLength = 1024
+
+// conditions are the conditions of the status of a CR
+type conditions interface {
+	LookupCondition(conditionType hubblev1beta1.ConditionType) *hubblev1beta1.Condition
+	SetCondition(condition hubblev1beta1.Condition)
+}
+
+// syncTarget is the CR the merged state of all CRs is applied for. The HubbleRbac CRs and the dedicated kinds keep track of the syncs and the drift checks in the same way
+type syncTarget struct {
+	object             runtime.Object //the events are recorded on the CR
+	name               string
+	generation         int64
+	observedGeneration int64
+	status             *hubblev1beta1.SyncStatus
+	conditions         conditions
+}
+
+func hubbleRbacTarget(instance *hubblev1beta1.HubbleRbac) syncTarget {
+	return syncTarget{
+		object:             instance,
+		name:               instance.Name,
+		generation:         instance.Generation,
+		observedGeneration: instance.Status.ObservedGeneration,
+		status:             &instance.Status.SyncStatus,
+		conditions:         &instance.Status,
+	}
+}
+
+func hubbleObjectTarget(object hubbleObject) syncTarget {
+	status := object.GetObjectStatus()
+	return syncTarget{
+		object:             object,
+		name:               object.GetName(),
+		generation:         object.GetGeneration(),
+		observedGeneration: status.ObservedGeneration,
+		status:             &status.SyncStatus,
+		conditions:         status,
+	}
+}
+
+// syncer holds the configuration of the periodic resync and the drift checks, which is the same for all reconcilers
+type syncer struct {
+	applier                ModelApplier
+	recorder               record.EventRecorder
+	log                    logr.Logger
+	dryRun                 bool
+	resyncInterval         time.Duration
+	driftReportOnly        bool
+	groupRefreshInterval   time.Duration
+	roleBasedAccessControl bool
+}
+
+// Periodically requeues the CR, if a resync interval has been configured, so changes made outside of the controller are detected.
+// CRs that reference google groups are also requeued at the group refresh interval, and all CRs are requeued when one of their role assignments expires.
+func (s syncer) requeue(spec *hubblev1beta1.HubbleRbacSpec) ctrl.Result {
+	result := requeueForGroups(ctrl.Result{RequeueAfter: s.resyncInterval}, spec, s.groupRefreshInterval)
+	return requeueAtExpiry(result, spec, time.Now())
+}
+
+// Records the size of the merged model in the status of the CR and in the metrics
+func (s syncer) observe(target syncTarget, model hubble.Model) {
+	setManagedCounts(target.status, model)
+	observeManaged(model, s.roleBasedAccessControl)
+}
+
+// A resync is a reconcile of a CR that has not changed since it was last applied successfully.
+// Changes found during a resync have been made outside of the controller and are reported as drift.
+// If role assignments of the CRs have expired since the last sync, the reconcile is not a resync, as the roles must be revoked even if drift is only reported.
+// Likewise, if the members of the google groups have changed since the last sync, the changes are applied like a change of the spec.
+func (s syncer) isResync(target syncTarget, fragments []Fragment, groupMembers map[string][]string) bool {
+	if s.dryRun || target.observedGeneration != target.generation {
+		return false
+	}
+	if !reflect.DeepEqual(target.status.GroupMembers, groupMembers) {
+		return false
+	}
+	var lastSync time.Time
+	if target.status.LastSyncTime != nil {
+		lastSync = target.status.LastSyncTime.Time
+	}
+	if expiredSince(fragments, lastSync, time.Now()) {
+		return false
+	}
+	ready := target.conditions.LookupCondition(hubblev1beta1.ConditionReady)
+	return ready != nil && ready.Status == hubblev1beta1.ConditionTrue
+}
+
+func driftMessage(planned []*report.Action) string {
+	var actions []string
+	for _, action := range planned {
+		actions = append(actions, action.String())
+	}
+	message := fmt.Sprintf("%d changes were made outside of the controller: %s", len(planned), strings.Join(actions, "; "))
+	if len(message) > maxDriftMessageLength {
+		message = message[:maxDriftMessageLength-3] + "..."
+	}
+	return message
+}
+
+// Plans the model against the live state of all backends without changing anything, and records any differences as drift.
+// Returns true if drift was detected.
+func (s syncer) detectDrift(target syncTarget, model hubble.Model) (bool, error) {
+
+	driftReport, err := s.applier.Apply(model, true)
+
+	now := metav1.Now()
+	target.status.LastDriftCheckTime = &now
+
+	condition := hubblev1beta1.Condition{Type: hubblev1beta1.ConditionDrifted, ObservedGeneration: target.generation}
+
+	if err != nil {
+		condition.Status = hubblev1beta1.ConditionUnknown
+		condition.Reason = ReasonDriftCheckFailed
+		condition.Message = err.Error()
+		target.conditions.SetCondition(condition)
+		return false, fmt.Errorf("unable to check for drift: %w", err)
+	}
+
+	observeDrift(driftReport)
+
+	planned := driftReport.InState(report.Planned)
+	if len(planned) == 0 {
+		target.status.Drift = nil
+		condition.Status = hubblev1beta1.ConditionFalse
+		condition.Reason = ReasonNoDrift
+		condition.Message = "the backends are in line with the spec"
+		target.conditions.SetCondition(condition)
+		return false, nil
+	}
+
+	message := driftMessage(planned)
+	target.status.Drift = buildBackendReports(driftReport)
+	condition.Status = hubblev1beta1.ConditionTrue
+	condition.Reason = ReasonDriftDetected
+	condition.Message = message
+	target.conditions.SetCondition(condition)
+
+	s.log.Info("drift detected", "name", target.name, "changes", len(planned))
+	if s.recorder != nil {
+		s.recorder.Event(target.object, corev1.EventTypeWarning, ReasonDriftDetected, message)
+	}
+
+	return true, nil
+}
+
+// Checks the backends for drift instead of applying the model, if the CR is resynced.
+// Returns true if the model doesn't need to be applied, because nothing has drifted or drift is only reported.
+func (s syncer) resync(target syncTarget, fragments []Fragment, model hubble.Model, groupMembers map[string][]string, groupErr error) (bool, error) {
+
+	//if the groups can't be looked up, the CR is applied anyway, so the failed lookup is reported like any other failed apply
+	if groupErr != nil || !s.isResync(target, fragments, groupMembers) {
+		return false, nil
+	}
+
+	drifted, err := s.detectDrift(target, model)
+	if err != nil {
+		s.log.Error(err, "drift check failed")
+		return true, err
+	}
+	return !drifted || s.driftReportOnly, nil
+}
+
+// Records the successful apply of the model in the status of the CR
+func (s syncer) synced(target syncTarget, groupMembers map[string][]string, groupErr error) {
+	setDriftCorrected(target)
+	if s.dryRun {
+		return
+	}
+	if groupErr == nil {
+		target.status.GroupMembers = groupMembers
+	}
+	now := metav1.Now()
+	target.status.LastSyncTime = &now
+}
+
+// Marks the drift found by the last drift check as corrected
+func setDriftCorrected(target syncTarget) {
+	drifted := target.conditions.LookupCondition(hubblev1beta1.ConditionDrifted)
+	if drifted == nil || drifted.Status != hubblev1beta1.ConditionTrue {
+		return
+	}
+	target.conditions.SetCondition(hubblev1beta1.Condition{
+		Type:               hubblev1beta1.ConditionDrifted,
+		Status:             hubblev1beta1.ConditionFalse,
+		ObservedGeneration: target.generation,
+		Reason:             ReasonDriftCorrected,
+		Message:            "the drift found by the last drift check has been corrected",
+	})
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+	"time"
+)
+
+// Returns the valid HubbleRbac CR as it looks after it has been applied successfully
+func syncedHubbleRbac(t *testing.T) *hubblev1beta1.HubbleRbac {
+	object, err := DecodeObject([]byte(validHubbleRbac))
+	if err != nil {
+		t.Fatal(err)
+	}
+	instance := object.(*hubblev1beta1.HubbleRbac)
+	instance.Generation = 2
+	instance.Finalizers = []string{CleanupFinalizer}
+	instance.Status.ObservedGeneration = 2
+	lastSync := metav1.NewTime(time.Now().Add(-time.Hour))
+	instance.Status.LastSyncTime = &lastSync
+	instance.Status.SetCondition(hubblev1beta1.Condition{Type: hubblev1beta1.ConditionReady, Status: hubblev1beta1.ConditionTrue, ObservedGeneration: 2, Reason: ReasonSynced})
+	return instance
+}
+
+func driftReport() *report.ApplyReport {
+	result := report.New()
+	result.Planned(report.Redshift, "RevokeAccess", "hubble/bi")
+	return result
+}
+
+func Test_IsResync(t *testing.T) {
+
+	expired := metav1.NewTime(time.Now().Add(-time.Minute))
+
+	testCases := []struct {
+		name         string
+		dryRun       bool
+		groupMembers map[string][]string
+		modify       func(instance *hubblev1beta1.HubbleRbac)
+		expected     bool
+	}{
+		{name: "unchanged since the last sync", modify: func(instance *hubblev1beta1.HubbleRbac) {}, expected: true},
+		{name: "dry run", dryRun: true, modify: func(instance *hubblev1beta1.HubbleRbac) {}, expected: false},
+		{name: "spec changed", modify: func(instance *hubblev1beta1.HubbleRbac) { instance.Generation = 3 }, expected: false},
+		{name: "last apply failed", modify: func(instance *hubblev1beta1.HubbleRbac) {
+			instance.Status.SetCondition(hubblev1beta1.Condition{Type: hubblev1beta1.ConditionReady, Status: hubblev1beta1.ConditionFalse})
+		}, expected: false},
+		{name: "never applied", modify: func(instance *hubblev1beta1.HubbleRbac) { instance.Status.Conditions = nil }, expected: false},
+		{name: "role assignment expired since the last sync", modify: func(instance *hubblev1beta1.HubbleRbac) {
+			instance.Spec.Users[0].Roles[0].ExpiresAt = &expired
+		}, expected: false},
+		{name: "group members changed since the last sync", modify: func(instance *hubblev1beta1.HubbleRbac) {
+			instance.Status.GroupMembers = map[string][]string{"bi@lunar.app": {"kni@lunar.app"}}
+		}, expected: false},
+		{name: "group members unchanged", groupMembers: map[string][]string{"bi@lunar.app": {"jwr@lunar.app"}}, modify: func(instance *hubblev1beta1.HubbleRbac) {
+			instance.Status.GroupMembers = map[string][]string{"bi@lunar.app": {"jwr@lunar.app"}}
+		}, expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := syncedHubbleRbac(t)
+			tc.modify(instance)
+			fragment, err := FragmentOf(instance)
+			assert.NoError(t, err)
+
+			sync := syncer{dryRun: tc.dryRun}
+			assert.Equal(t, tc.expected, sync.isResync(hubbleRbacTarget(instance), []Fragment{fragment}, tc.groupMembers))
+		})
+	}
+}
+
+func Test_DetectDrift(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		applier *fakeApplier
+		drifted bool
+		err     bool
+		status  hubblev1beta1.ConditionStatus
+		reason  string
+		events  int
+	}{
+		{name: "in sync", applier: &fakeApplier{}, status: hubblev1beta1.ConditionFalse, reason: ReasonNoDrift},
+		{name: "drifted", applier: &fakeApplier{dryRunReport: driftReport()}, drifted: true, status: hubblev1beta1.ConditionTrue, reason: ReasonDriftDetected, events: 1},
+		{name: "check failed", applier: &fakeApplier{err: fmt.Errorf("connection refused")}, err: true, status: hubblev1beta1.ConditionUnknown, reason: ReasonDriftCheckFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := syncedHubbleRbac(t)
+			recorder := record.NewFakeRecorder(10)
+			sync := syncer{applier: tc.applier, recorder: recorder, log: logf.NullLogger{}}
+
+			drifted, err := sync.detectDrift(hubbleRbacTarget(instance), hubble.Model{})
+
+			assert.Equal(t, tc.drifted, drifted)
+			assert.Equal(t, tc.err, err != nil)
+			assert.Equal(t, []bool{true}, tc.applier.applied, "the drift check is planned, nothing is applied")
+			assert.NotNil(t, instance.Status.LastDriftCheckTime)
+
+			condition := instance.Status.LookupCondition(hubblev1beta1.ConditionDrifted)
+			assert.Equal(t, tc.status, condition.Status)
+			assert.Equal(t, tc.reason, condition.Reason)
+			assert.Equal(t, tc.drifted, instance.Status.Drift != nil)
+			assert.Len(t, recordedEvents(recorder), tc.events)
+		})
+	}
+}
+
+func Test_Reconcile_Drift(t *testing.T) {
+
+	testCases := []struct {
+		name            string
+		driftReportOnly bool
+		dryRunReport    *report.ApplyReport
+		applied         []bool
+		drifted         hubblev1beta1.ConditionStatus
+		reason          string
+	}{
+		{name: "in sync", dryRunReport: report.New(), applied: []bool{true}, drifted: hubblev1beta1.ConditionFalse, reason: ReasonNoDrift},
+		{name: "drift is corrected", dryRunReport: driftReport(), applied: []bool{true, false}, drifted: hubblev1beta1.ConditionFalse, reason: ReasonDriftCorrected},
+		{name: "drift is only reported", driftReportOnly: true, dryRunReport: driftReport(), applied: []bool{true}, drifted: hubblev1beta1.ConditionTrue, reason: ReasonDriftDetected},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := syncedHubbleRbac(t)
+			applier := &fakeApplier{dryRunReport: tc.dryRunReport}
+			r := &HubbleRbacReconciler{
+				Client:          fake.NewFakeClientWithScheme(decodeScheme, instance),
+				Log:             logf.NullLogger{},
+				Applier:         applier,
+				ResyncInterval:  time.Hour,
+				DriftReportOnly: tc.driftReportOnly,
+			}
+
+			name := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+			result, err := r.Reconcile(reconcile.Request{NamespacedName: name})
+
+			assert.NoError(t, err)
+			assert.Equal(t, time.Hour, result.RequeueAfter)
+			assert.Equal(t, tc.applied, applier.applied)
+
+			updated := &hubblev1beta1.HubbleRbac{}
+			assert.NoError(t, r.Get(context.TODO(), name, updated))
+			drifted := updated.Status.LookupCondition(hubblev1beta1.ConditionDrifted)
+			assert.Equal(t, tc.drifted, drifted.Status)
+			assert.Equal(t, tc.reason, drifted.Reason)
+		})
+	}
+}
+
+func Test_Reconcile_GroupMembersChanged(t *testing.T) {
+
+	instance := syncedHubbleRbac(t)
+	instance.Spec.Roles[0].GoogleGroups = []string{"bi@lunar.app"}
+	instance.Status.GroupMembers = map[string][]string{"bi@lunar.app": {"jwr@lunar.app"}}
+
+	members := map[string][]string{"bi@lunar.app": {"jwr@lunar.app", "kni@lunar.app"}}
+	applier := &fakeApplier{dryRunReport: driftReport(), groupMembers: members}
+	r := &HubbleRbacReconciler{
+		Client:               fake.NewFakeClientWithScheme(decodeScheme, instance),
+		Log:                  logf.NullLogger{},
+		Applier:              applier,
+		DriftReportOnly:      true,
+		GroupRefreshInterval: 15 * time.Minute,
+	}
+
+	name := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: name})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false}, applier.applied, "the new members are applied, even though drift is only reported")
+	assert.Equal(t, 15*time.Minute, result.RequeueAfter, "the CR is requeued to look up the members again, even without a resync interval")
+
+	updated := &hubblev1beta1.HubbleRbac{}
+	assert.NoError(t, r.Get(context.TODO(), name, updated))
+	assert.Equal(t, members, updated.Status.GroupMembers)
+	assert.Nil(t, updated.Status.LookupCondition(hubblev1beta1.ConditionDrifted), "the change is not reported as drift")
+}
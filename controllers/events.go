@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+)
+
+// Emits an event on the CR for every action in the report, so the changes of the last reconcile can be seen with kubectl describe.
+// The event reason is the action type, e.g. RoleCreated or CreateUser.
+func recordEvents(recorder record.EventRecorder, instance runtime.Object, applyReport *report.ApplyReport) {
+
+	if recorder == nil || applyReport == nil {
+		return
+	}
+
+	for _, action := range applyReport.Actions {
+		eventType := corev1.EventTypeNormal
+		if action.State == report.Failed || action.State == report.Skipped {
+			eventType = corev1.EventTypeWarning
+		}
+		recorder.Event(instance, eventType, action.Type, action.String())
+	}
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"fmt"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/record"
+	"testing"
+)
+
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	return events
+}
+
+func Test_RecordEvents(t *testing.T) {
+
+	applyReport := report.New()
+	applyReport.Executed(report.Redshift, "CreateUser", "hubble/jwr")
+	applyReport.Planned(report.Google, "UpdateRoles", "jwr@lunar.app")
+	applyReport.Failed(report.IAM, "RoleCreated", "bianalyst", fmt.Errorf("access denied"))
+	applyReport.Skipped(report.Redshift, "AddToGroup", "hubble/jwr_bianalyst")
+
+	recorder := record.NewFakeRecorder(10)
+	recordEvents(recorder, &hubblev1beta1.HubbleRbac{}, applyReport)
+
+	assert.Equal(t, []string{
+		"Normal CreateUser Redshift CreateUser(hubble/jwr) Executed",
+		"Normal UpdateRoles Google UpdateRoles(jwr@lunar.app) Planned",
+		"Warning RoleCreated IAM RoleCreated(bianalyst) Failed: access denied",
+		"Warning AddToGroup Redshift AddToGroup(hubble/jwr_bianalyst) Skipped",
+	}, recordedEvents(recorder))
+}
+
+func Test_RecordEvents_NothingToRecord(t *testing.T) {
+
+	recorder := record.NewFakeRecorder(10)
+	recordEvents(recorder, &hubblev1beta1.HubbleRbac{}, nil)
+	recordEvents(recorder, &hubblev1beta1.HubbleRbac{}, report.New())
+	recordEvents(nil, &hubblev1beta1.HubbleRbac{}, report.New())
+
+	assert.Empty(t, recordedEvents(recorder))
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"time"
+)
+
+func isExpired(assignment hubblev1beta1.RoleAssignment, now time.Time) bool {
+	return assignment.ExpiresAt != nil && !assignment.ExpiresAt.Time.After(now)
+}
+
+// Adds the assignment to the roles of a user. If the role has already been assigned, the assignment that lasts the longest is kept.
+func mergeRoleAssignment(roles []hubblev1beta1.RoleAssignment, assignment hubblev1beta1.RoleAssignment) []hubblev1beta1.RoleAssignment {
+	for i, existing := range roles {
+		if existing.Name != assignment.Name {
+			continue
+		}
+		if existing.ExpiresAt != nil && (assignment.ExpiresAt == nil || assignment.ExpiresAt.Time.After(existing.ExpiresAt.Time)) {
+			roles[i] = assignment
+		}
+		return roles
+	}
+	return append(roles, assignment)
+}
+
+// Returns the time the next of the role assignments in the spec expires, or nil if none of them expire in the future
+func nextExpiry(spec *hubblev1beta1.HubbleRbacSpec, now time.Time) *time.Time {
+	var next *time.Time
+	for _, user := range spec.Users {
+		for _, assignment := range user.Roles {
+			if assignment.ExpiresAt == nil || isExpired(assignment, now) {
+				continue
+			}
+			if next == nil || assignment.ExpiresAt.Time.Before(*next) {
+				expiresAt := assignment.ExpiresAt.Time
+				next = &expiresAt
+			}
+		}
+	}
+	return next
+}
+
+// Returns true if any of the role assignments of the fragments have expired after the given time, i.e. they have to be revoked
+func expiredSince(fragments []Fragment, since time.Time, now time.Time) bool {
+	for _, fragment := range fragments {
+		for _, user := range fragment.Spec.Users {
+			for _, assignment := range user.Roles {
+				if isExpired(assignment, now) && assignment.ExpiresAt.Time.After(since) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Requeues the CR when the next of its role assignments expires, if that is before the CR would otherwise be requeued, so the role is revoked on time
+func requeueAtExpiry(result ctrl.Result, spec *hubblev1beta1.HubbleRbacSpec, now time.Time) ctrl.Result {
+	next := nextExpiry(spec, now)
+	if next == nil {
+		return result
+	}
+	//the assignment is dropped once it has expired, so the CR is requeued a second after the expiry time
+	after := next.Sub(now) + time.Second
+	if result.RequeueAfter == 0 || after < result.RequeueAfter {
+		result.RequeueAfter = after
+	}
+	return result
+}
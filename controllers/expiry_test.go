@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"testing"
+	"time"
+)
+
+var fixedNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func expiresAt(offset time.Duration) *metav1.Time {
+	t := metav1.NewTime(fixedNow.Add(offset))
+	return &t
+}
+
+func assignment(name string, expiry *metav1.Time) hubblev1beta1.RoleAssignment {
+	return hubblev1beta1.RoleAssignment{Name: name, ExpiresAt: expiry}
+}
+
+func specWithAssignments(assignments ...hubblev1beta1.RoleAssignment) *hubblev1beta1.HubbleRbacSpec {
+	return &hubblev1beta1.HubbleRbacSpec{Users: []hubblev1beta1.User{
+		{Name: "jwr", Email: "jwr@lunar.app", Roles: assignments},
+		{Name: "kni", Email: "kni@lunar.app"},
+	}}
+}
+
+func Test_IsExpired(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		expiry   *metav1.Time
+		expected bool
+	}{
+		{name: "never expires", expected: false},
+		{name: "expires later", expiry: expiresAt(time.Minute), expected: false},
+		{name: "expires now", expiry: expiresAt(0), expected: true},
+		{name: "expired", expiry: expiresAt(-time.Minute), expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isExpired(assignment("bianalyst", tc.expiry), fixedNow))
+		})
+	}
+}
+
+func Test_MergeRoleAssignment(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		existing *metav1.Time
+		merged   *metav1.Time
+		expected *metav1.Time
+	}{
+		{name: "both expire, the later expiry is kept", existing: expiresAt(time.Hour), merged: expiresAt(2 * time.Hour), expected: expiresAt(2 * time.Hour)},
+		{name: "both expire, the earlier expiry is ignored", existing: expiresAt(2 * time.Hour), merged: expiresAt(time.Hour), expected: expiresAt(2 * time.Hour)},
+		{name: "the existing assignment doesn't expire", merged: expiresAt(time.Hour), expected: nil},
+		{name: "the merged assignment doesn't expire", existing: expiresAt(time.Hour), expected: nil},
+		{name: "neither expires", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			roles := []hubblev1beta1.RoleAssignment{assignment("analyst", nil), assignment("bianalyst", tc.existing)}
+
+			roles = mergeRoleAssignment(roles, assignment("bianalyst", tc.merged))
+
+			assert.Equal(t, []hubblev1beta1.RoleAssignment{assignment("analyst", nil), assignment("bianalyst", tc.expected)}, roles)
+		})
+	}
+}
+
+func Test_MergeRoleAssignment_NewRole(t *testing.T) {
+
+	roles := mergeRoleAssignment([]hubblev1beta1.RoleAssignment{assignment("analyst", nil)}, assignment("bianalyst", expiresAt(time.Hour)))
+
+	assert.Equal(t, []hubblev1beta1.RoleAssignment{assignment("analyst", nil), assignment("bianalyst", expiresAt(time.Hour))}, roles)
+}
+
+func Test_NextExpiry(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		spec     *hubblev1beta1.HubbleRbacSpec
+		expected *time.Time
+	}{
+		{name: "no assignments expire", spec: specWithAssignments(assignment("analyst", nil))},
+		{name: "all assignments have expired", spec: specWithAssignments(assignment("analyst", expiresAt(-time.Hour)), assignment("bianalyst", expiresAt(0)))},
+		{name: "the earliest future expiry", spec: specWithAssignments(
+			assignment("analyst", expiresAt(2*time.Hour)),
+			assignment("bianalyst", expiresAt(-time.Hour)),
+			assignment("reader", expiresAt(time.Hour)),
+			assignment("writer", nil),
+		), expected: &expiresAt(time.Hour).Time},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, nextExpiry(tc.spec, fixedNow))
+		})
+	}
+}
+
+func Test_ExpiredSince(t *testing.T) {
+
+	fragments := []Fragment{
+		{Spec: *specWithAssignments(assignment("analyst", nil))},
+		{Spec: *specWithAssignments(assignment("bianalyst", expiresAt(-time.Hour)), assignment("reader", expiresAt(time.Hour)))},
+	}
+
+	testCases := []struct {
+		name     string
+		since    time.Time
+		expected bool
+	}{
+		{name: "expired after the last sync", since: fixedNow.Add(-2 * time.Hour), expected: true},
+		{name: "expired before the last sync", since: fixedNow.Add(-time.Minute), expected: false},
+		{name: "never synced", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, expiredSince(fragments, tc.since, fixedNow))
+		})
+	}
+}
+
+func Test_RequeueAtExpiry(t *testing.T) {
+
+	spec := specWithAssignments(assignment("analyst", expiresAt(time.Hour)))
+
+	testCases := []struct {
+		name     string
+		result   ctrl.Result
+		spec     *hubblev1beta1.HubbleRbacSpec
+		expected time.Duration
+	}{
+		{name: "nothing expires", spec: specWithAssignments(assignment("analyst", nil)), result: ctrl.Result{RequeueAfter: 30 * time.Minute}, expected: 30 * time.Minute},
+		{name: "no requeue yet", spec: spec, expected: time.Hour + time.Second},
+		{name: "later requeue is brought forward", spec: spec, result: ctrl.Result{RequeueAfter: 2 * time.Hour}, expected: time.Hour + time.Second},
+		{name: "earlier requeue is never lengthened", spec: spec, result: ctrl.Result{RequeueAfter: 30 * time.Minute}, expected: 30 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, requeueAtExpiry(tc.result, tc.spec, fixedNow).RequeueAfter)
+		})
+	}
+}
+
+func Test_BuildHubbleModel_ExpiredAssignments(t *testing.T) {
+
+	spec := specWithAssignments(assignment("bianalyst", expiresAt(-time.Minute)), assignment("dbtdeveloper", expiresAt(time.Minute)))
+	spec.Roles = []hubblev1beta1.Role{{Name: "bianalyst"}, {Name: "dbtdeveloper"}}
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected []string
+	}{
+		{name: "one of the assignments expired", now: fixedNow, expected: []string{"dbtdeveloper"}},
+		{name: "before both expire", now: fixedNow.Add(-time.Hour), expected: []string{"bianalyst", "dbtdeveloper"}},
+		{name: "both expired", now: fixedNow.Add(time.Hour)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, err := BuildHubbleModel(&hubblev1beta1.HubbleRbac{Spec: *spec}, tc.now)
+			assert.NoError(t, err)
+
+			var roles []string
+			for _, role := range model.Users[0].AssignedTo {
+				roles = append(roles, role.Name)
+			}
+			assert.Equal(t, tc.expected, roles)
+		})
+	}
+}
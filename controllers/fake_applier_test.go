@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+)
+
+// Records the models it is asked to apply and returns the configured reports, the dry run report is returned when planning
+type fakeApplier struct {
+	report       *report.ApplyReport
+	dryRunReport *report.ApplyReport
+	err          error
+	groupMembers map[string][]string
+
+	applied []bool //the dry run flag of every apply
+	removed []hubble.Model
+}
+
+func (a *fakeApplier) result(dryRun bool) (*report.ApplyReport, error) {
+	a.applied = append(a.applied, dryRun)
+	result := a.report
+	if dryRun && a.dryRunReport != nil {
+		result = a.dryRunReport
+	}
+	if result == nil {
+		result = report.New()
+	}
+	return result, a.err
+}
+
+func (a *fakeApplier) Apply(model hubble.Model, dryRun bool) (*report.ApplyReport, error) {
+	return a.result(dryRun)
+}
+
+func (a *fakeApplier) ApplyRemoving(model hubble.Model, removed hubble.Model, dryRun bool) (*report.ApplyReport, error) {
+	a.removed = append(a.removed, removed)
+	return a.result(dryRun)
+}
+
+func (a *fakeApplier) GroupMembers(model hubble.Model) (map[string][]string, error) {
+	return a.groupMembers, nil
+}
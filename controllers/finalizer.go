@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	corev1 "k8s.io/api/core/v1"
+	"time"
+)
+
+const (
+	// CleanupFinalizer ensures that everything granted by a HubbleRbac CR is removed before the CR is deleted
+	CleanupFinalizer = "hubble.lunar.tech/cleanup"
+	// OrphanAnnotation can be set to "true" on a HubbleRbac CR to leave the redshift users, IAM roles and google roles behind when the CR is deleted
+	OrphanAnnotation = "hubble.lunar.tech/orphan-resources"
+)
+
+const (
+	ReasonCleanupFailed = "CleanupFailed"
+	ReasonCleanedUp     = "CleanedUp"
+	ReasonOrphaned      = "Orphaned"
+)
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	var result []string
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func (r *HubbleRbacReconciler) event(instance *hubblev1beta1.HubbleRbac, eventType string, reason string, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(instance, eventType, reason, message)
+	}
+}
+
+func (r *HubbleRbacReconciler) addFinalizer(instance *hubblev1beta1.HubbleRbac) error {
+	if containsString(instance.Finalizers, CleanupFinalizer) {
+		return nil
+	}
+	instance.Finalizers = append(instance.Finalizers, CleanupFinalizer)
+	return r.Update(context.TODO(), instance)
+}
+
+// Removes everything granted by the CR, unless it has been annotated to orphan the resources, and then removes the finalizer so the CR can be deleted.
+func (r *HubbleRbacReconciler) finalize(instance *hubblev1beta1.HubbleRbac) error {
+
+	if !containsString(instance.Finalizers, CleanupFinalizer) {
+		return nil
+	}
+
+	if instance.Annotations[OrphanAnnotation] == "true" {
+		r.Log.Info("orphaning resources of deleted HubbleRbac CR", "hubblerbac", instance.Name)
+		r.event(instance, corev1.EventTypeNormal, ReasonOrphaned, "the resources granted by the CR have been left behind")
+	} else {
+		err := r.cleanup(instance)
+		if err != nil {
+			return err
+		}
+	}
+
+	instance.Finalizers = removeString(instance.Finalizers, CleanupFinalizer)
+	return r.Update(context.TODO(), instance)
+}
+
+// Returns the merged state of the CRs without the CR of the fragment, and the merged state including it, whose resources are removed unless they are still granted by the other CRs
+func cleanupModels(fragments []Fragment, fragment Fragment, now time.Time) (hubble.Model, hubble.Model, error) {
+
+	remaining := removeFragment(fragments, fragment)
+
+	model, err := BuildAggregatedHubbleModel(remaining, now)
+	if err != nil {
+		return model, hubble.Model{}, err
+	}
+
+	removed, err := BuildAggregatedHubbleModel(append(remaining, fragment), now)
+	if err != nil {
+		return model, removed, err
+	}
+
+	return model, removed, nil
+}
+
+// The CR is removed from the merged state of all CRs, so only what is granted by this CR alone is removed
+func (r *HubbleRbacReconciler) cleanup(instance *hubblev1beta1.HubbleRbac) error {
+
+	fragments, err := listFragments(context.TODO(), r)
+	if err != nil {
+		return err
+	}
+
+	fragment, err := FragmentOf(instance)
+	if err != nil {
+		return err
+	}
+
+	model, removed, err := cleanupModels(fragments, fragment, time.Now())
+	if err != nil {
+		//without a valid model we don't know what to clean up, so the finalizer is kept until the CRs are fixed or the CR is annotated to orphan the resources
+		message := fmt.Sprintf("the CRs are invalid so the resources can't be cleaned up: %v", err)
+		r.event(instance, corev1.EventTypeWarning, ReasonCleanupFailed, message)
+		r.setStatusFailed(instance, ReasonCleanupFailed, err, r.Log)
+		return fmt.Errorf("unable to clean up resources as the CRs are invalid: %w", err)
+	}
+
+	r.Log.Info("cleaning up resources of deleted HubbleRbac CR", "hubblerbac", instance.Name)
+
+	applyReport, err := r.Applier.ApplyRemoving(model, removed, r.DryRun)
+	recordEvents(r.Recorder, instance, applyReport)
+	observeApplyReport(applyReport)
+
+	if err != nil {
+		r.event(instance, corev1.EventTypeWarning, ReasonCleanupFailed, err.Error())
+		r.setStatusFailed(instance, ReasonCleanupFailed, err, r.Log)
+		return fmt.Errorf("unable to clean up resources: %w", err)
+	}
+
+	r.event(instance, corev1.EventTypeNormal, ReasonCleanedUp, "the resources granted only by the CR have been removed")
+	return nil
+}
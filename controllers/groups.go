@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"time"
+)
+
+func referencesGroups(spec *hubblev1beta1.HubbleRbacSpec) bool {
+	for _, role := range spec.Roles {
+		if len(role.GoogleGroups) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// The members of google groups change without a change of the CRs, so the CRs whose roles reference groups are requeued at the group refresh interval to apply the changes.
+// The requeue is never postponed, an earlier requeue is kept.
+func requeueForGroups(result ctrl.Result, spec *hubblev1beta1.HubbleRbacSpec, interval time.Duration) ctrl.Result {
+	if interval == 0 || !referencesGroups(spec) {
+		return result
+	}
+	if result.RequeueAfter == 0 || interval < result.RequeueAfter {
+		result.RequeueAfter = interval
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"testing"
+	"time"
+)
+
+func Test_RequeueForGroups(t *testing.T) {
+
+	withGroups := &hubblev1beta1.HubbleRbacSpec{Roles: []hubblev1beta1.Role{{Name: "bianalyst"}, {Name: "analyst", GoogleGroups: []string{"bi@lunar.app"}}}}
+	withoutGroups := &hubblev1beta1.HubbleRbacSpec{Roles: []hubblev1beta1.Role{{Name: "bianalyst"}}}
+
+	testCases := []struct {
+		name     string
+		result   ctrl.Result
+		spec     *hubblev1beta1.HubbleRbacSpec
+		interval time.Duration
+		expected time.Duration
+	}{
+		{name: "no groups", spec: withoutGroups, interval: 15 * time.Minute, expected: 0},
+		{name: "groups", spec: withGroups, interval: 15 * time.Minute, expected: 15 * time.Minute},
+		{name: "refresh disabled", spec: withGroups, expected: 0},
+		{name: "earlier requeue is kept", result: ctrl.Result{RequeueAfter: time.Minute}, spec: withGroups, interval: 15 * time.Minute, expected: time.Minute},
+		{name: "later requeue is brought forward", result: ctrl.Result{RequeueAfter: time.Hour}, spec: withGroups, interval: 15 * time.Minute, expected: 15 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, requeueForGroups(tc.result, tc.spec, tc.interval).RequeueAfter)
+		})
+	}
+}
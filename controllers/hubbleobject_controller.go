@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+// hubbleObject is implemented by the dedicated HubbleUser, HubbleRole, HubbleDatabase and HubblePolicy CRs
+type hubbleObject interface {
+	runtime.Object
+	metav1.Object
+	GetObjectStatus() *hubblev1beta1.ObjectStatus
+}
+
+// HubbleObjectReconciler reconciles one of the dedicated HubbleUser, HubbleRole, HubbleDatabase and HubblePolicy kinds.
+// The CRs are merged with all other CRs into a single desired state, so reconciling a CR applies the merged state and reports the outcome in the status of the CR.
+type HubbleObjectReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Applier  ModelApplier
+	Recorder record.EventRecorder
+	DryRun   bool
+	Kind     string //the kind of CR reconciled, e.g. HubbleUser
+
+	ResyncInterval  time.Duration //the interval at which unchanged CRs are checked for drift. Zero disables the periodic resync
+	DriftReportOnly bool          //if set, drift is only reported and not corrected
+
+	GroupRefreshInterval time.Duration //the interval at which CRs that reference google groups are reconciled, so changes of the group members are applied. Zero disables the refresh
+
+	RoleBasedAccessControl bool //the roles are managed as redshift roles instead of redshift groups, as configured for the applier
+}
+
+// +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubbleusers;hubbleroles;hubbledatabases;hubblepolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubbleusers/status;hubbleroles/status;hubbledatabases/status;hubblepolicies/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubbleusers/finalizers;hubbleroles/finalizers;hubbledatabases/finalizers;hubblepolicies/finalizers,verbs=update
+
+func (r *HubbleObjectReconciler) newObject() (hubbleObject, error) {
+	object, err := NewObject(r.Kind)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := object.(hubbleObject)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a dedicated hubble kind", r.Kind)
+	}
+	return result, nil
+}
+
+func (r *HubbleObjectReconciler) syncer() syncer {
+	return syncer{
+		applier:                r.Applier,
+		recorder:               r.Recorder,
+		log:                    r.Log,
+		dryRun:                 r.DryRun,
+		resyncInterval:         r.ResyncInterval,
+		driftReportOnly:        r.DriftReportOnly,
+		groupRefreshInterval:   r.GroupRefreshInterval,
+		roleBasedAccessControl: r.RoleBasedAccessControl,
+	}
+}
+
+func (r *HubbleObjectReconciler) event(object hubbleObject, eventType string, reason string, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(object, eventType, reason, message)
+	}
+}
+
+func (r *HubbleObjectReconciler) updateStatus(object hubbleObject) {
+	err := r.Status().Update(context.TODO(), object)
+	if err != nil {
+		r.Log.Error(err, "unable to update status")
+	}
+}
+
+func (r *HubbleObjectReconciler) setStatus(object hubbleObject, status hubblev1beta1.ConditionStatus, reason string, message string) {
+
+	objectStatus := object.GetObjectStatus()
+	objectStatus.ObservedGeneration = object.GetGeneration()
+	objectStatus.Error = ""
+	if status == hubblev1beta1.ConditionFalse {
+		objectStatus.Error = message
+	}
+	objectStatus.SetCondition(hubblev1beta1.Condition{
+		Type:               hubblev1beta1.ConditionReady,
+		Status:             status,
+		ObservedGeneration: object.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	})
+
+	r.updateStatus(object)
+}
+
+func (r *HubbleObjectReconciler) setStatusOk(object hubbleObject) {
+	if r.DryRun {
+		r.setStatus(object, hubblev1beta1.ConditionUnknown, ReasonDryRun, "the model has been planned but not applied as the controller runs in dry run mode")
+		return
+	}
+	r.setStatus(object, hubblev1beta1.ConditionTrue, ReasonSynced, "the model has been applied to all backends")
+}
+
+func (r *HubbleObjectReconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
+
+	logger := r.Log.WithValues(r.Kind, request.NamespacedName)
+
+	object, err := r.newObject()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	err = r.Get(context.TODO(), request.NamespacedName, object)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	fragment, err := FragmentOf(object)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if !object.GetDeletionTimestamp().IsZero() {
+		return reconcile.Result{}, r.finalize(object, fragment, logger)
+	}
+
+	if !containsString(object.GetFinalizers(), CleanupFinalizer) {
+		object.SetFinalizers(append(object.GetFinalizers(), CleanupFinalizer))
+		err = r.Update(context.TODO(), object)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	start := time.Now()
+
+	fragments, err := listFragments(context.TODO(), r)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	fragments = replaceFragment(fragments, fragment)
+	model, err := BuildAggregatedHubbleModel(fragments, start)
+	if err != nil {
+		logger.Error(err, "invalid or conflicting CRs encountered")
+		r.setStatus(object, hubblev1beta1.ConditionFalse, ReasonInvalidSpec, err.Error())
+		r.event(object, corev1.EventTypeWarning, ReasonInvalidSpec, err.Error())
+		observeReconcile(ReconcileInvalid, start)
+		return reconcile.Result{}, nil //don't reschedule, the CR is reconciled again when it or one of the CRs it conflicts with changes
+	}
+
+	sync := r.syncer()
+	target := hubbleObjectTarget(object)
+	sync.observe(target, model)
+
+	groupMembers, groupErr := r.Applier.GroupMembers(model)
+
+	skip, err := sync.resync(target, fragments, model, groupMembers, groupErr)
+	if skip {
+		r.updateStatus(object)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		return sync.requeue(&fragment.Spec), nil
+	}
+
+	applyReport, err := r.Applier.Apply(model, r.DryRun)
+	observeApplyReport(applyReport)
+	recordEvents(r.Recorder, object, applyReport)
+	if err != nil {
+		r.setStatus(object, hubblev1beta1.ConditionFalse, ReasonApplyFailed, err.Error())
+		observeReconcile(ReconcileFailed, start)
+		return reconcile.Result{}, err
+	}
+
+	sync.synced(target, groupMembers, groupErr)
+	r.setStatusOk(object)
+	observeReconcile(ReconcileSucceeded, start)
+
+	return sync.requeue(&fragment.Spec), nil
+}
+
+// Removes what is granted only by the CR, unless it has been annotated to orphan the resources, and then removes the finalizer so the CR can be deleted.
+func (r *HubbleObjectReconciler) finalize(object hubbleObject, fragment Fragment, logger logr.Logger) error {
+
+	if !containsString(object.GetFinalizers(), CleanupFinalizer) {
+		return nil
+	}
+
+	if object.GetAnnotations()[OrphanAnnotation] == "true" {
+		logger.Info("orphaning resources of deleted CR")
+		r.event(object, corev1.EventTypeNormal, ReasonOrphaned, "the resources granted by the CR have been left behind")
+	} else {
+		err := r.cleanup(object, fragment, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	object.SetFinalizers(removeString(object.GetFinalizers(), CleanupFinalizer))
+	return r.Update(context.TODO(), object)
+}
+
+func (r *HubbleObjectReconciler) cleanup(object hubbleObject, fragment Fragment, logger logr.Logger) error {
+
+	fragments, err := listFragments(context.TODO(), r)
+	if err != nil {
+		return err
+	}
+
+	model, removed, err := cleanupModels(fragments, fragment, time.Now())
+	if err != nil {
+		//without a valid model we don't know what to clean up, so the finalizer is kept until the CRs are fixed or the CR is annotated to orphan the resources
+		message := fmt.Sprintf("the CRs are invalid so the resources can't be cleaned up: %v", err)
+		r.event(object, corev1.EventTypeWarning, ReasonCleanupFailed, message)
+		r.setStatus(object, hubblev1beta1.ConditionFalse, ReasonCleanupFailed, message)
+		return fmt.Errorf("unable to clean up resources as the CRs are invalid: %w", err)
+	}
+
+	logger.Info("cleaning up resources of deleted CR")
+
+	applyReport, err := r.Applier.ApplyRemoving(model, removed, r.DryRun)
+	recordEvents(r.Recorder, object, applyReport)
+	observeApplyReport(applyReport)
+
+	if err != nil {
+		r.event(object, corev1.EventTypeWarning, ReasonCleanupFailed, err.Error())
+		r.setStatus(object, hubblev1beta1.ConditionFalse, ReasonCleanupFailed, err.Error())
+		return fmt.Errorf("unable to clean up resources: %w", err)
+	}
+
+	r.event(object, corev1.EventTypeNormal, ReasonCleanedUp, "the resources granted only by the CR have been removed")
+	return nil
+}
+
+func (r *HubbleObjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
+
+	object, err := r.newObject()
+	if err != nil {
+		return err
+	}
+
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(object).
+		WithEventFilter(specOrAnnotationChanged())
+	return watchFragments(builder, mgr.GetClient(), r.Kind, r.Log).Complete(r)
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+	"time"
+)
+
+const analystRole = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleRole
+metadata:
+  name: analyst
+  namespace: default
+spec:
+  databases: [prod]
+`
+
+var jwrName = types.NamespacedName{Namespace: "default", Name: "jwr"}
+
+func decodeObjects(t *testing.T, crs ...string) []runtime.Object {
+	var result []runtime.Object
+	for _, cr := range crs {
+		object, err := DecodeObject([]byte(cr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, object)
+	}
+	return result
+}
+
+func newObjectReconciler(applier *fakeApplier, dryRun bool, objects ...runtime.Object) (*HubbleObjectReconciler, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(100)
+	return &HubbleObjectReconciler{
+		Client:   fake.NewFakeClientWithScheme(decodeScheme, objects...),
+		Log:      logf.NullLogger{},
+		Applier:  applier,
+		Recorder: recorder,
+		DryRun:   dryRun,
+		Kind:     "HubbleUser",
+	}, recorder
+}
+
+func Test_HubbleObjectReconciler_Reconcile(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		crs     []string
+		applier *fakeApplier
+		dryRun  bool
+		err     bool
+		applied []bool
+		status  hubblev1beta1.ConditionStatus
+		reason  string
+	}{
+		{name: "applied", crs: []string{jwrUser, analystRole, prodDatabase}, applier: &fakeApplier{}, applied: []bool{false}, status: hubblev1beta1.ConditionTrue, reason: ReasonSynced},
+		{name: "dry run", crs: []string{jwrUser, analystRole, prodDatabase}, applier: &fakeApplier{}, dryRun: true, applied: []bool{true}, status: hubblev1beta1.ConditionUnknown, reason: ReasonDryRun},
+		{name: "invalid", crs: []string{jwrUser, prodDatabase}, applier: &fakeApplier{}, status: hubblev1beta1.ConditionFalse, reason: ReasonInvalidSpec},
+		{name: "apply failed", crs: []string{jwrUser, analystRole, prodDatabase}, applier: &fakeApplier{err: fmt.Errorf("access denied")}, err: true, applied: []bool{false}, status: hubblev1beta1.ConditionFalse, reason: ReasonApplyFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := newObjectReconciler(tc.applier, tc.dryRun, decodeObjects(t, tc.crs...)...)
+
+			_, err := r.Reconcile(reconcile.Request{NamespacedName: jwrName})
+
+			assert.Equal(t, tc.err, err != nil)
+			assert.Equal(t, tc.applied, tc.applier.applied)
+
+			user := &hubblev1beta1.HubbleUser{}
+			assert.NoError(t, r.Get(context.TODO(), jwrName, user))
+			assert.Equal(t, []string{CleanupFinalizer}, user.Finalizers)
+
+			ready := user.Status.LookupCondition(hubblev1beta1.ConditionReady)
+			assert.Equal(t, tc.status, ready.Status)
+			assert.Equal(t, tc.reason, ready.Reason)
+			assert.Equal(t, tc.status == hubblev1beta1.ConditionFalse, user.Status.Error != "", "the error is only set when the CR failed")
+		})
+	}
+}
+
+func Test_HubbleObjectReconciler_NotFound(t *testing.T) {
+
+	applier := &fakeApplier{}
+	r, _ := newObjectReconciler(applier, false)
+
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: jwrName})
+
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.Empty(t, applier.applied)
+}
+
+func Test_HubbleObjectReconciler_Finalize(t *testing.T) {
+
+	testCases := []struct {
+		name       string
+		crs        []string
+		orphan     bool
+		applier    *fakeApplier
+		err        bool
+		removed    int
+		finalizers []string
+		reason     string
+	}{
+		{name: "cleaned up", applier: &fakeApplier{}, removed: 1, reason: ReasonCleanedUp},
+		{name: "orphaned", orphan: true, applier: &fakeApplier{}, reason: ReasonOrphaned},
+		{name: "cleanup failed", applier: &fakeApplier{err: fmt.Errorf("access denied")}, err: true, removed: 1, finalizers: []string{CleanupFinalizer}, reason: ReasonCleanupFailed},
+		{name: "remaining CRs invalid", crs: []string{jwrUser, analystRole}, applier: &fakeApplier{}, err: true, finalizers: []string{CleanupFinalizer}, reason: ReasonCleanupFailed},
+		{name: "remaining CRs invalid but orphaned", crs: []string{jwrUser, analystRole}, orphan: true, applier: &fakeApplier{}, reason: ReasonOrphaned},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			crs := tc.crs
+			if crs == nil {
+				crs = []string{jwrUser, analystRole, prodDatabase}
+			}
+			objects := decodeObjects(t, crs...)
+			deleted := objects[0].(*hubblev1beta1.HubbleUser)
+			now := metav1.Now()
+			deleted.DeletionTimestamp = &now
+			deleted.Finalizers = []string{CleanupFinalizer}
+			if tc.orphan {
+				deleted.Annotations = map[string]string{OrphanAnnotation: "true"}
+			}
+			r, recorder := newObjectReconciler(tc.applier, false, objects...)
+
+			_, err := r.Reconcile(reconcile.Request{NamespacedName: jwrName})
+
+			assert.Equal(t, tc.err, err != nil)
+			assert.Len(t, tc.applier.removed, tc.removed)
+			if tc.removed > 0 {
+				assert.Equal(t, "jwr@lunar.app", tc.applier.removed[0].Users[0].Email, "the user declared only by the deleted CR is removed")
+			}
+
+			user := &hubblev1beta1.HubbleUser{}
+			assert.NoError(t, r.Get(context.TODO(), jwrName, user))
+			assert.Equal(t, tc.finalizers, user.Finalizers, "the finalizer is kept until the resources have been cleaned up")
+
+			events := recordedEvents(recorder)
+			assert.Contains(t, events[len(events)-1], tc.reason)
+		})
+	}
+}
+
+func Test_HubbleObjectReconciler_Drift(t *testing.T) {
+
+	testCases := []struct {
+		name            string
+		driftReportOnly bool
+		dryRunReport    *report.ApplyReport
+		applied         []bool
+		drifted         hubblev1beta1.ConditionStatus
+		reason          string
+	}{
+		{name: "in sync", dryRunReport: report.New(), applied: []bool{true}, drifted: hubblev1beta1.ConditionFalse, reason: ReasonNoDrift},
+		{name: "drift is corrected", dryRunReport: driftReport(), applied: []bool{true, false}, drifted: hubblev1beta1.ConditionFalse, reason: ReasonDriftCorrected},
+		{name: "drift is only reported", driftReportOnly: true, dryRunReport: driftReport(), applied: []bool{true}, drifted: hubblev1beta1.ConditionTrue, reason: ReasonDriftDetected},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := decodeObjects(t, jwrUser, analystRole, prodDatabase)
+			synced := objects[0].(*hubblev1beta1.HubbleUser)
+			synced.Generation = 2
+			synced.Finalizers = []string{CleanupFinalizer}
+			synced.Status.ObservedGeneration = 2
+			lastSync := metav1.NewTime(time.Now().Add(-time.Hour))
+			synced.Status.LastSyncTime = &lastSync
+			synced.Status.SetCondition(hubblev1beta1.Condition{Type: hubblev1beta1.ConditionReady, Status: hubblev1beta1.ConditionTrue, ObservedGeneration: 2, Reason: ReasonSynced})
+
+			applier := &fakeApplier{dryRunReport: tc.dryRunReport}
+			r, _ := newObjectReconciler(applier, false, objects...)
+			r.ResyncInterval = time.Hour
+			r.DriftReportOnly = tc.driftReportOnly
+
+			result, err := r.Reconcile(reconcile.Request{NamespacedName: jwrName})
+
+			assert.NoError(t, err)
+			assert.Equal(t, time.Hour, result.RequeueAfter)
+			assert.Equal(t, tc.applied, applier.applied)
+
+			user := &hubblev1beta1.HubbleUser{}
+			assert.NoError(t, r.Get(context.TODO(), jwrName, user))
+			drifted := user.Status.LookupCondition(hubblev1beta1.ConditionDrifted)
+			assert.Equal(t, tc.drifted, drifted.Status)
+			assert.Equal(t, tc.reason, drifted.Reason)
+			assert.NotNil(t, user.Status.LastDriftCheckTime)
+			assert.Equal(t, 1, user.Status.ManagedUsers, "the managed counts cover the merged state of all CRs")
+			assert.Equal(t, 1, user.Status.ManagedRoles)
+			assert.Equal(t, 1, user.Status.ManagedDatabases)
+		})
+	}
+}
+
+func Test_HubbleObjectReconciler_Synced(t *testing.T) {
+
+	members := map[string][]string{"bi@lunar.app": {"jwr@lunar.app"}}
+	applier := &fakeApplier{groupMembers: members}
+	r, _ := newObjectReconciler(applier, false, decodeObjects(t, jwrUser, analystRole, prodDatabase)...)
+
+	_, err := r.Reconcile(reconcile.Request{NamespacedName: jwrName})
+	assert.NoError(t, err)
+
+	user := &hubblev1beta1.HubbleUser{}
+	assert.NoError(t, r.Get(context.TODO(), jwrName, user))
+	assert.NotNil(t, user.Status.LastSyncTime)
+	assert.Equal(t, members, user.Status.GroupMembers)
+
+	//the CR is unchanged since it was applied, so the next reconcile only checks for drift
+	_, err = r.Reconcile(reconcile.Request{NamespacedName: jwrName})
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, true}, applier.applied)
+}
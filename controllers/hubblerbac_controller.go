@@ -19,15 +19,17 @@ package controllers
 import (
 	"context"
 	"github.com/go-logr/logr"
-	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
 
-	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
 )
 
 var log = logf.Log.WithName("controller_hubblerbac")
@@ -35,17 +37,26 @@ var log = logf.Log.WithName("controller_hubblerbac")
 // HubbleRbacReconciler reconciles a HubbleRbac object
 type HubbleRbacReconciler struct {
 	client.Client
-	Log     logr.Logger
-	Scheme  *runtime.Scheme
-	Applier *service.Applier
-	DryRun  bool
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Applier  ModelApplier
+	Recorder record.EventRecorder
+	DryRun   bool
+
+	ResyncInterval  time.Duration //the interval at which unchanged CRs are checked for drift. Zero disables the periodic resync
+	DriftReportOnly bool          //if set, drift is only reported and not corrected
+
+	GroupRefreshInterval time.Duration //the interval at which CRs that reference google groups are reconciled, so changes of the group members are applied. Zero disables the refresh
+
+	RoleBasedAccessControl bool //the roles are managed as redshift roles instead of redshift groups, as configured for the applier
 }
 
 // +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubblerbacs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubblerbacs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=hubble.lunar.tech,resources=hubblerbacs/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
-func (r *HubbleRbacReconciler) setStatusFailed(instance *hubblev1alpha1.HubbleRbac, err error, logger logr.Logger) {
-	instance.Status.Error = err.Error()
+func (r *HubbleRbacReconciler) updateStatus(instance *hubblev1beta1.HubbleRbac, logger logr.Logger) {
 	statusUpdateError := r.Status().Update(context.TODO(), instance)
 
 	if statusUpdateError != nil {
@@ -53,12 +64,50 @@ func (r *HubbleRbacReconciler) setStatusFailed(instance *hubblev1alpha1.HubbleRb
 	}
 }
 
-func (r *HubbleRbacReconciler) setStatusOk(instance *hubblev1alpha1.HubbleRbac, logger logr.Logger) {
+func (r *HubbleRbacReconciler) setStatusFailed(instance *hubblev1beta1.HubbleRbac, reason string, err error, logger logr.Logger) {
+	instance.Status.Error = err.Error()
+	instance.Status.ObservedGeneration = instance.Generation
+	instance.Status.SetCondition(hubblev1beta1.Condition{
+		Type:               hubblev1beta1.ConditionReady,
+		Status:             hubblev1beta1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            err.Error(),
+	})
+	r.updateStatus(instance, logger)
+}
+
+func (r *HubbleRbacReconciler) setStatusOk(instance *hubblev1beta1.HubbleRbac, logger logr.Logger) {
 	instance.Status.Error = ""
-	statusUpdateError := r.Status().Update(context.TODO(), instance)
+	instance.Status.ObservedGeneration = instance.Generation
+
+	ready := hubblev1beta1.Condition{
+		Type:               hubblev1beta1.ConditionReady,
+		Status:             hubblev1beta1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             ReasonSynced,
+		Message:            "the model has been applied to all backends",
+	}
+	if r.DryRun {
+		ready.Status = hubblev1beta1.ConditionUnknown
+		ready.Reason = ReasonDryRun
+		ready.Message = "the model has been planned but not applied as the controller runs in dry run mode"
+	}
+	instance.Status.SetCondition(ready)
 
-	if statusUpdateError != nil {
-		logger.Error(statusUpdateError, "unable to update status")
+	r.updateStatus(instance, logger)
+}
+
+func (r *HubbleRbacReconciler) syncer() syncer {
+	return syncer{
+		applier:                r.Applier,
+		recorder:               r.Recorder,
+		log:                    r.Log,
+		dryRun:                 r.DryRun,
+		resyncInterval:         r.ResyncInterval,
+		driftReportOnly:        r.DriftReportOnly,
+		groupRefreshInterval:   r.GroupRefreshInterval,
+		roleBasedAccessControl: r.RoleBasedAccessControl,
 	}
 }
 
@@ -67,7 +116,7 @@ func (r *HubbleRbacReconciler) Reconcile(request ctrl.Request) (ctrl.Result, err
 	_ = r.Log.WithValues("hubblerbac", request.NamespacedName)
 
 	// your logic here
-	instance := &hubblev1alpha1.HubbleRbac{}
+	instance := &hubblev1beta1.HubbleRbac{}
 
 	err := r.Get(context.TODO(), request.NamespacedName, instance)
 	if err != nil {
@@ -77,26 +126,75 @@ func (r *HubbleRbacReconciler) Reconcile(request ctrl.Request) (ctrl.Result, err
 		return reconcile.Result{}, err
 	}
 
-	model, err := buildHubbleModel(instance)
+	if !instance.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, r.finalize(instance)
+	}
+
+	err = r.addFinalizer(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	start := time.Now()
+
+	fragments, err := listFragments(context.TODO(), r)
 	if err != nil {
-		r.Log.Error(err, "invalid HubbleRbac CR encountered")
-		r.setStatusFailed(instance, err, r.Log)
-		return reconcile.Result{}, nil //don't reschedule, if we can't construct the hubble model from the CR it is a permanent problem
+		return reconcile.Result{}, err
+	}
+
+	fragment, err := FragmentOf(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	//all the CRs are merged into a single desired state, so the reconcile of any CR applies the changes of all of them
+	fragments = replaceFragment(fragments, fragment)
+	model, err := BuildAggregatedHubbleModel(fragments, start)
+	if err != nil {
+		r.Log.Error(err, "invalid or conflicting HubbleRbac CRs encountered")
+		r.setStatusFailed(instance, ReasonInvalidSpec, err, r.Log)
+		r.event(instance, corev1.EventTypeWarning, ReasonInvalidSpec, err.Error())
+		observeReconcile(ReconcileInvalid, start)
+		return reconcile.Result{}, nil //don't reschedule, the CR is reconciled again when it or one of the CRs it conflicts with changes
+	}
+
+	sync := r.syncer()
+	target := hubbleRbacTarget(instance)
+	sync.observe(target, model)
+
+	groupMembers, groupErr := r.Applier.GroupMembers(model)
+
+	skip, err := sync.resync(target, fragments, model, groupMembers, groupErr)
+	if skip {
+		r.updateStatus(instance, r.Log)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		return sync.requeue(&fragment.Spec), nil
 	}
 
-	err = r.Applier.Apply(model, r.DryRun)
+	applyReport, err := r.Applier.Apply(model, r.DryRun)
+	instance.Status.LastApply = buildBackendReports(applyReport)
+	setBackendConditions(&instance.Status, instance.Generation, applyReport, err, r.DryRun)
+	observeApplyReport(applyReport)
+	recordEvents(r.Recorder, instance, applyReport)
 	if err != nil {
-		r.setStatusFailed(instance, err, r.Log)
+		r.setStatusFailed(instance, ReasonApplyFailed, err, r.Log)
+		observeReconcile(ReconcileFailed, start)
 		return reconcile.Result{}, err
 	}
 
+	sync.synced(target, groupMembers, groupErr)
 	r.setStatusOk(instance, r.Log)
+	observeReconcile(ReconcileSucceeded, start)
 
-	return ctrl.Result{}, nil
+	return sync.requeue(&fragment.Spec), nil
 }
 
 func (r *HubbleRbacReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&hubblev1alpha1.HubbleRbac{}).
-		Complete(r)
+	//only changes of the spec or the annotations cause a reconcile, the periodic resync is driven by RequeueAfter
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&hubblev1beta1.HubbleRbac{}).
+		WithEventFilter(specOrAnnotationChanged())
+	return watchFragments(builder, mgr.GetClient(), "HubbleRbac", r.Log).Complete(r)
 }
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"net/http"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"time"
+)
+
+const ValidatingWebhookPath = "/validate-hubble-lunar-tech-hubble-objects"
+
+// +kubebuilder:webhook:path=/validate-hubble-lunar-tech-hubble-objects,mutating=false,failurePolicy=fail,groups=hubble.lunar.tech,resources=hubblerbacs;hubbleusers;hubbleroles;hubbledatabases;hubblepolicies,verbs=create;update,versions=v1alpha1;v1beta1,name=vhubbleobjects.lunar.tech
+
+// HubbleRbacValidator rejects HubbleRbac, HubbleUser, HubbleRole, HubbleDatabase and HubblePolicy CRs that cannot be applied, so the errors are reported when the CR is created or updated instead of during reconcile
+type HubbleRbacValidator struct {
+	Client   client.Reader //used to look up the other CRs, the CR is validated as part of the merged state of all CRs
+	Excluded *redshift.Exclusions
+	Log      logr.Logger
+
+	RoleBasedAccessControl bool //the CRs are validated as the redshift roles, instead of the redshift groups, the applier maps them to
+}
+
+func (v *HubbleRbacValidator) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(ValidatingWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
+
+func (v *HubbleRbacValidator) Handle(ctx context.Context, request admission.Request) admission.Response {
+
+	//the CR is validated in the version the controllers work with, whatever version it was submitted in
+	object, err := DecodeObject(request.Object.Raw)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	fragment, err := FragmentOf(object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	fragments, err := listFragments(ctx, v.Client)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	err = ValidateFragments(replaceFragment(fragments, fragment), v.Excluded, v.RoleBasedAccessControl, time.Now())
+	if err != nil {
+		v.Log.Info("rejected invalid CR", "kind", request.Kind.Kind, "name", request.Name, "error", err.Error())
+		return admission.Denied(fmt.Sprintf("invalid %s: %v", request.Kind.Kind, err))
+	}
+
+	return admission.Allowed("")
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+const validHubbleRbac = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleRbac
+metadata:
+  name: analysts
+  namespace: default
+spec:
+  users:
+  - name: jwr
+    email: jwr@lunar.app
+    roles: [{name: bianalyst}]
+  roles:
+  - name: bianalyst
+    databases: [prod]
+    datawarehouseGrants: [bi]
+  databases:
+  - name: prod
+    cluster: hubble
+    database: prod
+`
+
+const validV1alpha1HubbleRbac = `
+apiVersion: hubble.lunar.tech/v1alpha1
+kind: HubbleRbac
+metadata:
+  name: analysts
+  namespace: default
+spec:
+  users:
+  - name: jwr
+    email: jwr@lunar.app
+    roles: [bianalyst]
+  roles:
+  - name: bianalyst
+    databases: [prod]
+    devDatabases: []
+    datalakeGrants: []
+    datawarehouseGrants: [bi]
+    policies: []
+  policies: []
+  databases:
+  - name: prod
+    cluster: hubble
+    database: prod
+  devDatabases: []
+`
+
+const invalidHubbleRbac = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleRbac
+metadata:
+  name: analysts
+  namespace: default
+spec:
+  roles:
+  - name: bianalyst
+    databases: [staging]
+`
+
+const conflictingHubbleDatabase = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleDatabase
+metadata:
+  name: prod
+  namespace: default
+spec:
+  cluster: hubble-unstable
+  database: prod
+`
+
+func admissionRequest(kind string, data string) admission.Request {
+	return admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Kind:   metav1.GroupVersionKind{Group: hubblev1beta1.GroupVersion.Group, Kind: kind},
+		Name:   "test",
+		Object: runtime.RawExtension{Raw: []byte(data)},
+	}}
+}
+
+func newValidator(t *testing.T, existing ...string) *HubbleRbacValidator {
+	var objects []runtime.Object
+	for _, data := range existing {
+		object, err := DecodeObject([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, object)
+	}
+	return &HubbleRbacValidator{
+		Client:   fake.NewFakeClientWithScheme(decodeScheme, objects...),
+		Excluded: redshift.NewExclusions([]string{"template0"}, []string{"lunarway"}),
+		Log:      logf.NullLogger{},
+	}
+}
+
+func Test_Webhook(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		existing []string
+		kind     string
+		object   string
+		allowed  bool
+		code     int32
+		message  string
+
+		roleBasedAccessControl bool
+	}{
+		{name: "valid CR", kind: "HubbleRbac", object: validHubbleRbac, allowed: true},
+		{name: "valid CR in the old version", kind: "HubbleRbac", object: validV1alpha1HubbleRbac, allowed: true},
+		{name: "update of an existing CR", existing: []string{validHubbleRbac}, kind: "HubbleRbac", object: validHubbleRbac, allowed: true},
+		{name: "invalid CR", kind: "HubbleRbac", object: invalidHubbleRbac, code: http.StatusForbidden, message: "HubbleRbac/default/analysts.spec.roles[0].databases[0]: no such database: staging"},
+		{name: "conflicting CRs", existing: []string{validHubbleRbac}, kind: "HubbleDatabase", object: conflictingHubbleDatabase, code: http.StatusForbidden, message: "database prod is also declared in"},
+		{name: "undecodable CR", kind: "HubbleRbac", object: "{", code: http.StatusBadRequest},
+		{name: "valid CR with redshift roles", roleBasedAccessControl: true, kind: "HubbleRbac", object: validHubbleRbac, allowed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator := newValidator(t, tc.existing...)
+			validator.RoleBasedAccessControl = tc.roleBasedAccessControl
+			response := validator.Handle(context.TODO(), admissionRequest(tc.kind, tc.object))
+
+			assert.Equal(t, tc.allowed, response.Allowed)
+			if !tc.allowed {
+				assert.Equal(t, tc.code, response.Result.Code)
+				//denials carry the errors in the reason, other errors in the message
+				assert.Contains(t, string(response.Result.Reason)+response.Result.Message, tc.message)
+			}
+		})
+	}
+}
@@ -2,12 +2,25 @@ package controllers
 
 import (
 	"fmt"
-	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
-	"strings"
+	"time"
 )
 
-func buildHubbleModel(users *hubblev1alpha1.HubbleRbac) (hubble.Model, error) {
+// Maps the HubbleRbac CR to the hubble model. If the spec is invalid, all the validation errors found are returned.
+// Role assignments that have expired at the given instant are left out of the model.
+func BuildHubbleModel(users *hubblev1beta1.HubbleRbac, now time.Time) (hubble.Model, error) {
+
+	err := validateSpec(&users.Spec).ErrorOrNil()
+	if err != nil {
+		return hubble.Model{}, err
+	}
+
+	return buildModel(users, now)
+}
+
+// Maps a valid HubbleRbac CR to the hubble model
+func buildModel(users *hubblev1beta1.HubbleRbac, now time.Time) (hubble.Model, error) {
 
 	model := hubble.Model{}
 
@@ -29,10 +42,30 @@ func buildHubbleModel(users *hubblev1alpha1.HubbleRbac) (hubble.Model, error) {
 		policyMap[policy.Name] = model.AddPolicyReference(policy.Arn)
 	}
 
+	externalSchemaMap := make(map[string]*hubble.GlueDatabase)
+	for _, schema := range users.Spec.ExternalSchemas {
+		externalSchemaMap[schema.Name] = &hubble.GlueDatabase{
+			ShortName:     schema.Name,
+			Name:          schema.GlueDatabase,
+			IamRoles:      schema.IamRoles,
+			CatalogRegion: schema.CatalogRegion,
+		}
+	}
+
+	//a datalake grant references a declared external schema, or the name of a glue database that is exposed with the default settings
 	for _, role := range users.Spec.Roles {
 		for _, name := range role.DatalakeGrants {
+			if schema, ok := externalSchemaMap[name]; ok {
+				datalakeGrantsMap[name] = schema
+				continue
+			}
+			shortName := datalakeGrantShortName(name)
+			if schema, ok := externalSchemaMap[shortName]; ok {
+				datalakeGrantsMap[name] = schema
+				continue
+			}
 			datalakeGrantsMap[name] = &hubble.GlueDatabase{
-				ShortName: strings.ReplaceAll(name, "-", ""),
+				ShortName: shortName,
 				Name:      name,
 			}
 		}
@@ -77,30 +110,94 @@ func buildHubbleModel(users *hubblev1alpha1.HubbleRbac) (hubble.Model, error) {
 			policies = append(policies, policy)
 		}
 
+		var privileges map[hubble.DataSet]hubble.Privilege
+		for schema, privilege := range role.DatawarehousePrivileges {
+			if privileges == nil {
+				privileges = make(map[hubble.DataSet]hubble.Privilege)
+			}
+			privileges[hubble.DataSet(schema)] = hubble.Privilege(privilege)
+		}
+
+		var tableGrants []*hubble.TableGrant
+		for _, grant := range role.TableGrants {
+			tableGrants = append(tableGrants, &hubble.TableGrant{
+				DataSet: hubble.DataSet(grant.Schema),
+				Table:   grant.Table,
+				Columns: grant.Columns,
+			})
+		}
+
 		r := &hubble.Role{
 			Name:                 role.Name,
 			GrantedDatabases:     databases,
 			GrantedDevDatabases:  devDatabases,
 			GrantedGlueDatabases: datalakeGrants,
 			Acl:                  acl,
+			Privileges:           privileges,
+			TableGrants:          tableGrants,
 			Policies:             policies,
+			GoogleGroups:         role.GoogleGroups,
+			SessionDuration:      int(sessionDuration(role).Seconds()),
+			TrustedPrincipals:    role.TrustedPrincipals,
 		}
 
 		model.Roles = append(model.Roles, r)
 		roleMap[role.Name] = r
 	}
 
+	for _, role := range users.Spec.Roles {
+		for _, name := range role.Extends {
+			base, ok := roleMap[name]
+			if !ok {
+				return model, fmt.Errorf("no such role: %s", name)
+			}
+			roleMap[role.Name].Extend(base)
+		}
+	}
+
+	//the inherited grants are copied into the roles, so the resolver only has to look at the grants of each role
+	err := model.Flatten()
+	if err != nil {
+		return model, err
+	}
+
+	userMap := make(map[string]*hubble.User)
+
 	for _, user := range users.Spec.Users {
 		a := model.AddUser(user.Name, user.Email)
+		userMap[user.Name] = a
 
 		for _, r := range user.Roles {
-			role, ok := roleMap[r]
+			//expired assignments are left out, so the roles are revoked
+			if isExpired(r, now) {
+				continue
+			}
+			role, ok := roleMap[r.Name]
 			if !ok {
-				return model, fmt.Errorf("no such role: %s", r)
+				return model, fmt.Errorf("no such role: %s", r.Name)
 			}
 			a.Assign(role)
 		}
 	}
 
+	for _, team := range users.Spec.Teams {
+		t := model.AddTeam(team.Name)
+
+		for _, name := range team.Members {
+			member, ok := userMap[name]
+			if !ok {
+				return model, fmt.Errorf("no such user: %s", name)
+			}
+			t.AddMember(member)
+		}
+		for _, name := range team.Roles {
+			role, ok := roleMap[name]
+			if !ok {
+				return model, fmt.Errorf("no such role: %s", name)
+			}
+			t.Assign(role)
+		}
+	}
+
 	return model, nil
 }
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+const (
+	ReconcileSucceeded = "succeeded"
+	ReconcileFailed    = "failed"
+	ReconcileInvalid   = "invalid"
+)
+
+var (
+	reconcileDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "hubble_rbac_reconcile_duration_seconds",
+		Help:    "Time it took to reconcile a HubbleRbac CR",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+	})
+	reconcilesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_reconciles_total",
+		Help: "Number of reconciles by result (succeeded, failed or invalid)",
+	}, []string{"result"})
+	backendApplyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hubble_rbac_backend_apply_duration_seconds",
+		Help:    "Time it took to apply the model to a backend",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 14),
+	}, []string{"backend"})
+	redshiftTasksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_redshift_tasks_total",
+		Help: "Number of redshift tasks by task type and final state in the reconciliation DAG (Success, Failed, Skipped, ...)",
+	}, []string{"task_type", "state"})
+	iamEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_iam_events_total",
+		Help: "Number of IAM changes by event type and state",
+	}, []string{"event_type", "state"})
+	googleUpdatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_google_updates_total",
+		Help: "Number of Google user role updates by state",
+	}, []string{"state"})
+	lastApplyActions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hubble_rbac_last_apply_actions",
+		Help: "Number of actions in the last apply by backend and state. Executed or planned actions on an unchanged CR indicate drift",
+	}, []string{"backend", "state"})
+	driftActions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hubble_rbac_drift_actions",
+		Help: "Number of changes found by the last drift check by backend, i.e. changes made outside of the controller",
+	}, []string{"backend"})
+	driftChecksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "hubble_rbac_drift_checks_total",
+		Help: "Number of drift checks by result (drifted or in_sync)",
+	}, []string{"result"})
+	managedUsers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hubble_rbac_managed_users",
+		Help: "Number of redshift users managed on a cluster",
+	}, []string{"cluster"})
+	managedGroups = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "hubble_rbac_managed_groups",
+		Help: "Number of redshift groups, or redshift roles with role based access control, managed on a cluster",
+	}, []string{"cluster"})
+	managedRoles = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "hubble_rbac_managed_roles",
+		Help: "Number of managed IAM roles",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		reconcileDuration,
+		reconcilesTotal,
+		backendApplyDuration,
+		redshiftTasksTotal,
+		iamEventsTotal,
+		googleUpdatesTotal,
+		lastApplyActions,
+		driftActions,
+		driftChecksTotal,
+		managedUsers,
+		managedGroups,
+		managedRoles,
+	)
+}
+
+func observeManaged(model hubble.Model, roleBasedAccessControl bool) {
+
+	redshiftModel, iamModel, _ := (&resolver.Resolver{RoleBasedAccessControl: roleBasedAccessControl}).Resolve(model)
+
+	managedUsers.Reset()
+	managedGroups.Reset()
+	for _, cluster := range redshiftModel.Clusters {
+		managedUsers.WithLabelValues(cluster.Identifier).Set(float64(len(cluster.Users)))
+		managedGroups.WithLabelValues(cluster.Identifier).Set(float64(len(cluster.Groups) + len(cluster.Roles)))
+	}
+	managedRoles.Set(float64(len(iamModel.Roles)))
+}
+
+func observeApplyReport(applyReport *report.ApplyReport) {
+
+	if applyReport == nil {
+		return
+	}
+
+	for backend, duration := range applyReport.Durations {
+		backendApplyDuration.WithLabelValues(string(backend)).Observe(duration.Seconds())
+	}
+
+	for _, action := range applyReport.Actions {
+		switch action.Backend {
+		case report.Redshift:
+			redshiftTasksTotal.WithLabelValues(action.Type, action.TaskState).Inc()
+		case report.IAM:
+			iamEventsTotal.WithLabelValues(action.Type, string(action.State)).Inc()
+		case report.Google:
+			googleUpdatesTotal.WithLabelValues(string(action.State)).Inc()
+		}
+	}
+
+	lastApplyActions.Reset()
+	for _, backend := range report.Backends {
+		for _, state := range []report.ActionState{report.Planned, report.Executed, report.Skipped, report.Failed} {
+			lastApplyActions.WithLabelValues(string(backend), string(state)).Set(float64(applyReport.Count(backend, state)))
+		}
+	}
+}
+
+func observeReconcile(result string, start time.Time) {
+	reconcilesTotal.WithLabelValues(result).Inc()
+	reconcileDuration.Observe(time.Since(start).Seconds())
+}
+
+func observeDrift(driftReport *report.ApplyReport) {
+	for _, backend := range report.Backends {
+		driftActions.WithLabelValues(string(backend)).Set(float64(driftReport.Count(backend, report.Planned)))
+	}
+	if len(driftReport.InState(report.Planned)) > 0 {
+		driftChecksTotal.WithLabelValues("drifted").Inc()
+	} else {
+		driftChecksTotal.WithLabelValues("in_sync").Inc()
+	}
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ObserveApplyReport(t *testing.T) {
+
+	applyReport := report.New()
+	applyReport.Executed(report.Redshift, "CreateUser", "hubble/jwr").TaskState = "Success"
+	applyReport.Executed(report.Redshift, "CreateUser", "hubble/kni").TaskState = "Success"
+	applyReport.Failed(report.Redshift, "GrantAccess", "hubble/bi", fmt.Errorf("permission denied")).TaskState = "Failed"
+	applyReport.Skipped(report.Redshift, "AddToGroup", "hubble/jwr_bianalyst").TaskState = "Pending"
+	applyReport.Executed(report.IAM, "RoleCreated", "bianalyst")
+	applyReport.Planned(report.Google, "UpdateRoles", "jwr@lunar.app")
+
+	createdUsers := testutil.ToFloat64(redshiftTasksTotal.WithLabelValues("CreateUser", "Success"))
+	failedGrants := testutil.ToFloat64(redshiftTasksTotal.WithLabelValues("GrantAccess", "Failed"))
+	skippedTasks := testutil.ToFloat64(redshiftTasksTotal.WithLabelValues("AddToGroup", "Pending"))
+	createdRoles := testutil.ToFloat64(iamEventsTotal.WithLabelValues("RoleCreated", string(report.Executed)))
+	googleUpdates := testutil.ToFloat64(googleUpdatesTotal.WithLabelValues(string(report.Planned)))
+
+	observeApplyReport(applyReport)
+
+	assert.Equal(t, createdUsers+2, testutil.ToFloat64(redshiftTasksTotal.WithLabelValues("CreateUser", "Success")))
+	assert.Equal(t, failedGrants+1, testutil.ToFloat64(redshiftTasksTotal.WithLabelValues("GrantAccess", "Failed")))
+	assert.Equal(t, skippedTasks+1, testutil.ToFloat64(redshiftTasksTotal.WithLabelValues("AddToGroup", "Pending")), "tasks are labelled with their state in the DAG")
+	assert.Equal(t, createdRoles+1, testutil.ToFloat64(iamEventsTotal.WithLabelValues("RoleCreated", string(report.Executed))))
+	assert.Equal(t, googleUpdates+1, testutil.ToFloat64(googleUpdatesTotal.WithLabelValues(string(report.Planned))))
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(lastApplyActions.WithLabelValues(string(report.Redshift), string(report.Executed))))
+	assert.Equal(t, float64(1), testutil.ToFloat64(lastApplyActions.WithLabelValues(string(report.Redshift), string(report.Failed))))
+	assert.Equal(t, float64(0), testutil.ToFloat64(lastApplyActions.WithLabelValues(string(report.IAM), string(report.Failed))))
+}
+
+func Test_ObserveDrift(t *testing.T) {
+
+	inSync := testutil.ToFloat64(driftChecksTotal.WithLabelValues("in_sync"))
+	drifted := testutil.ToFloat64(driftChecksTotal.WithLabelValues("drifted"))
+
+	observeDrift(report.New())
+
+	assert.Equal(t, inSync+1, testutil.ToFloat64(driftChecksTotal.WithLabelValues("in_sync")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(driftActions.WithLabelValues(string(report.Redshift))))
+
+	driftReport := report.New()
+	driftReport.Planned(report.Redshift, "RevokeAccess", "hubble/bi")
+	observeDrift(driftReport)
+
+	assert.Equal(t, drifted+1, testutil.ToFloat64(driftChecksTotal.WithLabelValues("drifted")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(driftActions.WithLabelValues(string(report.Redshift))))
+}
+
+func Test_ObserveManaged(t *testing.T) {
+
+	model, err := BuildAggregatedHubbleModel(fragmentsOf(t, validHubbleRbac), fixedNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observeManaged(model, false)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(managedUsers.WithLabelValues("hubble")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(managedGroups.WithLabelValues("hubble")))
+
+	observeManaged(model, true)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(managedGroups.WithLabelValues("hubble")), "the redshift roles are counted when role based access control is used")
+}
@@ -0,0 +1,13 @@
+package controllers
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+)
+
+// ModelApplier applies the merged hubble model to the backends. It is implemented by the service.Applier
+type ModelApplier interface {
+	Apply(model hubble.Model, dryRun bool) (*report.ApplyReport, error)
+	ApplyRemoving(model hubble.Model, removed hubble.Model, dryRun bool) (*report.ApplyReport, error)
+	GroupMembers(model hubble.Model) (map[string][]string, error)
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Passes updates that change the annotations of a CR, e.g. when the orphan annotation is set on a CR whose cleanup is failing
+var annotationChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		if e.MetaOld == nil || e.MetaNew == nil {
+			return false
+		}
+		return !reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations())
+	},
+}
+
+// Filters the events of the CRs down to changes of the spec or the annotations.
+// Status updates change neither, so they are filtered out and don't cause a new reconcile.
+func specOrAnnotationChanged() predicate.Predicate {
+	return predicate.Or(predicate.GenerationChangedPredicate{}, annotationChangedPredicate)
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"testing"
+)
+
+func Test_SpecOrAnnotationChanged(t *testing.T) {
+
+	old := &hubblev1beta1.HubbleRbac{}
+	old.Generation = 1
+
+	specChanged := old.DeepCopy()
+	specChanged.Generation = 2
+
+	annotated := old.DeepCopy()
+	annotated.Annotations = map[string]string{OrphanAnnotation: "true"}
+
+	statusChanged := old.DeepCopy()
+	statusChanged.Status.Error = "access denied"
+
+	testCases := []struct {
+		name     string
+		updated  *hubblev1beta1.HubbleRbac
+		expected bool
+	}{
+		{name: "spec changed", updated: specChanged, expected: true},
+		{name: "annotation changed", updated: annotated, expected: true},
+		{name: "status changed", updated: statusChanged, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			updateEvent := event.UpdateEvent{MetaOld: old, ObjectOld: old, MetaNew: tc.updated, ObjectNew: tc.updated}
+			assert.Equal(t, tc.expected, specOrAnnotationChanged().Update(updateEvent))
+		})
+	}
+}
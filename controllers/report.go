@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+)
+
+func buildBackendReports(applyReport *report.ApplyReport) []hubblev1beta1.BackendReport {
+
+	if applyReport == nil {
+		return nil
+	}
+
+	var result []hubblev1beta1.BackendReport
+
+	for _, backend := range report.Backends {
+		backendReport := hubblev1beta1.BackendReport{
+			Backend:  string(backend),
+			Planned:  applyReport.Count(backend, report.Planned),
+			Executed: applyReport.Count(backend, report.Executed),
+			Skipped:  applyReport.Count(backend, report.Skipped),
+			Failed:   applyReport.Count(backend, report.Failed),
+		}
+
+		for _, action := range applyReport.ForBackend(backend) {
+			if action.State == report.Failed {
+				backendReport.Failures = append(backendReport.Failures, action.String())
+			}
+		}
+
+		result = append(result, backendReport)
+	}
+
+	return result
+}
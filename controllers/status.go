@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"strings"
+)
+
+const (
+	ReasonSynced      = "Synced"
+	ReasonDryRun      = "DryRun"
+	ReasonApplyFailed = "ApplyFailed"
+	ReasonNotApplied  = "NotApplied"
+	ReasonInvalidSpec = "InvalidSpec"
+)
+
+var backendConditions = map[report.Backend]hubblev1beta1.ConditionType{
+	report.Redshift: hubblev1beta1.ConditionRedshiftSynced,
+	report.IAM:      hubblev1beta1.ConditionIAMSynced,
+	report.Google:   hubblev1beta1.ConditionGoogleSynced,
+}
+
+func setManagedCounts(status *hubblev1beta1.SyncStatus, model hubble.Model) {
+	status.ManagedUsers = len(model.Users)
+	status.ManagedRoles = len(model.Roles)
+	status.ManagedDatabases = len(model.Databases) + len(model.DevDatabases)
+}
+
+// Sets a condition for every backend based on the outcome of applying the model.
+// If applying one of the backends failed, the backends following it have not been applied and their condition is set to unknown.
+// If the apply failed before any backend was applied, e.g. because the model could not be built, the condition of every backend is set to unknown.
+func setBackendConditions(status *hubblev1beta1.HubbleRbacStatus, generation int64, applyReport *report.ApplyReport, applyErr error, dryRun bool) {
+
+	var backendError *service.BackendError
+	failedBackend := report.Backend("")
+	if errors.As(applyErr, &backendError) {
+		failedBackend = backendError.Backend
+	}
+
+	notApplied := false
+	for _, backend := range report.Backends {
+		condition := hubblev1beta1.Condition{Type: backendConditions[backend], ObservedGeneration: generation}
+
+		switch {
+		case applyErr != nil && backendError == nil:
+			condition.Status = hubblev1beta1.ConditionUnknown
+			condition.Reason = ReasonNotApplied
+			condition.Message = fmt.Sprintf("the %s model was not applied: %v", backend, applyErr)
+		case notApplied:
+			condition.Status = hubblev1beta1.ConditionUnknown
+			condition.Reason = ReasonNotApplied
+			condition.Message = fmt.Sprintf("the %s model was not applied because applying the %s model failed", backend, failedBackend)
+		case backend == failedBackend:
+			condition.Status = hubblev1beta1.ConditionFalse
+			condition.Reason = ReasonApplyFailed
+			condition.Message = failureMessage(applyReport, backend, backendError.Err)
+			notApplied = true
+		case dryRun:
+			condition.Status = hubblev1beta1.ConditionUnknown
+			condition.Reason = ReasonDryRun
+			condition.Message = fmt.Sprintf("%d actions planned", countActions(applyReport, backend, report.Planned))
+		default:
+			condition.Status = hubblev1beta1.ConditionTrue
+			condition.Reason = ReasonSynced
+			condition.Message = fmt.Sprintf("%d actions executed", countActions(applyReport, backend, report.Executed))
+		}
+
+		status.SetCondition(condition)
+	}
+}
+
+func failureMessage(applyReport *report.ApplyReport, backend report.Backend, err error) string {
+	if applyReport == nil {
+		return err.Error()
+	}
+
+	var messages []string
+	for _, action := range applyReport.ForBackend(backend) {
+		if action.State == report.Failed {
+			messages = append(messages, action.String())
+		}
+	}
+
+	if len(messages) == 0 {
+		return err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func countActions(applyReport *report.ApplyReport, backend report.Backend, state report.ActionState) int {
+	if applyReport == nil {
+		return 0
+	}
+	return applyReport.Count(backend, state)
+}
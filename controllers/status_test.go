@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"fmt"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func conditionStatuses(status *hubblev1beta1.HubbleRbacStatus) map[hubblev1beta1.ConditionType]hubblev1beta1.ConditionStatus {
+	result := make(map[hubblev1beta1.ConditionType]hubblev1beta1.ConditionStatus)
+	for _, condition := range status.Conditions {
+		result[condition.Type] = condition.Status
+	}
+	return result
+}
+
+func Test_SetBackendConditions(t *testing.T) {
+
+	applyReport := report.New()
+	applyReport.Executed(report.Redshift, "CreateUser", "jwr_bianalyst")
+	applyReport.Failed(report.IAM, "RoleCreated", "bianalyst", fmt.Errorf("access denied"))
+
+	testCases := []struct {
+		name     string
+		err      error
+		dryRun   bool
+		expected map[hubblev1beta1.ConditionType]hubblev1beta1.ConditionStatus
+	}{
+		{
+			name: "applied",
+			expected: map[hubblev1beta1.ConditionType]hubblev1beta1.ConditionStatus{
+				hubblev1beta1.ConditionRedshiftSynced: hubblev1beta1.ConditionTrue,
+				hubblev1beta1.ConditionIAMSynced:      hubblev1beta1.ConditionTrue,
+				hubblev1beta1.ConditionGoogleSynced:   hubblev1beta1.ConditionTrue,
+			},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			expected: map[hubblev1beta1.ConditionType]hubblev1beta1.ConditionStatus{
+				hubblev1beta1.ConditionRedshiftSynced: hubblev1beta1.ConditionUnknown,
+				hubblev1beta1.ConditionIAMSynced:      hubblev1beta1.ConditionUnknown,
+				hubblev1beta1.ConditionGoogleSynced:   hubblev1beta1.ConditionUnknown,
+			},
+		},
+		{
+			name: "backend failed",
+			err:  &service.BackendError{Backend: report.IAM, Err: fmt.Errorf("access denied")},
+			expected: map[hubblev1beta1.ConditionType]hubblev1beta1.ConditionStatus{
+				hubblev1beta1.ConditionRedshiftSynced: hubblev1beta1.ConditionTrue,
+				hubblev1beta1.ConditionIAMSynced:      hubblev1beta1.ConditionFalse,
+				hubblev1beta1.ConditionGoogleSynced:   hubblev1beta1.ConditionUnknown,
+			},
+		},
+		{
+			name: "failed before any backend was applied",
+			err:  fmt.Errorf("unable to expand google groups"),
+			expected: map[hubblev1beta1.ConditionType]hubblev1beta1.ConditionStatus{
+				hubblev1beta1.ConditionRedshiftSynced: hubblev1beta1.ConditionUnknown,
+				hubblev1beta1.ConditionIAMSynced:      hubblev1beta1.ConditionUnknown,
+				hubblev1beta1.ConditionGoogleSynced:   hubblev1beta1.ConditionUnknown,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status := &hubblev1beta1.HubbleRbacStatus{}
+			setBackendConditions(status, 2, applyReport, tc.err, tc.dryRun)
+
+			assert.Equal(t, tc.expected, conditionStatuses(status))
+			for _, condition := range status.Conditions {
+				assert.Equal(t, int64(2), condition.ObservedGeneration)
+			}
+		})
+	}
+}
+
+func Test_SetBackendConditions_FailureMessage(t *testing.T) {
+
+	applyReport := report.New()
+	applyReport.Failed(report.IAM, "RoleCreated", "bianalyst", fmt.Errorf("access denied"))
+
+	status := &hubblev1beta1.HubbleRbacStatus{}
+	setBackendConditions(status, 1, applyReport, &service.BackendError{Backend: report.IAM, Err: fmt.Errorf("1 action failed")}, false)
+
+	iam := status.LookupCondition(hubblev1beta1.ConditionIAMSynced)
+	assert.Equal(t, ReasonApplyFailed, iam.Reason)
+	assert.Contains(t, iam.Message, "bianalyst", "the failed actions are listed")
+
+	google := status.LookupCondition(hubblev1beta1.ConditionGoogleSynced)
+	assert.Equal(t, ReasonNotApplied, google.Reason)
+}
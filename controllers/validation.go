@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/validation"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// datalake grants that don't reference a declared external schema are names of glue databases, they are exposed in redshift as external schemas named after the glue database without hyphens
+var datalakeGrantPattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+// the IAM roles redshift assumes to access the data lake
+var iamRolePattern = regexp.MustCompile(`^arn:aws:iam::\d{12}:role/.+$`)
+
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// the schemas, tables and columns of table grants are used as identifiers in the grant statements
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var validPrivileges = map[hubblev1beta1.DatawarehousePrivilege]bool{"read": true, "write": true, "owner": true}
+
+// the limits of the max session duration of an IAM role
+const (
+	minSessionDuration = time.Hour
+	maxSessionDuration = 12 * time.Hour
+)
+
+// Returns the session duration of the role, or zero if it uses the default
+func sessionDuration(role hubblev1beta1.Role) time.Duration {
+	if role.SessionDuration == nil {
+		return 0
+	}
+	return role.SessionDuration.Duration
+}
+
+// the principals that may assume a role are IAM roles, users or whole accounts
+var trustedPrincipalPattern = regexp.MustCompile(`^arn:aws:iam::\d{12}:(root|role/.+|user/.+)$`)
+
+// google groups are referenced by their email
+var googleGroupPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+
+func datalakeGrantShortName(name string) string {
+	return strings.ReplaceAll(name, "-", "")
+}
+
+// Records an error for every name that has been seen before
+func checkDuplicate(errors *validation.Errors, seen map[string]bool, path string, kind string, name string) {
+	if seen[name] {
+		errors.Add(path, "duplicate %s: %s", kind, name)
+	}
+	seen[name] = true
+}
+
+// The names declared in the spec that the roles, users and teams reference
+type declarations struct {
+	databases       map[string]bool
+	devDatabases    map[string]bool
+	policies        map[string]bool
+	externalSchemas map[string]bool
+	datalakeGrants  map[string]string //the glue database exposed by every external schema, by the name of the schema
+	roles           map[string]bool
+	users           map[string]bool
+}
+
+// Walks the whole spec and returns all the errors found, with the path of every invalid field.
+func validateSpec(spec *hubblev1beta1.HubbleRbacSpec) validation.Errors {
+
+	var errors validation.Errors
+
+	declared := declarations{
+		databases:       make(map[string]bool),
+		devDatabases:    make(map[string]bool),
+		policies:        make(map[string]bool),
+		externalSchemas: make(map[string]bool),
+		datalakeGrants:  make(map[string]string),
+		roles:           make(map[string]bool),
+		users:           make(map[string]bool),
+	}
+
+	validateDatabases(&errors, spec, &declared)
+	validateExternalSchemas(&errors, spec.ExternalSchemas, &declared)
+	validateRoles(&errors, spec.Roles, &declared)
+	validateUsers(&errors, spec.Users, &declared)
+	validateTeams(&errors, spec.Teams, &declared)
+
+	return errors
+}
+
+// Validates the databases, developer databases and policies the roles reference
+func validateDatabases(errors *validation.Errors, spec *hubblev1beta1.HubbleRbacSpec, declared *declarations) {
+
+	for i, database := range spec.Databases {
+		checkDuplicate(errors, declared.databases, validation.Index("spec.databases", i), "database name", database.Name)
+	}
+
+	for i, database := range spec.DevDatabases {
+		checkDuplicate(errors, declared.devDatabases, validation.Index("spec.devDatabases", i), "developer database name", database.Name)
+	}
+
+	for i, policy := range spec.Policies {
+		checkDuplicate(errors, declared.policies, validation.Index("spec.policies", i), "policy name", policy.Name)
+	}
+}
+
+func validateExternalSchemas(errors *validation.Errors, schemas []hubblev1beta1.ExternalSchema, declared *declarations) {
+
+	for i, schema := range schemas {
+		path := validation.Index("spec.externalSchemas", i)
+		checkDuplicate(errors, declared.externalSchemas, path+".name", "external schema name", schema.Name)
+		if !datalakeGrantPattern.MatchString(schema.GlueDatabase) {
+			errors.Add(path+".glueDatabase", "invalid glue database name: %s", schema.GlueDatabase)
+		}
+		for j, role := range schema.IamRoles {
+			if !iamRolePattern.MatchString(role) {
+				errors.Add(validation.Index(path+".iamRoles", j), "invalid IAM role: %s is not the ARN of an IAM role", role)
+			}
+		}
+		if schema.CatalogRegion != "" && !awsRegionPattern.MatchString(schema.CatalogRegion) {
+			errors.Add(path+".catalogRegion", "invalid catalog region: %s is not an AWS region", schema.CatalogRegion)
+		}
+		declared.datalakeGrants[schema.Name] = schema.GlueDatabase
+	}
+}
+
+func validateRoles(errors *validation.Errors, roles []hubblev1beta1.Role, declared *declarations) {
+
+	for i, role := range roles {
+		path := validation.Index("spec.roles", i)
+		checkDuplicate(errors, declared.roles, path, "role name", role.Name)
+
+		validateReferences(errors, path, role, declared)
+		validateDatalakeGrants(errors, path, role, declared)
+		//the session duration in google is the shortest of the user's roles, so it never exceeds the max session duration of any of them as long as the roles are within the bounds
+		if duration := sessionDuration(role); role.SessionDuration != nil && (duration < minSessionDuration || duration > maxSessionDuration || duration%time.Second != 0) {
+			errors.Add(path+".sessionDuration", "invalid session duration: %s must be whole seconds between %s and %s", duration, minSessionDuration, maxSessionDuration)
+		}
+		validateDatawarehouseGrants(errors, path, role)
+		for j, principal := range role.TrustedPrincipals {
+			if !trustedPrincipalPattern.MatchString(principal) {
+				errors.Add(validation.Index(path+".trustedPrincipals", j), "invalid trusted principal: %s is not the ARN of an IAM role, user or account", principal)
+			}
+		}
+		for j, group := range role.GoogleGroups {
+			if !googleGroupPattern.MatchString(group) {
+				errors.Add(validation.Index(path+".googleGroups", j), "invalid google group: %s is not an email", group)
+			}
+		}
+	}
+
+	//the roles may extend roles declared after them, so the inheritance is validated once all the roles are known
+	extends := make(map[string][]string)
+	for _, role := range roles {
+		extends[role.Name] = append(extends[role.Name], role.Extends...)
+	}
+	for i, role := range roles {
+		path := validation.Index("spec.roles", i)
+		for j, name := range role.Extends {
+			if !declared.roles[name] {
+				errors.Add(validation.Index(path+".extends", j), "no such role: %s", name)
+			}
+		}
+		if err := hubble.CheckInheritance(extends, role.Name); err != nil {
+			errors.Add(path+".extends", "%v", err)
+		}
+	}
+}
+
+// Checks that the databases, developer databases and policies of the role have been declared
+func validateReferences(errors *validation.Errors, path string, role hubblev1beta1.Role, declared *declarations) {
+
+	for j, name := range role.Databases {
+		if !declared.databases[name] {
+			errors.Add(validation.Index(path+".databases", j), "no such database: %s", name)
+		}
+	}
+	for j, name := range role.DevDatabases {
+		if !declared.devDatabases[name] {
+			errors.Add(validation.Index(path+".devDatabases", j), "no such developer database: %s", name)
+		}
+	}
+	for j, name := range role.Policies {
+		if !declared.policies[name] {
+			errors.Add(validation.Index(path+".policies", j), "no such policy: %s", name)
+		}
+	}
+}
+
+// A datalake grant references a declared external schema or a glue database, no two glue databases may be exposed as the same external schema
+func validateDatalakeGrants(errors *validation.Errors, path string, role hubblev1beta1.Role, declared *declarations) {
+
+	for j, name := range role.DatalakeGrants {
+		grantPath := validation.Index(path+".datalakeGrants", j)
+		if declared.externalSchemas[name] {
+			continue
+		}
+		if !datalakeGrantPattern.MatchString(name) {
+			errors.Add(grantPath, "unknown datalake grant: %s is neither a declared external schema nor a valid glue database name", name)
+			continue
+		}
+		shortName := datalakeGrantShortName(name)
+		if existing, ok := declared.datalakeGrants[shortName]; ok && existing != name {
+			errors.Add(grantPath, "datalake grant %s conflicts with %s, both are exposed as the external schema %s", name, existing, shortName)
+			continue
+		}
+		declared.datalakeGrants[shortName] = name
+	}
+}
+
+// Validates the privilege levels and the table grants, which must not overlap the schemas granted as a whole
+func validateDatawarehouseGrants(errors *validation.Errors, path string, role hubblev1beta1.Role) {
+
+	warehouseGrants := make(map[string]bool)
+	for _, name := range role.DatawarehouseGrants {
+		warehouseGrants[strings.ToLower(name)] = true
+	}
+
+	var privilegeSchemas []string
+	for schema := range role.DatawarehousePrivileges {
+		privilegeSchemas = append(privilegeSchemas, schema)
+	}
+	for _, schema := range sortedCopy(privilegeSchemas) {
+		privilege := role.DatawarehousePrivileges[schema]
+		privilegePath := validation.Key(path+".datawarehousePrivileges", schema)
+		if !warehouseGrants[strings.ToLower(schema)] {
+			errors.Add(privilegePath, "no such datawarehouse grant: %s", schema)
+		}
+		if !validPrivileges[privilege] {
+			errors.Add(privilegePath, "invalid privilege level: %s must be read, write or owner", privilege)
+		}
+	}
+
+	tables := make(map[string]bool)
+	for j, grant := range role.TableGrants {
+		grantPath := validation.Index(path+".tableGrants", j)
+		if !identifierPattern.MatchString(grant.Schema) {
+			errors.Add(grantPath+".schema", "invalid schema name: %s", grant.Schema)
+		}
+		if !identifierPattern.MatchString(grant.Table) {
+			errors.Add(grantPath+".table", "invalid table name: %s", grant.Table)
+		}
+		table := strings.ToLower(grant.Schema + "." + grant.Table)
+		checkDuplicate(errors, tables, grantPath, "table grant", table)
+		if warehouseGrants[strings.ToLower(grant.Schema)] {
+			errors.Add(grantPath, "table %s is already granted by the datawarehouse grant %s", table, grant.Schema)
+		}
+		columns := make(map[string]bool)
+		for k, column := range grant.Columns {
+			columnPath := validation.Index(grantPath+".columns", k)
+			if !identifierPattern.MatchString(column) {
+				errors.Add(columnPath, "invalid column name: %s", column)
+			}
+			checkDuplicate(errors, columns, columnPath, "column", strings.ToLower(column))
+		}
+	}
+}
+
+func validateUsers(errors *validation.Errors, users []hubblev1beta1.User, declared *declarations) {
+
+	emails := make(map[string]bool)
+	for i, user := range users {
+		path := validation.Index("spec.users", i)
+		checkDuplicate(errors, declared.users, path+".name", "user name", user.Name)
+		checkDuplicate(errors, emails, path+".email", "email", user.Email)
+
+		for j, assignment := range user.Roles {
+			if !declared.roles[assignment.Name] {
+				errors.Add(validation.Index(path+".roles", j)+".name", "no such role: %s", assignment.Name)
+			}
+		}
+	}
+}
+
+func validateTeams(errors *validation.Errors, teams []hubblev1beta1.Team, declared *declarations) {
+
+	names := make(map[string]bool)
+	for i, team := range teams {
+		path := validation.Index("spec.teams", i)
+		checkDuplicate(errors, names, path+".name", "team name", team.Name)
+
+		for j, name := range team.Members {
+			if !declared.users[name] {
+				errors.Add(validation.Index(path+".members", j), "no such user: %s", name)
+			}
+		}
+		for j, name := range team.Roles {
+			if !declared.roles[name] {
+				errors.Add(validation.Index(path+".roles", j), "no such role: %s", name)
+			}
+		}
+	}
+}
+
+// Checks that the session duration of every user in google doesn't exceed the max session duration of the IAM roles the user can log into, as AWS rejects the login otherwise.
+// The session duration in google is the shortest of the roles of the user, while the max session duration of an IAM role can't exceed the limit of IAM
+// Validates the merged CRs and the redshift model they resolve to and returns all the errors found
+func ValidateFragments(fragments []Fragment, excluded *redshift.Exclusions, roleBasedAccessControl bool, now time.Time) error {
+
+	model, err := BuildAggregatedHubbleModel(fragments, now)
+	if err != nil {
+		return err
+	}
+
+	redshiftModel, _, _ := (&resolver.Resolver{RoleBasedAccessControl: roleBasedAccessControl}).Resolve(model)
+
+	err = redshiftModel.Validate(excluded)
+	if redshiftErrors, ok := err.(validation.Errors); ok {
+		var errors validation.Errors
+		errors.AddAll("redshift", redshiftErrors)
+		return errors
+	}
+	return err
+}
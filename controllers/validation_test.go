@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const validationHeader = `
+apiVersion: hubble.lunar.tech/v1beta1
+kind: HubbleRbac
+metadata:
+  name: analysts
+  namespace: default
+`
+
+func decodeSpec(t *testing.T, spec string) *hubblev1beta1.HubbleRbacSpec {
+	object, err := DecodeObject([]byte(validationHeader + spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &object.(*hubblev1beta1.HubbleRbac).Spec
+}
+
+func errorPaths(t *testing.T, spec string) map[string]string {
+	result := make(map[string]string)
+	for _, err := range validateSpec(decodeSpec(t, spec)) {
+		result[err.Path] = err.Message
+	}
+	return result
+}
+
+func Test_ValidateSpec(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		spec     string
+		expected map[string]string
+	}{
+		{
+			name: "valid",
+			spec: `
+spec:
+  users:
+  - {name: jwr, email: jwr@lunar.app, roles: [{name: bianalyst}]}
+  teams:
+  - {name: bi, members: [jwr], roles: [bianalyst]}
+  roles:
+  - {name: bianalyst, databases: [prod], datawarehouseGrants: [bi], datalakeGrants: [lake]}
+  databases:
+  - {name: prod, cluster: hubble, database: prod}
+  externalSchemas:
+  - {name: lake, glueDatabase: lake}
+`,
+			expected: map[string]string{},
+		},
+		{
+			name: "databases",
+			spec: `
+spec:
+  roles:
+  - {name: bianalyst, databases: [prod, staging], devDatabases: [dev], policies: [s3]}
+  databases:
+  - {name: prod, cluster: hubble, database: prod}
+  - {name: prod, cluster: hubble, database: prod}
+`,
+			expected: map[string]string{
+				"spec.databases[1]":             "duplicate database name: prod",
+				"spec.roles[0].databases[1]":    "no such database: staging",
+				"spec.roles[0].devDatabases[0]": "no such developer database: dev",
+				"spec.roles[0].policies[0]":     "no such policy: s3",
+			},
+		},
+		{
+			name: "roles",
+			spec: `
+spec:
+  roles:
+  - {name: bianalyst, extends: [analyst, reader]}
+  - {name: analyst, extends: [bianalyst]}
+  - {name: writer}
+  - {name: writer, datawarehousePrivileges: {bi: read}}
+`,
+			expected: map[string]string{
+				"spec.roles[0].extends":                     "cyclic role inheritance: bianalyst -> analyst -> bianalyst",
+				"spec.roles[0].extends[1]":                  "no such role: reader",
+				"spec.roles[1].extends":                     "cyclic role inheritance: analyst -> bianalyst -> analyst",
+				"spec.roles[3]":                             "duplicate role name: writer",
+				"spec.roles[3].datawarehousePrivileges[bi]": "no such datawarehouse grant: bi",
+			},
+		},
+		{
+			name: "users",
+			spec: `
+spec:
+  users:
+  - {name: jwr, email: jwr@lunar.app, roles: [{name: bianalyst}]}
+  - {name: kni, email: jwr@lunar.app}
+  - {name: jwr, email: jwr2@lunar.app}
+  - {name: mbj, email: mbj@lunar.app, roles: [{name: bianalyst}, {name: analyst}]}
+  roles:
+  - {name: bianalyst}
+`,
+			expected: map[string]string{
+				"spec.users[1].email":         "duplicate email: jwr@lunar.app",
+				"spec.users[2].name":          "duplicate user name: jwr",
+				"spec.users[3].roles[1].name": "no such role: analyst",
+			},
+		},
+		{
+			name: "teams",
+			spec: `
+spec:
+  users:
+  - {name: jwr, email: jwr@lunar.app}
+  teams:
+  - {name: bi, members: [jwr, kni], roles: [analyst]}
+  - {name: bi}
+`,
+			expected: map[string]string{
+				"spec.teams[0].members[1]": "no such user: kni",
+				"spec.teams[0].roles[0]":   "no such role: analyst",
+				"spec.teams[1].name":       "duplicate team name: bi",
+			},
+		},
+		{
+			name: "external schemas",
+			spec: `
+spec:
+  externalSchemas:
+  - name: lake
+    glueDatabase: lake
+    iamRoles: ["arn:aws:iam::123456789012:role/redshift", "redshift"]
+    catalogRegion: europe
+  - {name: lake, glueDatabase: lake}
+`,
+			expected: map[string]string{
+				"spec.externalSchemas[0].iamRoles[1]":   "invalid IAM role: redshift is not the ARN of an IAM role",
+				"spec.externalSchemas[0].catalogRegion": "invalid catalog region: europe is not an AWS region",
+				"spec.externalSchemas[1].name":          "duplicate external schema name: lake",
+			},
+		},
+		{
+			name: "session durations",
+			spec: `
+spec:
+  users:
+  - {name: jwr, email: jwr@lunar.app, roles: [{name: bianalyst}, {name: dbtdeveloper}]}
+  - {name: kni, email: kni@lunar.app, roles: [{name: dbtdeveloper}]}
+  - {name: mbj, email: mbj@lunar.app}
+  teams:
+  - {name: dbt, members: [mbj], roles: [dbtdeveloper]}
+  roles:
+  - {name: bianalyst, sessionDuration: 8h}
+  - {name: dbtdeveloper, sessionDuration: 13h}
+`,
+			expected: map[string]string{
+				"spec.roles[1].sessionDuration": "invalid session duration: 13h0m0s must be whole seconds between 1h0m0s and 12h0m0s",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, errorPaths(t, tc.spec))
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package diff
+
+import (
+	"fmt"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
+	"sort"
+)
+
+// Returns the actions that are needed to go from the current to the desired hubble model, without looking at any live state.
+// The models are resolved and reconciled with the given resolver and reconciler config, so the plan matches what the controller would do. All actions are reported as planned.
+func Diff(current hubble.Model, desired hubble.Model, r resolver.Resolver, reconcilerConfig redshift.ReconcilerConfig) *report.ApplyReport {
+
+	currentRedshift, currentIam, currentGoogle := r.Resolve(current)
+	desiredRedshift, desiredIam, desiredGoogle := r.Resolve(desired)
+
+	result := report.New()
+	result.Merge(Redshift(&currentRedshift, &desiredRedshift, reconcilerConfig))
+	result.Merge(IAM(currentIam, desiredIam))
+	result.Merge(Google(currentGoogle, desiredGoogle))
+	return result
+}
+
+func Redshift(current *redshift.Model, desired *redshift.Model, reconcilerConfig redshift.ReconcilerConfig) *report.ApplyReport {
+
+	result := report.New()
+
+	dag := redshift.Reconcile(current, desired, reconcilerConfig)
+
+	for _, task := range dag.Tasks() {
+		result.Planned(report.Redshift, task.Type().String(), fmt.Sprintf("%s/%s", task.ClusterIdentifier(), task.Identifier()))
+	}
+	return result
+}
+
+func databasesEqual(current *iam.DatabaseLoginPolicy, desired *iam.DatabaseLoginPolicy) bool {
+	if len(current.Databases) != len(desired.Databases) {
+		return false
+	}
+	for _, database := range desired.Databases {
+		if current.LookupDatabase(database.ClusterIdentifier, database.Name) == nil {
+			return false
+		}
+	}
+	return current.Email == desired.Email
+}
+
+func iamRole(current *iam.AwsRole, desired *iam.AwsRole) *report.ApplyReport {
+
+	result := report.New()
+
+	for _, desiredPolicy := range desired.Policies {
+		if current.LookupReferencedPolicy(desiredPolicy.Arn) == nil {
+			result.Planned(report.IAM, iam.PolicyAttached.ToString(), desiredPolicy.Arn)
+		}
+	}
+
+	for _, currentPolicy := range current.Policies {
+		if desired.LookupReferencedPolicy(currentPolicy.Arn) == nil {
+			result.Planned(report.IAM, iam.PolicyDetached.ToString(), currentPolicy.Arn)
+		}
+	}
+
+	for _, desiredPolicy := range desired.DatabaseLoginPolicies {
+		currentPolicy := current.LookupDatabaseLoginPolicyForUsername(desiredPolicy.DatabaseUsername)
+		if currentPolicy == nil {
+			result.Planned(report.IAM, iam.PolicyCreated.ToString(), desiredPolicy.DatabaseUsername)
+		} else if !databasesEqual(currentPolicy, desiredPolicy) {
+			result.Planned(report.IAM, iam.PolicyUpdated.ToString(), desiredPolicy.DatabaseUsername)
+		}
+	}
+
+	for _, currentPolicy := range current.DatabaseLoginPolicies {
+		if desired.LookupDatabaseLoginPolicyForUsername(currentPolicy.DatabaseUsername) == nil {
+			result.Planned(report.IAM, iam.PolicyDeleted.ToString(), currentPolicy.DatabaseUsername)
+		}
+	}
+
+	return result
+}
+
+func IAM(current iam.Model, desired iam.Model) *report.ApplyReport {
+
+	result := report.New()
+
+	for _, desiredRole := range desired.Roles {
+		currentRole := current.LookupRole(desiredRole.Name)
+
+		if currentRole == nil {
+			result.Planned(report.IAM, iam.RoleCreated.ToString(), desiredRole.Name)
+			currentRole = &iam.AwsRole{Name: desiredRole.Name}
+		}
+
+		roleReport := iamRole(currentRole, desiredRole)
+		if current.LookupRole(desiredRole.Name) != nil {
+			if currentRole.MaxSessionDuration != desiredRole.MaxSessionDuration {
+				roleReport.Planned(report.IAM, iam.MaxSessionDurationUpdated.ToString(), desiredRole.Name)
+			}
+			if !stringsEqual(currentRole.TrustedPrincipals, desiredRole.TrustedPrincipals) {
+				roleReport.Planned(report.IAM, iam.TrustPolicyUpdated.ToString(), desiredRole.Name)
+			}
+		}
+		result.Merge(roleReport)
+
+		if current.LookupRole(desiredRole.Name) != nil && len(roleReport.Actions) > 0 {
+			result.Planned(report.IAM, iam.RoleUpdated.ToString(), desiredRole.Name)
+		}
+	}
+
+	for _, currentRole := range current.Roles {
+		if desired.LookupRole(currentRole.Name) == nil {
+			result.Merge(iamRole(currentRole, &iam.AwsRole{Name: currentRole.Name}))
+			result.Planned(report.IAM, iam.RoleDeleted.ToString(), currentRole.Name)
+		}
+	}
+
+	return result
+}
+
+func sortedRoles(user *google.User) []string {
+	if user == nil {
+		return []string{}
+	}
+	roles := user.AssignedTo()
+	sort.Strings(roles)
+	return roles
+}
+
+func rolesEqual(current []string, desired []string) bool {
+	if len(current) != len(desired) {
+		return false
+	}
+	for i := range desired {
+		if current[i] != desired[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if the two lists hold the same strings, in any order
+func stringsEqual(current []string, desired []string) bool {
+	a := append([]string{}, current...)
+	b := append([]string{}, desired...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return rolesEqual(a, b)
+}
+
+func Google(current google.Model, desired google.Model) *report.ApplyReport {
+
+	result := report.New()
+
+	for _, desiredUser := range desired.Users {
+		currentUser := current.LookupUser(desiredUser.Email)
+		if !rolesEqual(sortedRoles(currentUser), sortedRoles(desiredUser)) {
+			result.Planned(report.Google, google.UpdateRoles, desiredUser.Email)
+		} else if currentUser != nil && currentUser.SessionDuration != desiredUser.SessionDuration {
+			result.Planned(report.Google, google.UpdateRoles, desiredUser.Email)
+		}
+	}
+
+	//the google applier only updates the users of the model, so the roles of users that are no longer part of it are left alone
+
+	return result
+}
@@ -0,0 +1,132 @@
+package diff
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func buildModel(roleNames ...string) hubble.Model {
+
+	model := hubble.Model{}
+	database := model.AddDatabase("hubble-unstable", "prod")
+	user := model.AddUser("jwr", "jwr@lunar.app")
+
+	for _, name := range roleNames {
+		role := model.AddRole(name, []hubble.DataSet{"bi"})
+		role.GrantAccess(database)
+		user.Assign(role)
+	}
+	return model
+}
+
+func diffModels(current hubble.Model, desired hubble.Model) *report.ApplyReport {
+	return Diff(current, desired, resolver.Resolver{}, redshift.DefaultReconcilerConfig())
+}
+
+func Test_Diff_NoChanges(t *testing.T) {
+
+	assert := assert.New(t)
+
+	result := diffModels(buildModel("bianalyst"), buildModel("bianalyst"))
+
+	assert.Empty(result.Actions)
+}
+
+func Test_Diff_RoleAdded(t *testing.T) {
+
+	assert := assert.New(t)
+
+	result := diffModels(hubble.Model{}, buildModel("bianalyst"))
+
+	assert.NotEmpty(result.ForBackend(report.Redshift), "the redshift user, group and grants are created")
+	assert.Equal(1, result.Count(report.Google, report.Planned), "the google user gets the role")
+	assert.Equal(len(result.Actions), len(result.InState(report.Planned)), "all actions are planned")
+
+	var iamActions []string
+	for _, action := range result.ForBackend(report.IAM) {
+		iamActions = append(iamActions, action.Type+":"+action.Name)
+	}
+	assert.ElementsMatch([]string{"RoleCreated:bianalyst", "PolicyCreated:jwr_bianalyst"}, iamActions)
+}
+
+func Test_Diff_RoleRemoved(t *testing.T) {
+
+	assert := assert.New(t)
+
+	result := diffModels(buildModel("bianalyst", "dbtdeveloper"), buildModel("bianalyst"))
+
+	var iamActions []string
+	for _, action := range result.ForBackend(report.IAM) {
+		iamActions = append(iamActions, action.Type+":"+action.Name)
+	}
+	assert.ElementsMatch([]string{"PolicyDeleted:jwr_dbtdeveloper", "RoleDeleted:dbtdeveloper"}, iamActions)
+	assert.Equal(1, result.Count(report.Google, report.Planned), "the role is removed from the google user")
+}
+
+func Test_Diff_SessionDurationChanged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	desired := buildModel("bianalyst")
+	desired.Roles[0].SessionDuration = 8 * 60 * 60
+
+	result := diffModels(buildModel("bianalyst"), desired)
+
+	var iamActions []string
+	for _, action := range result.ForBackend(report.IAM) {
+		iamActions = append(iamActions, action.Type+":"+action.Name)
+	}
+	assert.ElementsMatch([]string{"MaxSessionDurationUpdated:bianalyst", "RoleUpdated:bianalyst"}, iamActions)
+	assert.Equal(1, result.Count(report.Google, report.Planned), "the session duration of the google user is updated")
+	assert.Empty(result.ForBackend(report.Redshift))
+}
+
+func Test_Diff_TrustedPrincipalsChanged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	desired := buildModel("bianalyst")
+	desired.Roles[0].TrustedPrincipals = []string{"arn:aws:iam::478824949770:role/ci"}
+
+	result := diffModels(buildModel("bianalyst"), desired)
+
+	var iamActions []string
+	for _, action := range result.ForBackend(report.IAM) {
+		iamActions = append(iamActions, action.Type+":"+action.Name)
+	}
+	assert.ElementsMatch([]string{"TrustPolicyUpdated:bianalyst", "RoleUpdated:bianalyst"}, iamActions)
+	assert.Empty(result.ForBackend(report.Google))
+}
+
+func Test_Diff_UserRemoved(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildModel("bianalyst")
+	desired := buildModel("bianalyst")
+	removed := current.AddUser("nra", "nra@lunar.app")
+	removed.Assign(current.Roles[0])
+
+	result := diffModels(current, desired)
+
+	assert.Empty(result.ForBackend(report.Google), "the google applier leaves the users that are not part of the model alone")
+}
+
+func Test_Diff_RoleBasedAccessControl(t *testing.T) {
+
+	assert := assert.New(t)
+
+	result := Diff(hubble.Model{}, buildModel("bianalyst"), resolver.Resolver{RoleBasedAccessControl: true}, redshift.DefaultReconcilerConfig())
+
+	var redshiftTypes []string
+	for _, action := range result.ForBackend(report.Redshift) {
+		redshiftTypes = append(redshiftTypes, action.Type)
+	}
+	assert.Contains(redshiftTypes, "CreateRole")
+	assert.Contains(redshiftTypes, "GrantRole")
+	assert.NotContains(redshiftTypes, "CreateGroup", "redshift roles are planned instead of groups")
+}
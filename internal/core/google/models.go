@@ -1,8 +1,12 @@
 package google
 
+//The change applied to a google user, it names the actions of the apply reports
+const UpdateRoles = "UpdateRoles"
+
 type User struct {
-	Email string
-	Roles map[string]bool
+	Email           string
+	Roles           map[string]bool
+	SessionDuration int //the duration of the sessions the user logs into AWS with in seconds, it applies to all the roles of the user
 }
 
 type Model struct {
@@ -1,5 +1,10 @@
 package hubble
 
+import (
+	"fmt"
+	"strings"
+)
+
 func (m *Model) AddUser(username string, email string) *User {
 	user := User{
 		Username:   username,
@@ -70,6 +75,36 @@ func (r *Role) RevokeAccess(database *Database) {
 	r.GrantedDatabases = newDatabaseList
 }
 
+func (r *Role) GrantPrivilege(dataSet DataSet, privilege Privilege) {
+	if r.Privileges == nil {
+		r.Privileges = make(map[DataSet]Privilege)
+	}
+	r.Privileges[dataSet] = privilege
+}
+
+// Returns the privilege level the role is granted on the data set
+func (r *Role) PrivilegeOn(dataSet DataSet) Privilege {
+	privilege, ok := r.Privileges[dataSet]
+	if !ok {
+		return ReadPrivilege
+	}
+	return privilege
+}
+
+// Returns true if the privilege level includes the privileges of the other level
+func (p Privilege) Includes(other Privilege) bool {
+	rank := map[Privilege]int{ReadPrivilege: 0, WritePrivilege: 1, OwnerPrivilege: 2}
+	return rank[p] >= rank[other]
+}
+
+func (r *Role) GrantTable(dataSet DataSet, table string, columns ...string) {
+	r.TableGrants = append(r.TableGrants, &TableGrant{DataSet: dataSet, Table: table, Columns: columns})
+}
+
+func (r *Role) Extend(base *Role) {
+	r.Extends = append(r.Extends, base)
+}
+
 func (u *User) Assign(role *Role) {
 	u.AssignedTo = append(u.AssignedTo, role)
 }
@@ -86,3 +121,244 @@ func (u *User) Unassign(role *Role) {
 
 	u.AssignedTo = newAssignedToList
 }
+
+func (m *Model) AddTeam(name string) *Team {
+	team := Team{
+		Name:    name,
+		Members: []*User{},
+		Roles:   []*Role{},
+	}
+	m.Teams = append(m.Teams, &team)
+
+	return &team
+}
+
+func (t *Team) AddMember(user *User) {
+	t.Members = append(t.Members, user)
+}
+
+func (t *Team) Assign(role *Role) {
+	t.Roles = append(t.Roles, role)
+}
+
+// Returns the roles assigned to the user directly and through the teams the user is a member of, each role only once
+func (m *Model) RolesOf(user *User) []*Role {
+
+	var result []*Role
+	assigned := make(map[string]bool)
+
+	assign := func(roles []*Role) {
+		for _, role := range roles {
+			if !assigned[role.Name] {
+				assigned[role.Name] = true
+				result = append(result, role)
+			}
+		}
+	}
+
+	assign(user.AssignedTo)
+	for _, team := range m.Teams {
+		for _, member := range team.Members {
+			if member == user {
+				assign(team.Roles)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// Returns an error naming the chain of roles that leads from the role into a cycle, e.g. a -> b -> c -> b, if the roles extend each other in a cycle.
+// The roles are given by name, mapped to the names of the roles they extend, so the inheritance can also be checked before the model is built.
+func CheckInheritance(extends map[string][]string, role string) error {
+
+	visited := make(map[string]bool)
+
+	var visit func(path []string) error
+	visit = func(path []string) error {
+		for _, base := range extends[path[len(path)-1]] {
+			for _, name := range path {
+				if name == base {
+					return fmt.Errorf("cyclic role inheritance: %s", strings.Join(append(path, base), " -> "))
+				}
+			}
+			if visited[base] {
+				continue
+			}
+			visited[base] = true
+
+			err := visit(append(path, base))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return visit([]string{role})
+}
+
+// Returns the roles this role extends, directly or indirectly, each role only once. An error is returned if the roles extend each other in a cycle.
+func (r *Role) ancestors() ([]*Role, error) {
+
+	var result []*Role
+	visited := make(map[*Role]bool)
+	extends := make(map[string][]string)
+
+	var visit func(role *Role)
+	visit = func(role *Role) {
+		for _, base := range role.Extends {
+			extends[role.Name] = append(extends[role.Name], base.Name)
+		}
+		for _, base := range role.Extends {
+			if visited[base] {
+				continue
+			}
+			visited[base] = true
+			result = append(result, base)
+			visit(base)
+		}
+	}
+
+	visited[r] = true
+	visit(r)
+
+	err := CheckInheritance(extends, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Copies the grants of the extended roles into every role, so every role holds all of its grants and doesn't extend any roles.
+// Returns an error if the roles extend each other in a cycle.
+func (m *Model) Flatten() error {
+
+	flattened := make(map[*Role]Role)
+
+	for _, role := range m.Roles {
+		ancestors, err := role.ancestors()
+		if err != nil {
+			return err
+		}
+
+		//the grants are copied, so the slices of the role are never appended to in place
+		flat := *role
+		flat.Extends = nil
+		//the direct bases are kept, so the roles can be nested in redshift
+		if len(role.Extends) > 0 {
+			flat.Bases = role.Extends
+		}
+		flat.GrantedDatabases = append([]*Database{}, role.GrantedDatabases...)
+		flat.GrantedDevDatabases = append([]*DevDatabase{}, role.GrantedDevDatabases...)
+		flat.GrantedGlueDatabases = append([]*GlueDatabase{}, role.GrantedGlueDatabases...)
+		flat.Acl = append([]DataSet{}, role.Acl...)
+		flat.TableGrants = append([]*TableGrant{}, role.TableGrants...)
+		flat.Privileges = nil
+		for dataSet, privilege := range role.Privileges {
+			flat.GrantPrivilege(dataSet, privilege)
+		}
+		flat.Policies = append([]*PolicyReference{}, role.Policies...)
+		for _, base := range ancestors {
+			for _, database := range base.GrantedDatabases {
+				if !containsDatabase(flat.GrantedDatabases, database) {
+					flat.GrantedDatabases = append(flat.GrantedDatabases, database)
+				}
+			}
+			for _, database := range base.GrantedDevDatabases {
+				if !containsDevDatabase(flat.GrantedDevDatabases, database) {
+					flat.GrantedDevDatabases = append(flat.GrantedDevDatabases, database)
+				}
+			}
+			for _, database := range base.GrantedGlueDatabases {
+				if !containsGlueDatabase(flat.GrantedGlueDatabases, database) {
+					flat.GrantedGlueDatabases = append(flat.GrantedGlueDatabases, database)
+				}
+			}
+			for _, dataSet := range base.Acl {
+				if !containsDataSet(flat.Acl, dataSet) {
+					flat.Acl = append(flat.Acl, dataSet)
+				}
+			}
+			//the highest privilege level on a data set wins
+			for dataSet, privilege := range base.Privileges {
+				if !flat.PrivilegeOn(dataSet).Includes(privilege) {
+					flat.GrantPrivilege(dataSet, privilege)
+				}
+			}
+			for _, grant := range base.TableGrants {
+				if !containsTableGrant(flat.TableGrants, grant) {
+					flat.TableGrants = append(flat.TableGrants, grant)
+				}
+			}
+			for _, policy := range base.Policies {
+				if !containsPolicy(flat.Policies, policy) {
+					flat.Policies = append(flat.Policies, policy)
+				}
+			}
+		}
+		flattened[role] = flat
+	}
+
+	//the roles are updated in place, as the users refer to them
+	for _, role := range m.Roles {
+		*role = flattened[role]
+	}
+
+	return nil
+}
+
+func containsDatabase(databases []*Database, database *Database) bool {
+	for _, d := range databases {
+		if *d == *database {
+			return true
+		}
+	}
+	return false
+}
+
+func containsDevDatabase(databases []*DevDatabase, database *DevDatabase) bool {
+	for _, d := range databases {
+		if *d == *database {
+			return true
+		}
+	}
+	return false
+}
+
+func containsGlueDatabase(databases []*GlueDatabase, database *GlueDatabase) bool {
+	for _, d := range databases {
+		if d.ShortName == database.ShortName && d.Name == database.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsDataSet(dataSets []DataSet, dataSet DataSet) bool {
+	for _, d := range dataSets {
+		if d == dataSet {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTableGrant(grants []*TableGrant, grant *TableGrant) bool {
+	for _, g := range grants {
+		if g.DataSet == grant.DataSet && g.Table == grant.Table && strings.Join(g.Columns, ",") == strings.Join(grant.Columns, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPolicy(policies []*PolicyReference, policy *PolicyReference) bool {
+	for _, p := range policies {
+		if *p == *policy {
+			return true
+		}
+	}
+	return false
+}
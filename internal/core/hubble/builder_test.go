@@ -0,0 +1,105 @@
+package hubble
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestModel_Flatten(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := Model{}
+	prod := model.AddDatabase("hubble", "prod")
+	unstable := model.AddDatabase("hubble-unstable", "prod")
+	policy := model.AddPolicyReference("arn:aws:iam::478824949770:policy/tmp")
+
+	base := model.AddRole("analyst_base", []DataSet{"core"})
+	base.GrantAccess(prod)
+	base.Policies = append(base.Policies, policy)
+
+	biAnalyst := model.AddRole("bi_analyst", []DataSet{"bi", "core"})
+	biAnalyst.GrantAccess(unstable)
+	biAnalyst.Extend(base)
+
+	biAnalyst.GrantTable("credit", "loans", "id", "amount")
+	biAnalyst.GrantPrivilege("bi", WritePrivilege)
+	biAnalyst.GrantPrivilege("core", OwnerPrivilege)
+
+	creditAnalyst := model.AddRole("credit_analyst", []DataSet{"credit"})
+	creditAnalyst.GrantTable("credit", "loans", "id", "amount")
+	creditAnalyst.GrantPrivilege("bi", OwnerPrivilege)
+	creditAnalyst.GrantPrivilege("credit", WritePrivilege)
+	creditAnalyst.Extend(biAnalyst)
+
+	user := model.AddUser("jwr", "jwr@lunar.app")
+	user.Assign(creditAnalyst)
+
+	assert.NoError(model.Flatten())
+
+	assert.Equal([]DataSet{"core"}, base.Acl, "the base role is unchanged")
+	assert.Equal([]*Database{prod}, base.GrantedDatabases)
+
+	assert.Equal([]DataSet{"bi", "core"}, biAnalyst.Acl, "inherited grants are not duplicated")
+	assert.Equal([]*Database{unstable, prod}, biAnalyst.GrantedDatabases)
+	assert.Equal([]*PolicyReference{policy}, biAnalyst.Policies)
+	assert.Empty(biAnalyst.Extends)
+	assert.Equal([]*Role{base}, biAnalyst.Bases, "the direct bases are kept")
+
+	assert.Equal([]DataSet{"credit", "bi", "core"}, creditAnalyst.Acl, "grants are inherited through several levels")
+	assert.Equal([]*Database{unstable, prod}, creditAnalyst.GrantedDatabases)
+	assert.Equal([]*PolicyReference{policy}, creditAnalyst.Policies)
+	assert.Equal(1, len(creditAnalyst.TableGrants), "inherited table grants are not duplicated")
+	assert.Equal(map[DataSet]Privilege{"bi": OwnerPrivilege, "core": OwnerPrivilege, "credit": WritePrivilege}, creditAnalyst.Privileges, "the highest privilege level on a data set wins")
+	assert.Equal(ReadPrivilege, base.PrivilegeOn("core"), "the base role is still read only")
+	assert.Same(creditAnalyst, user.AssignedTo[0], "the roles are flattened in place")
+}
+
+func TestModel_Flatten_Cycle(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := Model{}
+	a := model.AddRole("a", []DataSet{})
+	b := model.AddRole("b", []DataSet{})
+	c := model.AddRole("c", []DataSet{})
+	a.Extend(b)
+	b.Extend(c)
+	c.Extend(b)
+
+	assert.EqualError(model.Flatten(), "cyclic role inheritance: a -> b -> c -> b")
+}
+
+func TestCheckInheritance(t *testing.T) {
+
+	extends := map[string][]string{
+		"a": {"b"},
+		"b": {"c", "d"},
+		"c": {"d"},
+		"e": {"f"},
+		"f": {"g"},
+		"g": {"e"},
+	}
+
+	testCases := []struct {
+		role     string
+		expected string
+	}{
+		{role: "a"},
+		{role: "c"},
+		{role: "unknown"},
+		{role: "e", expected: "cyclic role inheritance: e -> f -> g -> e"},
+		{role: "g", expected: "cyclic role inheritance: g -> e -> f -> g"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.role, func(t *testing.T) {
+			err := CheckInheritance(extends, tc.role)
+			if tc.expected == "" {
+				assert.NoError(t, err, "roles may share bases without a cycle")
+			} else {
+				assert.EqualError(t, err, tc.expected)
+			}
+		})
+	}
+}
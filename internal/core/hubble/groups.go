@@ -0,0 +1,130 @@
+package hubble
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Looks up the members of the google groups referenced by the roles
+type GroupDirectory interface {
+	// Returns the emails of all the users in the group, including the users of nested groups
+	GroupMembers(groupEmail string) ([]string, error)
+}
+
+// The usernames of the users found in google groups are derived from their emails by a regular expression, the username is the first submatch of the expression
+type UsernameRule struct {
+	pattern *regexp.Regexp
+}
+
+// By default the username is the local part of the email, e.g. jwr for jwr@lunar.app
+const DefaultUsernamePattern = `^([^@]+)@`
+
+var invalidUsernameCharacters = regexp.MustCompile(`[^a-z0-9_]`)
+
+func NewUsernameRule(pattern string) (*UsernameRule, error) {
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid username pattern %s: %w", pattern, err)
+	}
+	if compiled.NumSubexp() < 1 {
+		return nil, fmt.Errorf("invalid username pattern %s: the pattern must have a group that captures the username", pattern)
+	}
+	return &UsernameRule{pattern: compiled}, nil
+}
+
+// Derives the username from the email. The username is lower cased and the characters that aren't allowed in a redshift user name are replaced by underscores, e.g. john.doe@lunar.app becomes john_doe.
+func (r *UsernameRule) Username(email string) (string, error) {
+	match := r.pattern.FindStringSubmatch(email)
+	if len(match) < 2 || match[1] == "" {
+		return "", fmt.Errorf("unable to derive a username from %s with the pattern %s", email, r.pattern)
+	}
+	return invalidUsernameCharacters.ReplaceAllString(strings.ToLower(match[1]), "_"), nil
+}
+
+func (m *Model) userByEmail(email string) *User {
+	for _, user := range m.Users {
+		if strings.EqualFold(user.Email, email) {
+			return user
+		}
+	}
+	return nil
+}
+
+func (m *Model) userByName(username string) *User {
+	for _, user := range m.Users {
+		if user.Username == username {
+			return user
+		}
+	}
+	return nil
+}
+
+func (u *User) isAssigned(role *Role) bool {
+	for _, r := range u.AssignedTo {
+		if r.Name == role.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// Looks up the members of every google group referenced by the roles, by the email of the group. Returns nil if no groups are referenced.
+// The members are sorted, so the result can be compared with the members found by an earlier lookup.
+func (m *Model) GroupMembers(directory GroupDirectory) (map[string][]string, error) {
+
+	var result map[string][]string
+
+	for _, role := range m.Roles {
+		for _, group := range role.GoogleGroups {
+			if _, ok := result[group]; ok {
+				continue
+			}
+			emails, err := directory.GroupMembers(group)
+			if err != nil {
+				return nil, fmt.Errorf("unable to look up the members of the google group %s: %w", group, err)
+			}
+			if result == nil {
+				result = make(map[string][]string)
+			}
+			result[group] = append([]string{}, emails...)
+			sort.Strings(result[group])
+		}
+	}
+
+	return result, nil
+}
+
+// Assigns the roles to the members of the google groups they reference. The members that aren't declared as users are added to the model with a username derived by the rule.
+// Returns an error if a group can't be looked up, or if a derived username is already taken by a user with another email.
+func (m *Model) ExpandGroups(directory GroupDirectory, rule *UsernameRule) error {
+
+	members, err := m.GroupMembers(directory)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range m.Roles {
+		for _, group := range role.GoogleGroups {
+			for _, email := range members[group] {
+				user := m.userByEmail(email)
+				if user == nil {
+					username, err := rule.Username(email)
+					if err != nil {
+						return err
+					}
+					if existing := m.userByName(username); existing != nil {
+						return fmt.Errorf("the username %s of %s, a member of the google group %s, is already used by %s", username, email, group, existing.Email)
+					}
+					user = m.AddUser(username, email)
+				}
+				if !user.isAssigned(role) {
+					user.Assign(role)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package hubble
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeDirectory map[string][]string
+
+func (d fakeDirectory) GroupMembers(groupEmail string) ([]string, error) {
+	members, ok := d[groupEmail]
+	if !ok {
+		return nil, fmt.Errorf("no such group: %s", groupEmail)
+	}
+	return members, nil
+}
+
+func TestUsernameRule(t *testing.T) {
+
+	assert := assert.New(t)
+
+	rule, err := NewUsernameRule(DefaultUsernamePattern)
+	assert.NoError(err)
+
+	username, err := rule.Username("John.Doe@lunar.app")
+	assert.NoError(err)
+	assert.Equal("john_doe", username)
+
+	_, err = rule.Username("not-an-email")
+	assert.Error(err)
+
+	rule, err = NewUsernameRule(`^([a-z]+)\.`)
+	assert.NoError(err)
+	username, err = rule.Username("john.doe@lunar.app")
+	assert.NoError(err)
+	assert.Equal("john", username)
+
+	_, err = NewUsernameRule(`@lunar\.app$`)
+	assert.Error(err, "the pattern must capture the username")
+}
+
+func TestModel_ExpandGroups(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := Model{}
+	biAnalyst := model.AddRole("bi_analyst", []DataSet{"bi"})
+	biAnalyst.GoogleGroups = []string{"analysts@lunar.app"}
+	creditAnalyst := model.AddRole("credit_analyst", []DataSet{"credit"})
+	creditAnalyst.GoogleGroups = []string{"analysts@lunar.app", "credit@lunar.app"}
+
+	jwr := model.AddUser("jwr", "jwr@lunar.app")
+	jwr.Assign(biAnalyst)
+
+	directory := fakeDirectory{
+		"analysts@lunar.app": {"jwr@lunar.app", "abc@lunar.app"},
+		"credit@lunar.app":   {"abc@lunar.app"},
+	}
+	rule, _ := NewUsernameRule(DefaultUsernamePattern)
+
+	assert.NoError(model.ExpandGroups(directory, rule))
+
+	assert.Len(model.Users, 2, "the members are only added once")
+	assert.Equal([]*Role{biAnalyst, creditAnalyst}, jwr.AssignedTo, "a declared user is assigned the role of the group, and the role isn't assigned twice")
+
+	abc := model.Users[1]
+	assert.Equal("abc", abc.Username)
+	assert.Equal("abc@lunar.app", abc.Email)
+	assert.Equal([]*Role{biAnalyst, creditAnalyst}, abc.AssignedTo)
+}
+
+func TestModel_ExpandGroups_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	rule, _ := NewUsernameRule(DefaultUsernamePattern)
+
+	model := Model{}
+	model.AddRole("bi_analyst", []DataSet{"bi"}).GoogleGroups = []string{"missing@lunar.app"}
+	assert.Error(model.ExpandGroups(fakeDirectory{}, rule))
+
+	model = Model{}
+	model.AddRole("bi_analyst", []DataSet{"bi"}).GoogleGroups = []string{"analysts@lunar.app"}
+	model.AddUser("jwr", "jwr@lunar.app")
+	err := model.ExpandGroups(fakeDirectory{"analysts@lunar.app": {"jwr@other.com"}}, rule)
+	assert.EqualError(err, "the username jwr of jwr@other.com, a member of the google group analysts@lunar.app, is already used by jwr@lunar.app")
+}
+
+func TestModel_GroupMembers(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := Model{}
+	model.AddRole("analyst", []DataSet{"bi"})
+
+	members, err := model.GroupMembers(fakeDirectory{})
+	assert.NoError(err)
+	assert.Nil(members, "no groups are looked up if none are referenced")
+
+	model.AddRole("bi_analyst", []DataSet{"bi"}).GoogleGroups = []string{"analysts@lunar.app"}
+	model.AddRole("credit_analyst", []DataSet{"credit"}).GoogleGroups = []string{"analysts@lunar.app", "credit@lunar.app"}
+
+	members, err = model.GroupMembers(fakeDirectory{
+		"analysts@lunar.app": {"jwr@lunar.app", "abc@lunar.app"},
+		"credit@lunar.app":   {},
+	})
+	assert.NoError(err)
+	assert.Equal(map[string][]string{
+		"analysts@lunar.app": {"abc@lunar.app", "jwr@lunar.app"},
+		"credit@lunar.app":   {},
+	}, members, "the members are sorted")
+}
@@ -3,6 +3,22 @@ package hubble
 //An identifier for a group of related tables. In redshift this corresponds to a schema.
 type DataSet string
 
+//The privilege level a role is granted on a data set
+type Privilege string
+
+const (
+	ReadPrivilege  Privilege = "read"  //the role may read the data set
+	WritePrivilege Privilege = "write" //the role may also insert, update and delete data in the data set
+	OwnerPrivilege Privilege = "owner" //the role may also create tables in the data set
+)
+
+//Grants select on a single table of a data set, or only on some of its columns, without granting the rest of the data set.
+type TableGrant struct {
+	DataSet DataSet  //the data set the table belongs to
+	Table   string   //the name of the table
+	Columns []string //the granted columns. If empty, the whole table is granted
+}
+
 type Database struct {
 	ClusterIdentifier string //the identifier of the cluster on which the database resides
 	Name              string
@@ -18,8 +34,10 @@ type DevDatabase struct {
 //If a glue database has been declared an "external schema" will be created in redshift that points to the glue database
 //A glue database can be used to query the S3 data lake from redshift/athena/etc.
 type GlueDatabase struct {
-	ShortName string //the name of the external schema in redshift
-	Name      string //the name of database in AWS Glue
+	ShortName     string   //the name of the external schema in redshift
+	Name          string   //the name of database in AWS Glue
+	IamRoles      []string //the ARNs of the IAM roles used to access the glue database, chained in the given order. If empty, the default datalake role is used
+	CatalogRegion string   //the AWS region of the data catalog. If empty, the region of the cluster is used
 }
 
 //TODO: as we should keep the hubble model technology agnostic we should remove the "Glue" part of this type name.
@@ -32,12 +50,29 @@ type User struct {
 
 //If a user is assigned a role it can log into that role from the terminal and access the granted resources.
 type Role struct {
-	Name                 string             //the name of the role
-	GrantedDatabases     []*Database        //the set of databases this user has access to
-	GrantedDevDatabases  []*DevDatabase     //the set of dev databases that this user has access to
-	GrantedGlueDatabases []*GlueDatabase    //the set of glue databases this user has access to
-	Acl                  []DataSet          //the set of data groups this user has access to. E.g. a credit analyst should only have access to credit related data.
-	Policies             []*PolicyReference //the set of extra IAM policies this user has access to. Those could be policies required by the CLI's that are part of the analyst tool chain.
+	Name                 string                //the name of the role
+	GrantedDatabases     []*Database           //the set of databases this user has access to
+	GrantedDevDatabases  []*DevDatabase        //the set of dev databases that this user has access to
+	GrantedGlueDatabases []*GlueDatabase       //the set of glue databases this user has access to
+	Acl                  []DataSet             //the set of data groups this user has access to. E.g. a credit analyst should only have access to credit related data.
+	Privileges           map[DataSet]Privilege //the privilege level on the data sets of the Acl. The role may only read the data sets that are not in the map
+	TableGrants          []*TableGrant         //the single tables, or columns of tables, this user has access to in data sets that are not part of the Acl
+	Policies             []*PolicyReference    //the set of extra IAM policies this user has access to. Those could be policies required by the CLI's that are part of the analyst tool chain.
+	Extends              []*Role               //the roles whose grants this role inherits. The inherited grants are copied into the role when the model is flattened
+	Bases                []*Role               //the roles this role extended before the model was flattened. They become nested roles with role based access control in redshift
+	GoogleGroups         []string              //the emails of the google groups whose members are assigned this role. The members are added as users when the groups are expanded
+	SessionDuration      int                   //the maximum duration of a login session with this role in seconds. Zero means DefaultSessionDuration
+	TrustedPrincipals    []string              //the ARNs of the IAM principals, e.g. CI roles, that may assume this role in addition to the users logging in with SAML
+}
+
+// The maximum duration of a login session in seconds, if the role doesn't set one
+const DefaultSessionDuration = 4 * 60 * 60
+
+// A team assigns its roles to all its members, in addition to the roles assigned to the members directly.
+type Team struct {
+	Name    string
+	Members []*User
+	Roles   []*Role
 }
 
 //the complete Hubble model which contains all the resources that are managed by the controller.
@@ -47,6 +82,7 @@ type Model struct {
 	Users        []*User
 	Roles        []*Role
 	Policies     []*PolicyReference
+	Teams        []*Team
 }
 
 //A reference to an unmanaged IAM policy
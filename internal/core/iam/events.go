@@ -0,0 +1,46 @@
+package iam
+
+import "fmt"
+
+//The changes applied to IAM, they name the actions of the apply reports
+type ApplyEventType int
+
+const (
+	RoleUpdated ApplyEventType = iota
+	RoleCreated
+	RoleDeleted
+	PolicyUpdated
+	PolicyCreated
+	PolicyDeleted
+	PolicyAttached
+	PolicyDetached
+	MaxSessionDurationUpdated
+	TrustPolicyUpdated
+)
+
+func (t ApplyEventType) ToString() string {
+	switch t {
+	case RoleUpdated:
+		return "RoleUpdated"
+	case RoleCreated:
+		return "RoleCreated"
+	case RoleDeleted:
+		return "RoleDeleted"
+	case PolicyUpdated:
+		return "PolicyUpdated"
+	case PolicyCreated:
+		return "PolicyCreated"
+	case PolicyDeleted:
+		return "PolicyDeleted"
+	case PolicyAttached:
+		return "PolicyAttached"
+	case PolicyDetached:
+		return "PolicyDetached"
+	case MaxSessionDurationUpdated:
+		return "MaxSessionDurationUpdated"
+	case TrustPolicyUpdated:
+		return "TrustPolicyUpdated"
+	default:
+		return fmt.Sprintf("%d", int(t))
+	}
+}
@@ -22,6 +22,8 @@ type AwsRole struct {
 	Name                  string
 	DatabaseLoginPolicies []*DatabaseLoginPolicy
 	Policies              []*PolicyReference
+	MaxSessionDuration    int64    //the maximum duration of a session with the role in seconds
+	TrustedPrincipals     []string //the ARNs of the IAM principals that may assume the role besides the SAML users
 }
 
 //The complete IAM model consists of a set of managed IAM roles
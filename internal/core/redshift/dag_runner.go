@@ -4,6 +4,27 @@ import (
 	"github.com/go-logr/logr"
 )
 
+type DagRunner interface {
+	Run(dag *ReconciliationDag)
+}
+
+type DagRunnerConfig struct {
+	MaxConcurrency           int //the maximum number of tasks that are executed concurrently. A value of 1 or less results in a sequential execution.
+	MaxConcurrencyPerCluster int //the maximum number of tasks that are executed concurrently against a single cluster. A value of 0 or less means no limit.
+}
+
+func DefaultDagRunnerConfig() DagRunnerConfig {
+	return DagRunnerConfig{MaxConcurrency: 1}
+}
+
+// Creates the DAG runner that matches the given configuration
+func NewDagRunner(config DagRunnerConfig, taskRunner TaskRunner, logger logr.Logger) DagRunner {
+	if config.MaxConcurrency <= 1 {
+		return NewSequentialDagRunner(taskRunner, logger)
+	}
+	return NewParallelDagRunner(taskRunner, config.MaxConcurrency, config.MaxConcurrencyPerCluster, logger)
+}
+
 type SequentialDagRunner struct {
 	taskRunner TaskRunner
 	logger     logr.Logger
@@ -25,7 +46,7 @@ func (d *SequentialDagRunner) Run(dag *ReconciliationDag) {
 			task.Start()
 			err := ExecuteTask(d.taskRunner, task)
 			if err != nil {
-				task.Failed()
+				task.Failed(err)
 				d.logger.Error(err, "task failed", "task", task.String())
 				continue
 			}
@@ -34,4 +55,102 @@ func (d *SequentialDagRunner) Run(dag *ReconciliationDag) {
 	}
 }
 
-//TODO: implement a DAG runner that can parallelize the execution
+type taskResult struct {
+	task *Task
+	err  error
+}
+
+// The ParallelDagRunner executes the tasks of the DAG using a bounded pool of workers.
+// A task is only started when all of its upstream tasks are done, so the ordering guarantees are the same as for the SequentialDagRunner.
+// All state transitions of the tasks happen on the goroutine calling Run, the workers only execute the tasks.
+type ParallelDagRunner struct {
+	taskRunner               TaskRunner
+	maxConcurrency           int
+	maxConcurrencyPerCluster int
+	logger                   logr.Logger
+}
+
+func NewParallelDagRunner(taskRunner TaskRunner, maxConcurrency int, maxConcurrencyPerCluster int, logger logr.Logger) *ParallelDagRunner {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &ParallelDagRunner{
+		taskRunner:               taskRunner,
+		maxConcurrency:           maxConcurrency,
+		maxConcurrencyPerCluster: maxConcurrencyPerCluster,
+		logger:                   logger,
+	}
+}
+
+func (d *ParallelDagRunner) worker(tasks <-chan *Task, results chan<- taskResult) {
+	for task := range tasks {
+		results <- taskResult{task: task, err: ExecuteTask(d.taskRunner, task)}
+	}
+}
+
+func (d *ParallelDagRunner) clusterIsBusy(runningPerCluster map[string]int, clusterIdentifier string) bool {
+	return d.maxConcurrencyPerCluster > 0 && runningPerCluster[clusterIdentifier] >= d.maxConcurrencyPerCluster
+}
+
+func (d *ParallelDagRunner) Run(dag *ReconciliationDag) {
+
+	tasks := make(chan *Task)
+	//the results channel can hold a result from every worker, this ensures that a worker never blocks while the tasks are being scheduled
+	results := make(chan taskResult, d.maxConcurrency)
+
+	for i := 0; i < d.maxConcurrency; i++ {
+		go d.worker(tasks, results)
+	}
+	defer close(tasks)
+
+	running := 0
+	runningPerCluster := make(map[string]int)
+
+	for {
+		progressed := false
+
+		for _, task := range dag.GetWaiting() {
+			if task.CannotRun() {
+				d.logger.Info("skipping task", "task", task.String())
+				task.Skip()
+				progressed = true
+				continue
+			}
+			if running >= d.maxConcurrency {
+				break
+			}
+			clusterIdentifier := task.ClusterIdentifier()
+			if d.clusterIsBusy(runningPerCluster, clusterIdentifier) {
+				continue
+			}
+			task.Start()
+			running++
+			runningPerCluster[clusterIdentifier]++
+			tasks <- task
+			progressed = true
+		}
+
+		if running == 0 {
+			if !dag.PendingExists() {
+				return
+			}
+			if !progressed {
+				//this can only happen if the DAG contains a cycle
+				d.logger.Info("unable to schedule the remaining tasks", "dag", dag.String())
+				return
+			}
+			continue
+		}
+
+		result := <-results
+		running--
+		runningPerCluster[result.task.ClusterIdentifier()]--
+
+		if result.err != nil {
+			result.task.Failed(result.err)
+			d.logger.Error(result.err, "task failed", "task", result.task.String())
+		} else {
+			result.task.Success()
+		}
+	}
+}
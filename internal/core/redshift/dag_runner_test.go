@@ -0,0 +1,208 @@
+package redshift
+
+import (
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type nullLogger struct{}
+
+func (l nullLogger) Info(msg string, keysAndValues ...interface{})             {}
+func (l nullLogger) Enabled() bool                                             { return false }
+func (l nullLogger) Error(err error, msg string, keysAndValues ...interface{}) {}
+func (l nullLogger) V(level int) logr.InfoLogger                               { return l }
+func (l nullLogger) WithValues(keysAndValues ...interface{}) logr.Logger       { return l }
+func (l nullLogger) WithName(name string) logr.Logger                          { return l }
+
+// A task runner that keeps track of how many tasks are running concurrently
+type recordingTaskRunner struct {
+	mutex                sync.Mutex
+	running              int
+	runningPerCluster    map[string]int
+	maxRunning           int
+	maxRunningPerCluster int
+	executed             []string
+	failing              map[string]bool
+}
+
+func newRecordingTaskRunner() *recordingTaskRunner {
+	return &recordingTaskRunner{runningPerCluster: make(map[string]int), failing: make(map[string]bool)}
+}
+
+func (r *recordingTaskRunner) run(clusterIdentifier string, name string) error {
+	r.mutex.Lock()
+	r.running++
+	r.runningPerCluster[clusterIdentifier]++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+	if r.runningPerCluster[clusterIdentifier] > r.maxRunningPerCluster {
+		r.maxRunningPerCluster = r.runningPerCluster[clusterIdentifier]
+	}
+	r.mutex.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.running--
+	r.runningPerCluster[clusterIdentifier]--
+	r.executed = append(r.executed, name)
+
+	if r.failing[name] {
+		return fmt.Errorf("task %s failed", name)
+	}
+	return nil
+}
+
+func (r *recordingTaskRunner) CreateUser(model *UserModel) error {
+	return r.run(model.ClusterIdentifier, "CreateUser:"+model.User.Name)
+}
+func (r *recordingTaskRunner) DropUser(model *UserModel) error {
+	return r.run(model.ClusterIdentifier, "DropUser:"+model.User.Name)
+}
+func (r *recordingTaskRunner) CreateGroup(model *GroupModel) error {
+	return r.run(model.ClusterIdentifier, "CreateGroup:"+model.Group.Name)
+}
+func (r *recordingTaskRunner) DropGroup(model *GroupModel) error {
+	return r.run(model.ClusterIdentifier, "DropGroup:"+model.Group.Name)
+}
+func (r *recordingTaskRunner) CreateSchema(model *SchemaModel) error {
+	return r.run(model.Database.ClusterIdentifier, "CreateSchema:"+model.Schema.Name)
+}
+func (r *recordingTaskRunner) CreateExternalSchema(model *ExternalSchemaModel) error {
+	return r.run(model.Database.ClusterIdentifier, "CreateExternalSchema:"+model.Schema.Name)
+}
+func (r *recordingTaskRunner) ReplaceExternalSchema(model *ExternalSchemaModel) error {
+	return r.run(model.Database.ClusterIdentifier, "ReplaceExternalSchema:"+model.Schema.Name)
+}
+func (r *recordingTaskRunner) CreateDatabase(model *DatabaseModel) error {
+	return r.run(model.ClusterIdentifier, "CreateDatabase:"+model.Database.Name)
+}
+func (r *recordingTaskRunner) GrantAccess(model *GrantsModel) error {
+	return r.run(model.Database.ClusterIdentifier, "GrantAccess:"+model.GroupName+"->"+model.SchemaName)
+}
+func (r *recordingTaskRunner) RevokeAccess(model *GrantsModel) error {
+	return r.run(model.Database.ClusterIdentifier, "RevokeAccess:"+model.GroupName+"->"+model.SchemaName)
+}
+func (r *recordingTaskRunner) GrantTableAccess(model *TableGrantsModel) error {
+	return r.run(model.Database.ClusterIdentifier, "GrantTableAccess:"+model.GroupName+"->"+model.Table.Identifier())
+}
+func (r *recordingTaskRunner) RevokeTableAccess(model *TableGrantsModel) error {
+	return r.run(model.Database.ClusterIdentifier, "RevokeTableAccess:"+model.GroupName+"->"+model.Table.Identifier())
+}
+func (r *recordingTaskRunner) UpgradeAccess(model *GrantsModel) error {
+	return r.run(model.Database.ClusterIdentifier, "UpgradeAccess:"+model.GroupName+"->"+model.SchemaName)
+}
+func (r *recordingTaskRunner) DowngradeAccess(model *GrantsModel) error {
+	return r.run(model.Database.ClusterIdentifier, "DowngradeAccess:"+model.GroupName+"->"+model.SchemaName)
+}
+func (r *recordingTaskRunner) AddToGroup(model *MembershipModel) error {
+	return r.run(model.ClusterIdentifier, "AddToGroup:"+model.Username+"->"+model.GroupName)
+}
+func (r *recordingTaskRunner) RemoveFromGroup(model *MembershipModel) error {
+	return r.run(model.ClusterIdentifier, "RemoveFromGroup:"+model.Username+"->"+model.GroupName)
+}
+
+func (r *recordingTaskRunner) CreateRole(model *RoleModel) error {
+	return r.run(model.ClusterIdentifier, "CreateRole:"+model.Role.Name)
+}
+func (r *recordingTaskRunner) DropRole(model *RoleModel) error {
+	return r.run(model.ClusterIdentifier, "DropRole:"+model.Role.Name)
+}
+func (r *recordingTaskRunner) GrantRole(model *RoleGrantModel) error {
+	return r.run(model.ClusterIdentifier, "GrantRole:"+model.GranteeName+"->"+model.RoleName)
+}
+func (r *recordingTaskRunner) RevokeRole(model *RoleGrantModel) error {
+	return r.run(model.ClusterIdentifier, "RevokeRole:"+model.GranteeName+"->"+model.RoleName)
+}
+
+func (r *recordingTaskRunner) indexOf(name string) int {
+	for i, executed := range r.executed {
+		if executed == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func buildDesiredOnClusters(clusterIdentifiers ...string) Model {
+
+	model := Model{}
+	for _, clusterIdentifier := range clusterIdentifiers {
+		cluster := model.DeclareCluster(clusterIdentifier)
+		biGroup := cluster.DeclareGroup("bianalyst")
+		database := cluster.DeclareDatabase("prod")
+		biDatabaseGroup := database.DeclareGroup("bianalyst")
+		for i := 0; i < 5; i++ {
+			username := fmt.Sprintf("user%d_bianalyst", i)
+			cluster.DeclareUser(username, biGroup)
+			database.DeclareUser(username)
+		}
+		biDatabaseGroup.GrantSchema(&Schema{Name: "public"})
+		biDatabaseGroup.GrantSchema(&Schema{Name: "bi"})
+	}
+	return model
+}
+
+func Test_ParallelDagRunner_RespectsDependencies(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := Model{}
+	desired := buildDesiredOnClusters("dev", "prod")
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	taskRunner := newRecordingTaskRunner()
+	NewParallelDagRunner(taskRunner, 4, 0, nullLogger{}).Run(dag)
+
+	assert.Equal(dag.NumTasks(), len(taskRunner.executed), "all tasks have been executed")
+	assert.Empty(dag.GetFailed())
+	assert.True(taskRunner.maxRunning > 1, "tasks have been executed concurrently")
+	assert.True(taskRunner.maxRunning <= 4, "no more than 4 tasks have been executed concurrently")
+
+	for i := 0; i < 5; i++ {
+		username := fmt.Sprintf("user%d_bianalyst", i)
+		addToGroup := taskRunner.indexOf("AddToGroup:" + username + "->bianalyst")
+		assert.True(taskRunner.indexOf("CreateUser:"+username) < addToGroup, "user is created before being added to the group")
+		assert.True(taskRunner.indexOf("CreateGroup:bianalyst") < addToGroup, "group is created before users are added")
+	}
+	assert.True(taskRunner.indexOf("CreateSchema:bi") < taskRunner.indexOf("GrantAccess:bianalyst->bi"), "schema is created before access is granted")
+}
+
+func Test_ParallelDagRunner_RespectsClusterLimit(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := Model{}
+	desired := buildDesiredOnClusters("dev", "prod")
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	taskRunner := newRecordingTaskRunner()
+	NewParallelDagRunner(taskRunner, 8, 2, nullLogger{}).Run(dag)
+
+	assert.Equal(dag.NumTasks(), len(taskRunner.executed), "all tasks have been executed")
+	assert.True(taskRunner.maxRunningPerCluster <= 2, "no more than 2 tasks have been executed concurrently against a single cluster")
+}
+
+func Test_ParallelDagRunner_SkipsDownstreamOfFailedTask(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := Model{}
+	desired := buildDesiredOnClusters("dev")
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	taskRunner := newRecordingTaskRunner()
+	taskRunner.failing["CreateGroup:bianalyst"] = true
+	NewParallelDagRunner(taskRunner, 4, 0, nullLogger{}).Run(dag)
+
+	assert.Equal(1, len(dag.GetFailed()))
+	assert.False(dag.PendingExists())
+	assert.Equal(-1, taskRunner.indexOf("AddToGroup:user0_bianalyst->bianalyst"), "users are not added to a group that could not be created")
+	assert.NotEqual(-1, taskRunner.indexOf("CreateUser:user0_bianalyst"), "independent tasks are still executed")
+}
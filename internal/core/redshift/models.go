@@ -2,18 +2,63 @@ package redshift
 
 import (
 	"fmt"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/validation"
 	"strings"
 )
 
+//The privilege level a group is granted on a schema
+type Privilege int
+
+const (
+	ReadPrivilege  Privilege = iota //usage of the schema and select on its tables
+	WritePrivilege                  //usage of the schema and select, insert, update and delete on its tables
+	OwnerPrivilege                  //all privileges on the schema, i.e. the group may also create tables in it, and on its tables
+)
+
+func (p Privilege) String() string {
+	return [...]string{"read", "write", "owner"}[p]
+}
+
 //A redshift schema
 type Schema struct {
-	Name string
+	Name      string
+	Privilege Privilege //the privilege level the group is granted on the schema
 }
 
 //An external schema references a glue database that allows the user to query the S3 data lake.
 type ExternalSchema struct {
 	Name             string
 	GlueDatabaseName string
+	IamRoles         []string //the ARNs of the IAM roles redshift assumes to access the glue database, chained in the given order. If empty, the default datalake role of the account is used
+	CatalogRegion    string   //the AWS region of the data catalog. If empty, the region of the cluster is used
+}
+
+//A table, or some of its columns, granted without granting the whole schema it resides in.
+type Table struct {
+	Schema  string
+	Name    string
+	Columns []string //the granted columns. If empty, the whole table is granted
+}
+
+//The kind of principal that is granted access
+type Grantee int
+
+const (
+	GroupGrantee Grantee = iota
+	RoleGrantee
+	UserGrantee
+)
+
+func (g Grantee) String() string {
+	return [...]string{"group", "role", "user"}[g]
+}
+
+//The principal as it is written in GRANT and REVOKE statements. Users are written by their name only.
+func (g Grantee) Clause(name string) string {
+	if g == UserGrantee {
+		return name
+	}
+	return fmt.Sprintf("%s %s", strings.ToUpper(g.String()), name)
 }
 
 type DatabaseUser struct {
@@ -22,7 +67,8 @@ type DatabaseUser struct {
 
 type User struct {
 	Name     string
-	MemberOf []*Group
+	MemberOf []*Group //the set of groups the user is member of, e.g. the group of its role and auxiliary groups shared by several roles
+	Roles    []*Role  //the roles granted to the user when role based access control is used
 }
 
 //Access is granted to groups in redshift.
@@ -30,18 +76,28 @@ type Group struct {
 	Name string
 }
 
+//With role based access control, access is granted to roles instead of groups and the roles are granted to the users.
+//A role inherits the access of the roles granted to it.
+type Role struct {
+	Name    string
+	Granted []*Role
+}
+
 type Cluster struct {
 	Identifier string      //the redshift cluster identifier
 	Users      []*User     // set of managed users on this cluster
 	Groups     []*Group    // set of managed groups on this cluster
+	Roles      []*Role     // set of managed roles on this cluster
 	Databases  []*Database // set of managed databases on this cluster
 }
 
-//groups are declared on cluster-level but access is granted on database level. This represents the access granted to the group on the given database.
+//groups and roles are declared on cluster-level but access is granted on database level. This represents the access granted to the group, or role, on the given database.
 type DatabaseGroup struct {
 	Name                   string
+	Grantee                Grantee //either GroupGrantee or RoleGrantee
 	GrantedSchemas         []*Schema
 	GrantedExternalSchemas []*ExternalSchema
+	GrantedTables          []*Table //tables granted in schemas that are not granted as a whole
 }
 
 //a redshift database with the given name that resides on the given cluster
@@ -51,6 +107,7 @@ type Database struct {
 	Owner             *string //an optional owner of the database. on a dev database the developer is set as owner.
 	Users             []*DatabaseUser
 	Groups            []*DatabaseGroup
+	Roles             []*DatabaseGroup //the access granted to roles
 }
 
 //the complete redshift model consists of a set of managed redshift clusters
@@ -59,39 +116,50 @@ type Model struct {
 }
 
 func (m *Model) Validate(excluded *Exclusions) error {
+	var errors validation.Errors
 	for _, cluster := range m.Clusters {
-		err := cluster.Validate(excluded)
-
-		if err != nil {
-			return err
-		}
+		errors.AddAll(validation.Key("clusters", cluster.Identifier), cluster.validate(excluded))
 	}
-	return nil
+	return errors.ErrorOrNil()
 }
 
-//TODO: Collect all validation errors and return the list instead of returning only the first error found
 func (c *Cluster) Validate(excluded Excluder) error {
+	return c.validate(excluded).ErrorOrNil()
+}
+
+func (c *Cluster) validate(excluded Excluder) validation.Errors {
+	var errors validation.Errors
+
 	for _, database := range c.Databases {
+		path := validation.Key("databases", database.Name)
+
+		if len(database.Users) > 0 && excluded.IsDatabaseExcluded(database.Name) {
+			errors.Add(path, "database with name %s has been excluded and cannot be managed", database.Name)
+		}
 		for _, user := range database.Users {
-			if excluded.IsDatabaseExcluded(database.Name) {
-				return fmt.Errorf("database with name %s has been excluded and cannot be managed", database.Name)
-			}
 			if c.LookupUser(user.Name) == nil {
-				return fmt.Errorf("user with name %s from database %s has not been declared on the cluster", user.Name, database.Name)
+				errors.Add(validation.Key(validation.Join(path, "users"), user.Name), "user with name %s from database %s has not been declared on the cluster", user.Name, database.Name)
+			}
+		}
+		for _, role := range database.Roles {
+			if c.LookupRole(role.Name) == nil {
+				errors.Add(validation.Key(validation.Join(path, "roles"), role.Name), "role with name %s from database %s has not been declared on the cluster", role.Name, database.Name)
 			}
 		}
 	}
 
 	for _, user := range c.Users {
-		if user.Role() == nil {
-			return fmt.Errorf("role of user with name %s cannot be determined. User must be part of 1 and only 1 group", user.Name)
+		path := validation.Key("users", user.Name)
+
+		if len(user.MemberOf)+len(user.Roles) == 0 {
+			errors.Add(path, "user with name %s has no access. User must be part of a group or be granted a role", user.Name)
 		}
 		if excluded.IsUserExcluded(user.Name) {
-			return fmt.Errorf("user with name %s has been excluded and cannot be managed", user.Name)
+			errors.Add(path, "user with name %s has been excluded and cannot be managed", user.Name)
 		}
 	}
 
-	return nil
+	return errors
 }
 
 func (m *Model) LookupCluster(identifier string) *Cluster {
@@ -123,13 +191,30 @@ func (c *Cluster) LookupUser(username string) *User {
 	return nil
 }
 
+//Declares the user and adds it to the group, a user that has already been declared is added to the group as well
 func (c *Cluster) DeclareUser(name string, memberOf *Group) *User {
 	existing := c.LookupUser(name)
 	if existing != nil {
+		existing.JoinGroup(memberOf)
+		return existing
+	}
+
+	newUser := &User{Name: strings.ToLower(name)}
+	newUser.JoinGroup(memberOf)
+	c.Users = append(c.Users, newUser)
+	return newUser
+}
+
+//Declares a user that is granted the given role instead of being member of a group, a user that has already been declared is granted the role as well
+func (c *Cluster) DeclareUserWithRole(name string, role *Role) *User {
+	existing := c.LookupUser(name)
+	if existing != nil {
+		existing.GrantRole(role)
 		return existing
 	}
 
-	newUser := &User{Name: strings.ToLower(name), MemberOf: []*Group{memberOf}}
+	newUser := &User{Name: strings.ToLower(name)}
+	newUser.GrantRole(role)
 	c.Users = append(c.Users, newUser)
 	return newUser
 }
@@ -154,6 +239,26 @@ func (c *Cluster) DeclareGroup(name string) *Group {
 	return newGroup
 }
 
+func (c *Cluster) LookupRole(name string) *Role {
+	for _, role := range c.Roles {
+		if strings.EqualFold(role.Name, name) {
+			return role
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) DeclareRole(name string) *Role {
+	existing := c.LookupRole(name)
+	if existing != nil {
+		return existing
+	}
+
+	newRole := &Role{Name: strings.ToLower(name)}
+	c.Roles = append(c.Roles, newRole)
+	return newRole
+}
+
 func (c *Cluster) LookupDatabase(name string) *Database {
 	for _, db := range c.Databases {
 		if strings.EqualFold(db.Name, name) {
@@ -203,6 +308,41 @@ func (d *Database) DeclareGroup(name string) *DatabaseGroup {
 	return newGroup
 }
 
+func (d *Database) LookupRole(name string) *DatabaseGroup {
+	for _, role := range d.Roles {
+		if strings.EqualFold(role.Name, name) {
+			return role
+		}
+	}
+	return nil
+}
+
+func (d *Database) DeclareRole(name string) *DatabaseGroup {
+	existing := d.LookupRole(name)
+	if existing != nil {
+		return existing
+	}
+
+	newRole := &DatabaseGroup{Name: strings.ToLower(name), Grantee: RoleGrantee}
+	d.Roles = append(d.Roles, newRole)
+	return newRole
+}
+
+//The access granted to both the groups and the roles on the database
+func (d *Database) Grantees() []*DatabaseGroup {
+	var result []*DatabaseGroup
+	result = append(result, d.Groups...)
+	return append(result, d.Roles...)
+}
+
+//Looks up the access granted to a group, or role, of the same kind and name as the given one
+func (d *Database) LookupGrantee(grantee *DatabaseGroup) *DatabaseGroup {
+	if grantee.Grantee == RoleGrantee {
+		return d.LookupRole(grantee.Name)
+	}
+	return d.LookupGroup(grantee.Name)
+}
+
 func (d *Database) LookupUser(name string) *DatabaseUser {
 	for _, user := range d.Users {
 		if strings.EqualFold(user.Name, name) {
@@ -227,10 +367,21 @@ func (d *Database) Identifier() string {
 	return fmt.Sprintf("%s/%s", d.ClusterIdentifier, d.Name)
 }
 
+//The name of the group, or the name of the role prefixed with role: so roles and groups can be told apart
+func (g *DatabaseGroup) Identifier() string {
+	if g.Grantee == RoleGrantee {
+		return fmt.Sprintf("role:%s", g.Name)
+	}
+	return g.Name
+}
+
+//Grants the schema, or raises the privilege level if the schema has been granted before with a lower level
 func (g *DatabaseGroup) GrantSchema(schema *Schema) {
 	existing := g.LookupGrantedSchema(schema.Name)
 	if existing == nil {
 		g.GrantedSchemas = append(g.GrantedSchemas, schema)
+	} else if schema.Privilege > existing.Privilege {
+		existing.Privilege = schema.Privilege
 	}
 }
 
@@ -241,6 +392,141 @@ func (g *DatabaseGroup) GrantExternalSchema(schema *ExternalSchema) {
 	}
 }
 
+//Grants the table, or the union of the granted columns if the table has been granted before
+func (g *DatabaseGroup) GrantTable(table *Table) {
+	existing := g.LookupGrantedTable(table.Schema, table.Name)
+	if existing == nil {
+		g.GrantedTables = append(g.GrantedTables, &Table{Schema: strings.ToLower(table.Schema), Name: strings.ToLower(table.Name), Columns: lowercased(table.Columns)})
+		return
+	}
+	if len(existing.Columns) == 0 {
+		return
+	}
+	if len(table.Columns) == 0 {
+		existing.Columns = nil
+		return
+	}
+	for _, column := range lowercased(table.Columns) {
+		if !existing.HasColumn(column) {
+			existing.Columns = append(existing.Columns, column)
+		}
+	}
+}
+
+func (g *DatabaseGroup) LookupGrantedTable(schema string, name string) *Table {
+	for _, table := range g.GrantedTables {
+		if strings.EqualFold(table.Schema, schema) && strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+//Returns true if any of the tables granted to the group resides in the given schema
+func (g *DatabaseGroup) HasTablesIn(schema string) bool {
+	for _, table := range g.GrantedTables {
+		if strings.EqualFold(table.Schema, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+//Removes the grants that are inherited from a role granted to this role, i.e. the schemas it grants with the same or a higher privilege level and the tables it grants all the granted columns of
+func (g *DatabaseGroup) RevokeInherited(inherited *DatabaseGroup) {
+	var schemas []*Schema
+	for _, schema := range g.GrantedSchemas {
+		inheritedSchema := inherited.LookupGrantedSchema(schema.Name)
+		if inheritedSchema == nil || inheritedSchema.Privilege < schema.Privilege {
+			schemas = append(schemas, schema)
+		}
+	}
+	g.GrantedSchemas = schemas
+
+	var externalSchemas []*ExternalSchema
+	for _, schema := range g.GrantedExternalSchemas {
+		if inherited.LookupGrantedExternalSchema(schema.Name) == nil {
+			externalSchemas = append(externalSchemas, schema)
+		}
+	}
+	g.GrantedExternalSchemas = externalSchemas
+
+	var tables []*Table
+	for _, table := range g.GrantedTables {
+		if inherited.LookupGrantedSchema(table.Schema) == nil && !inherited.grantsAllColumnsOf(table) {
+			tables = append(tables, table)
+		}
+	}
+	g.GrantedTables = tables
+}
+
+func (g *DatabaseGroup) grantsAllColumnsOf(table *Table) bool {
+	granted := g.LookupGrantedTable(table.Schema, table.Name)
+	if granted == nil {
+		return false
+	}
+	if len(granted.Columns) == 0 {
+		return true
+	}
+	if len(table.Columns) == 0 {
+		return false
+	}
+	for _, column := range table.Columns {
+		if !granted.HasColumn(column) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Table) Identifier() string {
+	return fmt.Sprintf("%s.%s", t.Schema, t.Name)
+}
+
+func (t *Table) HasColumn(name string) bool {
+	for _, column := range t.Columns {
+		if strings.EqualFold(column, name) {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns true if both external schemas reference the same glue database through the same IAM roles and region
+func (s *ExternalSchema) SameDefinition(other *ExternalSchema) bool {
+	if s.GlueDatabaseName != other.GlueDatabaseName || s.CatalogRegion != other.CatalogRegion || len(s.IamRoles) != len(other.IamRoles) {
+		return false
+	}
+	//the roles are chained, so their order matters
+	for i, role := range s.IamRoles {
+		if role != other.IamRoles[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//Returns true if the same columns are granted on both tables, regardless of their order
+func (t *Table) SameColumns(other *Table) bool {
+	if len(t.Columns) != len(other.Columns) {
+		return false
+	}
+	for _, column := range other.Columns {
+		if !t.HasColumn(column) {
+			return false
+		}
+	}
+	return true
+}
+
+func lowercased(values []string) []string {
+	var result []string
+	for _, value := range values {
+		result = append(result, strings.ToLower(value))
+	}
+	return result
+}
+
 func (g *DatabaseGroup) Granted() []string {
 	schemas := make([]string, 0, len(g.GrantedSchemas)+len(g.GrantedExternalSchemas))
 	for _, schema := range g.GrantedSchemas {
@@ -270,13 +556,11 @@ func (g *DatabaseGroup) LookupGrantedExternalSchema(name string) *ExternalSchema
 	return nil
 }
 
-func (u *User) Role() *Group {
-
-	//TODO: Enforce len(u.MemberOf) == 1 as an invariant. Encapsulate the MemberOf field and ensure that it is always 1
-	if len(u.MemberOf) != 1 {
-		return nil
+//Adds the user to the group unless it is already a member
+func (u *User) JoinGroup(group *Group) {
+	if !u.IsMemberOf(group.Name) {
+		u.MemberOf = append(u.MemberOf, group)
 	}
-	return u.MemberOf[0]
 }
 
 func (u *User) IsMemberOf(groupName string) bool {
@@ -287,3 +571,35 @@ func (u *User) IsMemberOf(groupName string) bool {
 	}
 	return false
 }
+
+//Grants the role to the user unless it has already been granted
+func (u *User) GrantRole(role *Role) {
+	if !u.IsGranted(role.Name) {
+		u.Roles = append(u.Roles, role)
+	}
+}
+
+func (u *User) IsGranted(roleName string) bool {
+	for _, role := range u.Roles {
+		if role.Name == roleName {
+			return true
+		}
+	}
+	return false
+}
+
+//Grants the given role to the role, so it inherits the access of the given role
+func (r *Role) Grant(role *Role) {
+	if !r.IsGranted(role.Name) {
+		r.Granted = append(r.Granted, role)
+	}
+}
+
+func (r *Role) IsGranted(roleName string) bool {
+	for _, role := range r.Granted {
+		if role.Name == roleName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package redshift
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/validation"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Model_Validate_CollectsAllErrors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := Model{}
+	cluster := model.DeclareCluster("dev")
+	group := cluster.DeclareGroup("bianalyst")
+	cluster.DeclareUser("looker", group)
+	orphan := cluster.DeclareUser("jwr_bianalyst", group)
+	orphan.MemberOf = nil
+	cluster.DeclareDatabase("prod").DeclareUser("unknown_bianalyst")
+
+	err := model.Validate(NewExclusions([]string{}, []string{"looker"}))
+
+	errors, ok := err.(validation.Errors)
+	assert.True(ok)
+	assert.Equal(3, len(errors), "the undeclared user, the user without a group and the excluded user are all reported")
+	assert.Equal("clusters[dev].databases[prod].users[unknown_bianalyst]", errors[0].Path)
+}
+
+func Test_Model_Validate_Valid(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := buildDesiredOnClusters("dev", "prod")
+
+	assert.NoError(model.Validate(NewExclusions([]string{}, []string{})))
+}
+
+func Test_DatabaseGroup_GrantTable_MergesColumns(t *testing.T) {
+
+	assert := assert.New(t)
+
+	group := &DatabaseGroup{Name: "creditanalyst"}
+	group.GrantTable(&Table{Schema: "credit", Name: "Loans", Columns: []string{"id"}})
+	group.GrantTable(&Table{Schema: "credit", Name: "loans", Columns: []string{"Amount", "id"}})
+	group.GrantTable(&Table{Schema: "credit", Name: "customers"})
+	group.GrantTable(&Table{Schema: "credit", Name: "customers", Columns: []string{"id"}})
+
+	assert.Equal(2, len(group.GrantedTables))
+	assert.Equal([]string{"id", "amount"}, group.LookupGrantedTable("credit", "loans").Columns)
+	assert.Nil(group.LookupGrantedTable("credit", "customers").Columns, "a whole table covers all its columns")
+}
+
+func Test_DatabaseGroup_GrantSchema_RaisesPrivilege(t *testing.T) {
+
+	assert := assert.New(t)
+
+	group := &DatabaseGroup{Name: "dataengineer"}
+	group.GrantSchema(&Schema{Name: "bi"})
+	group.GrantSchema(&Schema{Name: "bi", Privilege: WritePrivilege})
+	group.GrantSchema(&Schema{Name: "bi", Privilege: ReadPrivilege})
+
+	assert.Equal(1, len(group.GrantedSchemas))
+	assert.Equal(WritePrivilege, group.LookupGrantedSchema("bi").Privilege, "the highest privilege level granted is kept")
+}
+
+func Test_Model_Validate_UsersWithRoles(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := Model{}
+	cluster := model.DeclareCluster("dev")
+	role := cluster.DeclareRole("bianalyst")
+	cluster.DeclareUserWithRole("jwr_bianalyst", role)
+	cluster.DeclareUserWithRole("nra_bianalyst", role)
+	cluster.DeclareUser("nra_bianalyst", cluster.DeclareGroup("readers"))
+	cluster.DeclareDatabase("prod").DeclareRole("unknown")
+
+	err := model.Validate(NewExclusions([]string{}, []string{}))
+
+	errors, ok := err.(validation.Errors)
+	assert.True(ok)
+	assert.Equal(1, len(errors), "a user may be both granted a role and be member of a group")
+	assert.Equal("clusters[dev].databases[prod].roles[unknown]", errors[0].Path)
+}
+
+func Test_Cluster_DeclareUser_SeveralGroups(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cluster := &Cluster{Identifier: "dev"}
+	biAnalyst := cluster.DeclareGroup("bianalyst")
+	readers := cluster.DeclareGroup("readers")
+	cluster.DeclareUser("jwr_bianalyst", biAnalyst)
+	user := cluster.DeclareUser("jwr_bianalyst", readers)
+	cluster.DeclareUser("jwr_bianalyst", biAnalyst)
+
+	assert.Equal([]*Group{biAnalyst, readers}, user.MemberOf, "the memberships are a set")
+	assert.NoError(cluster.Validate(NewExclusions([]string{}, []string{})))
+}
+
+func Test_DatabaseGroup_RevokeInherited(t *testing.T) {
+
+	assert := assert.New(t)
+
+	inherited := &DatabaseGroup{Name: "analyst", Grantee: RoleGrantee}
+	inherited.GrantSchema(&Schema{Name: "public"})
+	inherited.GrantSchema(&Schema{Name: "bi", Privilege: ReadPrivilege})
+	inherited.GrantExternalSchema(&ExternalSchema{Name: "lake"})
+	inherited.GrantTable(&Table{Schema: "credit", Name: "loans", Columns: []string{"id", "amount"}})
+
+	role := &DatabaseGroup{Name: "creditanalyst", Grantee: RoleGrantee}
+	role.GrantSchema(&Schema{Name: "public"})
+	role.GrantSchema(&Schema{Name: "bi", Privilege: WritePrivilege})
+	role.GrantExternalSchema(&ExternalSchema{Name: "lake"})
+	role.GrantTable(&Table{Schema: "credit", Name: "loans", Columns: []string{"id"}})
+	role.GrantTable(&Table{Schema: "credit", Name: "customers"})
+
+	role.RevokeInherited(inherited)
+
+	assert.Equal([]string{"bi"}, role.Granted(), "a higher privilege level than the inherited one is kept")
+	assert.Equal(1, len(role.GrantedTables))
+	assert.NotNil(role.LookupGrantedTable("credit", "customers"))
+}
@@ -27,7 +27,7 @@ type Reconciler struct {
 // The operations need to be executed in an order that respects the dependencies between the objects
 // otherwise the operations will fail. E.g. redshift will complain if one attempts to drop a group that has members or has grants on schemas.
 // Instead of executing the reconciliation it returns a DAG that represents the reconciliation process as a DAG of tasks.
-// The DAG can be executed using the SequentialDagRunner.
+// The DAG can be executed using the SequentialDagRunner or the ParallelDagRunner.
 // By modelling the process as a DAG we decouple the interdependencies of the tasks with the execution. This allows us to optimise the execution
 // independently from the task interdependencies (e.g. parallelising it). It also makes the code easier to understand and maintain because the code structure
 // would otherwise be coupled to the task interdependencies (the order of the function calls in the code would have to respect the dependencies)
@@ -55,15 +55,84 @@ func Reconcile(current *Model, desired *Model, config ReconcilerConfig) *Reconci
 		}
 	}
 
+	d.keepAccessWhileMigrating()
+
 	return NewDag(d.tasks)
 }
 
+//When a group is replaced by a role with the same name, e.g. when switching to role based access control,
+//the access is taken away from the group only once the role has been granted the access and has been granted to the users.
+//This way the users keep their access while migrating.
+func (d *Reconciler) keepAccessWhileMigrating() {
+	for _, revokeTask := range d.tasks {
+		groupName := revokedGroupOf(revokeTask)
+		if groupName == "" {
+			continue
+		}
+		for _, grantTask := range d.tasks {
+			if grantedRoleOf(grantTask) == groupName && grantTask.ClusterIdentifier() == revokeTask.ClusterIdentifier() {
+				revokeTask.dependsOn(grantTask)
+			}
+		}
+	}
+}
+
+//The name of the group the task takes access away from, if any
+func revokedGroupOf(task *Task) string {
+	switch model := task.model.(type) {
+	case *MembershipModel:
+		if task.taskType == RemoveFromGroup {
+			return model.GroupName
+		}
+	case *GroupModel:
+		if task.taskType == DropGroup {
+			return model.Group.Name
+		}
+	case *GrantsModel:
+		if (task.taskType == RevokeAccess || task.taskType == DowngradeAccess) && model.Grantee == GroupGrantee {
+			return model.GroupName
+		}
+	case *TableGrantsModel:
+		if task.taskType == RevokeTableAccess && model.Grantee == GroupGrantee {
+			return model.GroupName
+		}
+	}
+	return ""
+}
+
+//The name of the role the task creates, grants access to or grants to a user or role, if any
+func grantedRoleOf(task *Task) string {
+	switch model := task.model.(type) {
+	case *RoleModel:
+		if task.taskType == CreateRole {
+			return model.Role.Name
+		}
+	case *RoleGrantModel:
+		if task.taskType == GrantRole {
+			return model.RoleName
+		}
+	case *GrantsModel:
+		if (task.taskType == GrantAccess || task.taskType == UpgradeAccess) && model.Grantee == RoleGrantee {
+			return model.GroupName
+		}
+	case *TableGrantsModel:
+		if task.taskType == GrantTableAccess && model.Grantee == RoleGrantee {
+			return model.GroupName
+		}
+	}
+	return ""
+}
+
 func (d *Reconciler) addCluster(cluster *Cluster) {
 
 	for _, desiredGroup := range cluster.Groups {
 		d.createGroup(cluster.Identifier, desiredGroup)
 	}
 
+	for _, desiredRole := range cluster.Roles {
+		d.createRole(cluster.Identifier, desiredRole)
+	}
+
 	for _, desiredUser := range cluster.Users {
 		d.createUser(cluster.Identifier, desiredUser)
 	}
@@ -86,6 +155,10 @@ func (d *Reconciler) dropCluster(cluster *Cluster) {
 	for _, currentGroup := range cluster.Groups {
 		d.dropGroup(cluster.Identifier, currentGroup)
 	}
+
+	for _, currentRole := range cluster.Roles {
+		d.dropRole(cluster.Identifier, currentRole)
+	}
 }
 
 func (d *Reconciler) updateCluster(currentCluster *Cluster, desiredCluster *Cluster) {
@@ -109,6 +182,16 @@ func (d *Reconciler) updateCluster(currentCluster *Cluster, desiredCluster *Clus
 		//a group has no attributes, thus it makes no sense to update a group
 	}
 
+	for _, currentRole := range currentCluster.Roles {
+		desiredRole := desiredCluster.LookupRole(currentRole.Name)
+
+		if desiredRole == nil {
+			d.dropRole(currentCluster.Identifier, currentRole)
+		} else {
+			d.updateRole(currentCluster.Identifier, currentRole, desiredRole)
+		}
+	}
+
 	for _, currentDatabase := range currentCluster.Databases {
 		desiredDatabase := desiredCluster.LookupDatabase(currentDatabase.Name)
 
@@ -128,6 +211,16 @@ func (d *Reconciler) updateCluster(currentCluster *Cluster, desiredCluster *Clus
 		//a group has no attributes, thus it makes no sense to update a group
 	}
 
+	for _, desiredRole := range desiredCluster.Roles {
+		currentRole := currentCluster.LookupRole(desiredRole.Name)
+
+		if currentRole == nil {
+			d.createRole(currentCluster.Identifier, desiredRole)
+		} else {
+			d.updateRole(currentCluster.Identifier, currentRole, desiredRole)
+		}
+	}
+
 	for _, desiredUser := range desiredCluster.Users {
 		currentUser := currentCluster.LookupUser(desiredUser.Name)
 
@@ -153,14 +246,14 @@ func (d *Reconciler) addDatabase(database *Database) {
 
 	d.add(newCreateDatabaseTask(database.ClusterIdentifier, database))
 
-	for _, group := range database.Groups {
+	for _, group := range database.Grantees() {
 		d.addDatabaseGroup(database, group)
 	}
 }
 
 func (d *Reconciler) dropDatabase(database *Database) {
 
-	for _, group := range database.Groups {
+	for _, group := range database.Grantees() {
 		d.dropDatabaseGroup(database, group)
 	}
 }
@@ -181,9 +274,11 @@ func (d *Reconciler) updateDatabase(currentDatabase *Database, desiredDatabase *
 		panic(fmt.Errorf("Owners are different!!!"))
 	}
 
-	for _, currentGroup := range currentDatabase.Groups {
+	d.replaceChangedExternalSchemas(currentDatabase, desiredDatabase)
+
+	for _, currentGroup := range currentDatabase.Grantees() {
 
-		desiredGroup := desiredDatabase.LookupGroup(currentGroup.Name)
+		desiredGroup := desiredDatabase.LookupGrantee(currentGroup)
 
 		if desiredGroup == nil {
 			d.dropDatabaseGroup(currentDatabase, currentGroup)
@@ -192,9 +287,9 @@ func (d *Reconciler) updateDatabase(currentDatabase *Database, desiredDatabase *
 		}
 	}
 
-	for _, desiredGroup := range desiredDatabase.Groups {
+	for _, desiredGroup := range desiredDatabase.Grantees() {
 
-		currentGroup := currentDatabase.LookupGroup(desiredGroup.Name)
+		currentGroup := currentDatabase.LookupGrantee(desiredGroup)
 
 		if currentGroup == nil {
 			d.addDatabaseGroup(desiredDatabase, desiredGroup)
@@ -204,6 +299,25 @@ func (d *Reconciler) updateDatabase(currentDatabase *Database, desiredDatabase *
 	}
 }
 
+//Redshift can't alter the glue database, IAM roles or region of an external schema, so an external schema whose definition has changed is dropped and created again
+func (d *Reconciler) replaceChangedExternalSchemas(currentDatabase *Database, desiredDatabase *Database) {
+
+	for _, desiredGroup := range desiredDatabase.Grantees() {
+		for _, schema := range desiredGroup.GrantedExternalSchemas {
+			for _, currentGroup := range currentDatabase.Grantees() {
+				current := currentGroup.LookupGrantedExternalSchema(schema.Name)
+				if current != nil && !current.SameDefinition(schema) {
+					d.add(newReplaceExternalSchemaTask(currentDatabase, schema))
+				}
+			}
+		}
+	}
+}
+
+func (d *Reconciler) lookupReplaceExternalSchemaTask(database *Database, schemaName string) *Task {
+	return d.lookupTask(newReplaceExternalSchemaTask(database, &ExternalSchema{Name: schemaName}))
+}
+
 func (d *Reconciler) lookupTask(task *Task) *Task {
 	for _, t := range d.tasks {
 		if task.taskType == t.taskType && task.model.Equals(t.model) {
@@ -269,6 +383,56 @@ func (d *Reconciler) lookupDropGroupTask(clusterIdentifier string, name string)
 	return nil
 }
 
+func (d *Reconciler) lookupCreateRoleTask(clusterIdentifier string, name string) *Task {
+	for _, task := range d.tasks {
+		if task.taskType == CreateRole &&
+			task.model.(*RoleModel).ClusterIdentifier == clusterIdentifier &&
+			task.model.(*RoleModel).Role.Name == name {
+			return task
+		}
+	}
+	return nil
+}
+
+func (d *Reconciler) lookupDropRoleTask(clusterIdentifier string, name string) *Task {
+	for _, task := range d.tasks {
+		if task.taskType == DropRole &&
+			task.model.(*RoleModel).ClusterIdentifier == clusterIdentifier &&
+			task.model.(*RoleModel).Role.Name == name {
+			return task
+		}
+	}
+	return nil
+}
+
+func (d *Reconciler) lookupRoleGrantTasks(taskType TaskType, clusterIdentifier string, roleName string) []*Task {
+	var result []*Task
+	for _, task := range d.tasks {
+		if task.taskType == taskType &&
+			task.model.(*RoleGrantModel).ClusterIdentifier == clusterIdentifier &&
+			task.model.(*RoleGrantModel).RoleName == roleName {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+//Looks up the task creating the group or the role the access is granted to
+func (d *Reconciler) lookupCreateGranteeTask(clusterIdentifier string, group *DatabaseGroup) *Task {
+	if group.Grantee == RoleGrantee {
+		return d.lookupCreateRoleTask(clusterIdentifier, group.Name)
+	}
+	return d.lookupCreateGroupTask(clusterIdentifier, group.Name)
+}
+
+//Looks up the task dropping the group or the role the access is granted to
+func (d *Reconciler) lookupDropGranteeTask(clusterIdentifier string, group *DatabaseGroup) *Task {
+	if group.Grantee == RoleGrantee {
+		return d.lookupDropRoleTask(clusterIdentifier, group.Name)
+	}
+	return d.lookupDropGroupTask(clusterIdentifier, group.Name)
+}
+
 func (d *Reconciler) lookupCreateDatabaseTask(clusterIdentifier string, name string) *Task {
 	for _, task := range d.tasks {
 		if task.taskType == CreateDatabase &&
@@ -280,15 +444,37 @@ func (d *Reconciler) lookupCreateDatabaseTask(clusterIdentifier string, name str
 	return nil
 }
 
+func (d *Reconciler) lookupRevokeAccessTask(database *Database, schemaName string, group *DatabaseGroup) *Task {
+	return d.lookupTask(newRevokeAccessTask(database, schemaName, group))
+}
+
+func (d *Reconciler) lookupRevokeTableAccessTasks(database *Database, schemaName string, group *DatabaseGroup) []*Task {
+	var result []*Task
+	for _, task := range d.tasks {
+		if task.taskType == RevokeTableAccess &&
+			task.model.(*TableGrantsModel).Database.ClusterIdentifier == database.ClusterIdentifier &&
+			task.model.(*TableGrantsModel).Database.Name == database.Name &&
+			task.model.(*TableGrantsModel).GroupName == group.Name &&
+			task.model.(*TableGrantsModel).Grantee == group.Grantee &&
+			task.model.(*TableGrantsModel).Table.Schema == schemaName {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
 func (d *Reconciler) createUser(clusterIdentifier string, user *User) {
 
 	createUserTask := d.add(newCreateUserTask(clusterIdentifier, user))
-	addToGroupTask := d.add(newAddToGroupTask(clusterIdentifier, user, user.Role()))
-	addToGroupTask.dependsOn(createUserTask)
 
-	createGroupTask := d.lookupCreateGroupTask(clusterIdentifier, user.Role().Name)
-	if createGroupTask != nil {
-		addToGroupTask.dependsOn(createGroupTask)
+	for _, group := range user.MemberOf {
+		addToGroupTask := d.addToGroup(clusterIdentifier, user, group)
+		addToGroupTask.dependsOn(createUserTask)
+	}
+
+	for _, role := range user.Roles {
+		grantRoleTask := d.grantRole(clusterIdentifier, role.Name, UserGrantee, user.Name)
+		grantRoleTask.dependsOn(createUserTask)
 	}
 }
 
@@ -301,12 +487,18 @@ func (d *Reconciler) dropUser(clusterIdentifier string, user *User) {
 		dropUserTask.dependsOn(removeFromGroupTask)
 	}
 
+	for _, role := range user.Roles {
+		revokeRoleTask := d.revokeRole(clusterIdentifier, role.Name, UserGrantee, user.Name)
+		dropUserTask.dependsOn(revokeRoleTask)
+	}
 }
 
 func (d *Reconciler) updateUser(clusterIdentifier string, current *User, desired *User) {
 
+	d.updateUserRoles(clusterIdentifier, current, desired)
+
 	for _, group := range current.MemberOf {
-		if group.Name != desired.Role().Name {
+		if !desired.IsMemberOf(group.Name) {
 			removeFromGroupTask := d.add(newRemoveFromGroupTask(clusterIdentifier, current, group))
 			dropGroupTask := d.lookupDropGroupTask(clusterIdentifier, group.Name)
 
@@ -316,13 +508,36 @@ func (d *Reconciler) updateUser(clusterIdentifier string, current *User, desired
 		}
 	}
 
-	if !current.IsMemberOf(desired.Role().Name) {
-		addToGroupTask := d.add(newAddToGroupTask(clusterIdentifier, desired, desired.Role()))
+	for _, group := range desired.MemberOf {
+		if !current.IsMemberOf(group.Name) {
+			d.addToGroup(clusterIdentifier, desired, group)
+		}
+	}
+}
 
-		createGroupTask := d.lookupCreateGroupTask(clusterIdentifier, desired.Role().Name)
+func (d *Reconciler) addToGroup(clusterIdentifier string, user *User, group *Group) *Task {
 
-		if createGroupTask != nil {
-			addToGroupTask.dependsOn(createGroupTask)
+	addToGroupTask := d.add(newAddToGroupTask(clusterIdentifier, user, group))
+
+	createGroupTask := d.lookupCreateGroupTask(clusterIdentifier, group.Name)
+	if createGroupTask != nil {
+		addToGroupTask.dependsOn(createGroupTask)
+	}
+	return addToGroupTask
+}
+
+//Grants and revokes the roles of the user
+func (d *Reconciler) updateUserRoles(clusterIdentifier string, current *User, desired *User) {
+
+	for _, role := range current.Roles {
+		if !desired.IsGranted(role.Name) {
+			d.revokeRole(clusterIdentifier, role.Name, UserGrantee, current.Name)
+		}
+	}
+
+	for _, role := range desired.Roles {
+		if !current.IsGranted(role.Name) {
+			d.grantRole(clusterIdentifier, role.Name, UserGrantee, desired.Name)
 		}
 	}
 }
@@ -347,13 +562,88 @@ func (d *Reconciler) dropGroup(clusterIdentifier string, group *Group) {
 	}
 }
 
+func (d *Reconciler) createRole(clusterIdentifier string, role *Role) {
+
+	createRoleTask := d.add(newCreateRoleTask(clusterIdentifier, role))
+
+	for _, granted := range role.Granted {
+		grantRoleTask := d.grantRole(clusterIdentifier, granted.Name, RoleGrantee, role.Name)
+		grantRoleTask.dependsOn(createRoleTask)
+	}
+
+	for _, grantRoleTask := range d.lookupRoleGrantTasks(GrantRole, clusterIdentifier, role.Name) {
+		grantRoleTask.dependsOn(createRoleTask)
+	}
+}
+
+func (d *Reconciler) dropRole(clusterIdentifier string, role *Role) {
+
+	dropRoleTask := d.add(newDropRoleTask(clusterIdentifier, role))
+
+	for _, revokeRoleTask := range d.lookupRoleGrantTasks(RevokeRole, clusterIdentifier, role.Name) {
+		dropRoleTask.dependsOn(revokeRoleTask)
+	}
+}
+
+//Grants and revokes the roles granted to the role
+func (d *Reconciler) updateRole(clusterIdentifier string, current *Role, desired *Role) {
+
+	for _, granted := range current.Granted {
+		if !desired.IsGranted(granted.Name) {
+			d.revokeRole(clusterIdentifier, granted.Name, RoleGrantee, current.Name)
+		}
+	}
+
+	for _, granted := range desired.Granted {
+		if !current.IsGranted(granted.Name) {
+			d.grantRole(clusterIdentifier, granted.Name, RoleGrantee, desired.Name)
+		}
+	}
+}
+
+func (d *Reconciler) grantRole(clusterIdentifier string, roleName string, grantee Grantee, granteeName string) *Task {
+
+	grantRoleTask := d.add(newGrantRoleTask(clusterIdentifier, roleName, grantee, granteeName))
+
+	createRoleTask := d.lookupCreateRoleTask(clusterIdentifier, roleName)
+	if createRoleTask != nil {
+		grantRoleTask.dependsOn(createRoleTask)
+	}
+
+	if grantee == RoleGrantee {
+		createGranteeTask := d.lookupCreateRoleTask(clusterIdentifier, granteeName)
+		if createGranteeTask != nil {
+			grantRoleTask.dependsOn(createGranteeTask)
+		}
+	}
+	return grantRoleTask
+}
+
+func (d *Reconciler) revokeRole(clusterIdentifier string, roleName string, grantee Grantee, granteeName string) *Task {
+
+	revokeRoleTask := d.add(newRevokeRoleTask(clusterIdentifier, roleName, grantee, granteeName))
+
+	dropRoleTask := d.lookupDropRoleTask(clusterIdentifier, roleName)
+	if dropRoleTask != nil {
+		dropRoleTask.dependsOn(revokeRoleTask)
+	}
+
+	if grantee == RoleGrantee {
+		dropGranteeTask := d.lookupDropRoleTask(clusterIdentifier, granteeName)
+		if dropGranteeTask != nil {
+			dropGranteeTask.dependsOn(revokeRoleTask)
+		}
+	}
+	return revokeRoleTask
+}
+
 func (d *Reconciler) addDatabaseGroup(database *Database, group *DatabaseGroup) {
 
 	createDatabaseTask := d.lookupCreateDatabaseTask(database.ClusterIdentifier, database.Name)
 
 	for _, schema := range group.GrantedSchemas {
 
-		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group.Name))
+		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group, schema.Privilege))
 
 		if createDatabaseTask != nil {
 			grantAccessTask.dependsOn(createDatabaseTask)
@@ -367,7 +657,7 @@ func (d *Reconciler) addDatabaseGroup(database *Database, group *DatabaseGroup)
 
 		grantAccessTask.dependsOn(createSchemaTask)
 
-		createGroupTask := d.lookupCreateGroupTask(database.ClusterIdentifier, group.Name)
+		createGroupTask := d.lookupCreateGranteeTask(database.ClusterIdentifier, group)
 		if createGroupTask != nil {
 			grantAccessTask.dependsOn(createGroupTask)
 		}
@@ -375,7 +665,7 @@ func (d *Reconciler) addDatabaseGroup(database *Database, group *DatabaseGroup)
 
 	for _, schema := range group.GrantedExternalSchemas {
 
-		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group.Name))
+		grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, group, ReadPrivilege))
 
 		if createDatabaseTask != nil {
 			grantAccessTask.dependsOn(createDatabaseTask)
@@ -389,11 +679,30 @@ func (d *Reconciler) addDatabaseGroup(database *Database, group *DatabaseGroup)
 
 		grantAccessTask.dependsOn(createSchemaTask)
 
-		createGroupTask := d.lookupCreateGroupTask(database.ClusterIdentifier, group.Name)
+		replaceSchemaTask := d.lookupReplaceExternalSchemaTask(database, schema.Name)
+		if replaceSchemaTask != nil {
+			grantAccessTask.dependsOn(replaceSchemaTask)
+		}
+
+		createGroupTask := d.lookupCreateGranteeTask(database.ClusterIdentifier, group)
 		if createGroupTask != nil {
 			grantAccessTask.dependsOn(createGroupTask)
 		}
 	}
+
+	for _, table := range group.GrantedTables {
+
+		grantTableAccessTask := d.add(newGrantTableAccessTask(database, table, group))
+
+		if createDatabaseTask != nil {
+			grantTableAccessTask.dependsOn(createDatabaseTask)
+		}
+
+		createGroupTask := d.lookupCreateGranteeTask(database.ClusterIdentifier, group)
+		if createGroupTask != nil {
+			grantTableAccessTask.dependsOn(createGroupTask)
+		}
+	}
 }
 
 func (d *Reconciler) dropDatabaseGroup(database *Database, group *DatabaseGroup) {
@@ -401,9 +710,9 @@ func (d *Reconciler) dropDatabaseGroup(database *Database, group *DatabaseGroup)
 	for _, schema := range group.GrantedSchemas {
 
 		if d.config.RevokeAccessToPublicSchema || schema.Name != "public" {
-			revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, group.Name))
+			revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, group))
 
-			dropGroupTask := d.lookupDropGroupTask(database.ClusterIdentifier, group.Name)
+			dropGroupTask := d.lookupDropGranteeTask(database.ClusterIdentifier, group)
 			if dropGroupTask != nil {
 				dropGroupTask.dependsOn(revokeAccessTask)
 			}
@@ -412,12 +721,27 @@ func (d *Reconciler) dropDatabaseGroup(database *Database, group *DatabaseGroup)
 
 	for _, schema := range group.GrantedExternalSchemas {
 
-		revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, group.Name))
+		revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, group))
 
-		dropGroupTask := d.lookupDropGroupTask(database.ClusterIdentifier, group.Name)
+		dropGroupTask := d.lookupDropGranteeTask(database.ClusterIdentifier, group)
 		if dropGroupTask != nil {
 			dropGroupTask.dependsOn(revokeAccessTask)
 		}
+
+		replaceSchemaTask := d.lookupReplaceExternalSchemaTask(database, schema.Name)
+		if replaceSchemaTask != nil {
+			replaceSchemaTask.dependsOn(revokeAccessTask)
+		}
+	}
+
+	for _, table := range group.GrantedTables {
+
+		revokeTableAccessTask := d.add(newRevokeTableAccessTask(database, table, group, group.LookupGrantedSchema(table.Schema) == nil))
+
+		dropGroupTask := d.lookupDropGranteeTask(database.ClusterIdentifier, group)
+		if dropGroupTask != nil {
+			dropGroupTask.dependsOn(revokeTableAccessTask)
+		}
 	}
 }
 
@@ -429,9 +753,9 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 
 		if grantDesired == nil {
 			if d.config.RevokeAccessToPublicSchema || schema.Name != "public" {
-				revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, current.Name))
+				revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, current))
 
-				dropGroupTask := d.lookupDropGroupTask(database.ClusterIdentifier, current.Name)
+				dropGroupTask := d.lookupDropGranteeTask(database.ClusterIdentifier, current)
 				if dropGroupTask != nil {
 					dropGroupTask.dependsOn(revokeAccessTask)
 				}
@@ -444,12 +768,38 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 		grantDesired := desired.LookupGrantedExternalSchema(schema.Name)
 
 		if grantDesired == nil {
-			revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, current.Name))
+			revokeAccessTask := d.add(newRevokeAccessTask(database, schema.Name, current))
 
-			dropGroupTask := d.lookupDropGroupTask(database.ClusterIdentifier, current.Name)
+			dropGroupTask := d.lookupDropGranteeTask(database.ClusterIdentifier, current)
 			if dropGroupTask != nil {
 				dropGroupTask.dependsOn(revokeAccessTask)
 			}
+
+			replaceSchemaTask := d.lookupReplaceExternalSchemaTask(database, schema.Name)
+			if replaceSchemaTask != nil {
+				replaceSchemaTask.dependsOn(revokeAccessTask)
+			}
+		}
+	}
+
+	for _, table := range current.GrantedTables {
+
+		grantDesired := desired.LookupGrantedTable(table.Schema, table.Name)
+
+		if grantDesired == nil {
+			//the usage of the schema is kept as long as the group is granted other tables in it or the whole schema
+			revokeUsage := !desired.HasTablesIn(table.Schema) && desired.LookupGrantedSchema(table.Schema) == nil
+			revokeTableAccessTask := d.add(newRevokeTableAccessTask(database, table, current, revokeUsage))
+
+			dropGroupTask := d.lookupDropGranteeTask(database.ClusterIdentifier, current)
+			if dropGroupTask != nil {
+				dropGroupTask.dependsOn(revokeTableAccessTask)
+			}
+		} else if !table.SameColumns(grantDesired) {
+			//the granted columns are changed by revoking the current grant before granting the desired columns
+			revokeTableAccessTask := d.add(newRevokeTableAccessTask(database, table, current, false))
+			grantTableAccessTask := d.add(newGrantTableAccessTask(database, grantDesired, desired))
+			grantTableAccessTask.dependsOn(revokeTableAccessTask)
 		}
 	}
 
@@ -457,13 +807,29 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 
 		grantCurrent := current.LookupGrantedSchema(schema.Name)
 
+		if grantCurrent != nil && schema.Privilege > grantCurrent.Privilege {
+			d.add(newUpgradeAccessTask(database, schema, desired))
+		}
+
+		if grantCurrent != nil && schema.Privilege < grantCurrent.Privilege {
+			//the public schema is left alone if access to it mustn't be revoked
+			if d.config.RevokeAccessToPublicSchema || schema.Name != "public" {
+				d.add(newDowngradeAccessTask(database, schema, desired))
+			}
+		}
+
 		if grantCurrent == nil {
-			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired.Name))
+			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired, schema.Privilege))
 
 			createSchemaTask := d.add(newCreateSchemaTask(database, schema))
 			grantAccessTask.dependsOn(createSchemaTask)
 
-			createGroupTask := d.lookupCreateGroupTask(database.ClusterIdentifier, desired.Name)
+			//tables granted before the whole schema was granted are revoked first, so the revoke doesn't remove the select granted on all the tables of the schema
+			for _, revokeTableAccessTask := range d.lookupRevokeTableAccessTasks(database, schema.Name, desired) {
+				grantAccessTask.dependsOn(revokeTableAccessTask)
+			}
+
+			createGroupTask := d.lookupCreateGranteeTask(database.ClusterIdentifier, desired)
 			if createGroupTask != nil {
 				grantAccessTask.dependsOn(createGroupTask)
 			}
@@ -473,18 +839,47 @@ func (d *Reconciler) updateDatabaseGroup(database *Database, current *DatabaseGr
 	for _, schema := range desired.GrantedExternalSchemas {
 
 		grantCurrent := current.LookupGrantedExternalSchema(schema.Name)
+		replaceSchemaTask := d.lookupReplaceExternalSchemaTask(database, schema.Name)
 
 		if grantCurrent == nil {
 
-			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired.Name))
+			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired, ReadPrivilege))
 
 			createSchemaTask := d.add(newCreateExternalSchemaTask(database, schema))
 			grantAccessTask.dependsOn(createSchemaTask)
 
-			createGroupTask := d.lookupCreateGroupTask(database.ClusterIdentifier, desired.Name)
+			if replaceSchemaTask != nil {
+				grantAccessTask.dependsOn(replaceSchemaTask)
+			}
+
+			createGroupTask := d.lookupCreateGranteeTask(database.ClusterIdentifier, desired)
 			if createGroupTask != nil {
 				grantAccessTask.dependsOn(createGroupTask)
 			}
+		} else if replaceSchemaTask != nil {
+			//the grants on the schema are dropped along with it
+			grantAccessTask := d.add(newGrantAccessTask(database, schema.Name, desired, ReadPrivilege))
+			grantAccessTask.dependsOn(replaceSchemaTask)
+		}
+	}
+
+	for _, table := range desired.GrantedTables {
+
+		grantCurrent := current.LookupGrantedTable(table.Schema, table.Name)
+
+		if grantCurrent == nil {
+			grantTableAccessTask := d.add(newGrantTableAccessTask(database, table, desired))
+
+			//if the whole schema was granted before, it is revoked before the tables are granted
+			revokeAccessTask := d.lookupRevokeAccessTask(database, table.Schema, desired)
+			if revokeAccessTask != nil {
+				grantTableAccessTask.dependsOn(revokeAccessTask)
+			}
+
+			createGroupTask := d.lookupCreateGranteeTask(database.ClusterIdentifier, desired)
+			if createGroupTask != nil {
+				grantTableAccessTask.dependsOn(createGroupTask)
+			}
 		}
 	}
 }
@@ -40,6 +40,13 @@ func newCreateExternalSchemaTask(database *Database, model *ExternalSchema) *Tas
 	})
 }
 
+func newReplaceExternalSchemaTask(database *Database, model *ExternalSchema) *Task {
+	return NewTask(model.Name, ReplaceExternalSchema, &ExternalSchemaModel{
+		Schema:   model,
+		Database: database,
+	})
+}
+
 func newCreateDatabaseTask(clusterIdentifier string, model *Database) *Task {
 	return NewTask(model.Name, CreateDatabase, &DatabaseModel{
 		Database:          model,
@@ -47,27 +54,69 @@ func newCreateDatabaseTask(clusterIdentifier string, model *Database) *Task {
 	})
 }
 
-func newGrantAccessTask(database *Database, schemaName string, groupName string) *Task {
-	return NewTask(fmt.Sprintf("%s->%s", groupName, schemaName), GrantAccess, &GrantsModel{
-		GroupName:  groupName,
+func newGrantAccessTask(database *Database, schemaName string, group *DatabaseGroup, privilege Privilege) *Task {
+	return NewTask(fmt.Sprintf("%s->%s", group.Identifier(), schemaName), GrantAccess, &GrantsModel{
+		GroupName:  group.Name,
+		Grantee:    group.Grantee,
 		SchemaName: schemaName,
 		Database:   database,
+		Privilege:  privilege,
+	})
+}
+
+func newUpgradeAccessTask(database *Database, schema *Schema, group *DatabaseGroup) *Task {
+	return NewTask(fmt.Sprintf("%s->%s (%s)", group.Identifier(), schema.Name, schema.Privilege), UpgradeAccess, &GrantsModel{
+		GroupName:  group.Name,
+		Grantee:    group.Grantee,
+		SchemaName: schema.Name,
+		Database:   database,
+		Privilege:  schema.Privilege,
 	})
 }
 
-func newRevokeAccessTask(database *Database, schemaName string, groupName string) *Task {
-	return NewTask(fmt.Sprintf("%s->%s", groupName, schemaName), RevokeAccess, &GrantsModel{
-		GroupName:  groupName,
+func newDowngradeAccessTask(database *Database, schema *Schema, group *DatabaseGroup) *Task {
+	return NewTask(fmt.Sprintf("%s->%s (%s)", group.Identifier(), schema.Name, schema.Privilege), DowngradeAccess, &GrantsModel{
+		GroupName:  group.Name,
+		Grantee:    group.Grantee,
+		SchemaName: schema.Name,
+		Database:   database,
+		Privilege:  schema.Privilege,
+	})
+}
+
+func newRevokeAccessTask(database *Database, schemaName string, group *DatabaseGroup) *Task {
+	return NewTask(fmt.Sprintf("%s->%s", group.Identifier(), schemaName), RevokeAccess, &GrantsModel{
+		GroupName:  group.Name,
+		Grantee:    group.Grantee,
 		SchemaName: schemaName,
 		Database:   database,
 	})
 }
 
+func newGrantTableAccessTask(database *Database, table *Table, group *DatabaseGroup) *Task {
+	return NewTask(fmt.Sprintf("%s->%s", group.Identifier(), table.Identifier()), GrantTableAccess, &TableGrantsModel{
+		GroupName: group.Name,
+		Grantee:   group.Grantee,
+		Table:     table,
+		Database:  database,
+	})
+}
+
+func newRevokeTableAccessTask(database *Database, table *Table, group *DatabaseGroup, revokeUsage bool) *Task {
+	return NewTask(fmt.Sprintf("%s->%s", group.Identifier(), table.Identifier()), RevokeTableAccess, &TableGrantsModel{
+		GroupName:   group.Name,
+		Grantee:     group.Grantee,
+		Table:       table,
+		Database:    database,
+		RevokeUsage: revokeUsage,
+	})
+}
+
 func newAddToGroupTask(clusterIdentifier string, model *User, group *Group) *Task {
 	return NewTask(fmt.Sprintf("%s->%s", model.Name, group.Name), AddToGroup, &MembershipModel{
 		ClusterIdentifier: clusterIdentifier,
 		Username:          model.Name,
-		GroupName:         model.Role().Name,
+		GroupName:         group.Name,
 	})
 }
 
@@ -75,6 +124,38 @@ func newRemoveFromGroupTask(clusterIdentifier string, model *User, group *Group)
 	return NewTask(fmt.Sprintf("%s->%s", model.Name, group.Name), RemoveFromGroup, &MembershipModel{
 		ClusterIdentifier: clusterIdentifier,
 		Username:          model.Name,
-		GroupName:         model.Role().Name,
+		GroupName:         group.Name,
+	})
+}
+
+func newCreateRoleTask(clusterIdentifier string, model *Role) *Task {
+	return NewTask(model.Name, CreateRole, &RoleModel{
+		Role:              model,
+		ClusterIdentifier: clusterIdentifier,
+	})
+}
+
+func newDropRoleTask(clusterIdentifier string, model *Role) *Task {
+	return NewTask(model.Name, DropRole, &RoleModel{
+		Role:              model,
+		ClusterIdentifier: clusterIdentifier,
+	})
+}
+
+func newGrantRoleTask(clusterIdentifier string, roleName string, grantee Grantee, granteeName string) *Task {
+	return NewTask(fmt.Sprintf("%s->%s", granteeName, roleName), GrantRole, &RoleGrantModel{
+		ClusterIdentifier: clusterIdentifier,
+		RoleName:          roleName,
+		Grantee:           grantee,
+		GranteeName:       granteeName,
+	})
+}
+
+func newRevokeRoleTask(clusterIdentifier string, roleName string, grantee Grantee, granteeName string) *Task {
+	return NewTask(fmt.Sprintf("%s->%s", granteeName, roleName), RevokeRole, &RoleGrantModel{
+		ClusterIdentifier: clusterIdentifier,
+		RoleName:          roleName,
+		Grantee:           grantee,
+		GranteeName:       granteeName,
 	})
 }
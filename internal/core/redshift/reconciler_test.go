@@ -38,3 +38,344 @@ func Test_Dag1(t *testing.T) {
 
 	assert.Equal(8, dag.NumTasks())
 }
+
+func buildWithGrants(grant func(group *DatabaseGroup)) Model {
+
+	model := Model{}
+	cluster := model.DeclareCluster("dev")
+	group := cluster.DeclareGroup("creditanalyst")
+	database := cluster.DeclareDatabase("prod")
+	cluster.DeclareUser("jwr_creditanalyst", group)
+	database.DeclareUser("jwr_creditanalyst")
+	grant(database.DeclareGroup("creditanalyst"))
+
+	return model
+}
+
+func lookupTaskOfType(dag *ReconciliationDag, taskType TaskType) *Task {
+	for _, task := range dag.Tasks() {
+		if task.Type() == taskType {
+			return task
+		}
+	}
+	return nil
+}
+
+func Test_Dag_TableGrantColumnsChanged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantTable(&Table{Schema: "credit", Name: "loans", Columns: []string{"id"}})
+	})
+	desired := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantTable(&Table{Schema: "credit", Name: "loans", Columns: []string{"id", "amount"}})
+	})
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(2, dag.NumTasks())
+	revoke := lookupTaskOfType(dag, RevokeTableAccess)
+	grant := lookupTaskOfType(dag, GrantTableAccess)
+	assert.NotNil(revoke)
+	assert.NotNil(grant)
+	assert.True(grant.isUpstream(revoke), "the current columns are revoked before the desired columns are granted")
+	assert.Equal("dev", grant.ClusterIdentifier())
+	assert.False(revoke.model.(*TableGrantsModel).RevokeUsage, "the usage of the schema is kept as the table is still granted")
+}
+
+func Test_Dag_TableGrantUnchanged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantTable(&Table{Schema: "credit", Name: "loans", Columns: []string{"amount", "id"}})
+	})
+	desired := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantTable(&Table{Schema: "credit", Name: "loans", Columns: []string{"id", "amount"}})
+	})
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(0, dag.NumTasks(), "the order of the columns doesn't matter")
+}
+
+func Test_Dag_SchemaGrantReplacedByTableGrant(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantSchema(&Schema{Name: "credit"})
+	})
+	desired := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantTable(&Table{Schema: "credit", Name: "loans"})
+	})
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(2, dag.NumTasks())
+	revoke := lookupTaskOfType(dag, RevokeAccess)
+	grant := lookupTaskOfType(dag, GrantTableAccess)
+	assert.NotNil(revoke)
+	assert.NotNil(grant)
+	assert.True(grant.isUpstream(revoke), "the schema is revoked before the table is granted")
+}
+
+func Test_Dag_TableGrantRevoked(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantTable(&Table{Schema: "credit", Name: "loans"})
+		group.GrantTable(&Table{Schema: "credit", Name: "customers"})
+	})
+	desired := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantTable(&Table{Schema: "credit", Name: "loans"})
+	})
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(1, dag.NumTasks())
+	revoke := lookupTaskOfType(dag, RevokeTableAccess)
+	assert.Equal("customers", revoke.model.(*TableGrantsModel).Table.Name)
+	assert.False(revoke.model.(*TableGrantsModel).RevokeUsage, "the usage of the schema is kept as another table in it is still granted")
+}
+
+func Test_Dag_PrivilegeUpgradedAndDowngraded(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantSchema(&Schema{Name: "credit", Privilege: ReadPrivilege})
+		group.GrantSchema(&Schema{Name: "core", Privilege: OwnerPrivilege})
+		group.GrantSchema(&Schema{Name: "bi", Privilege: WritePrivilege})
+	})
+	desired := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantSchema(&Schema{Name: "credit", Privilege: WritePrivilege})
+		group.GrantSchema(&Schema{Name: "core", Privilege: ReadPrivilege})
+		group.GrantSchema(&Schema{Name: "bi", Privilege: WritePrivilege})
+	})
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(2, dag.NumTasks(), "the unchanged privilege level is left alone")
+
+	upgrade := lookupTaskOfType(dag, UpgradeAccess)
+	assert.Equal("credit", upgrade.model.(*GrantsModel).SchemaName)
+	assert.Equal(WritePrivilege, upgrade.model.(*GrantsModel).Privilege)
+
+	downgrade := lookupTaskOfType(dag, DowngradeAccess)
+	assert.Equal("core", downgrade.model.(*GrantsModel).SchemaName)
+	assert.Equal(ReadPrivilege, downgrade.model.(*GrantsModel).Privilege)
+}
+
+func Test_Dag_PublicSchemaNotDowngraded(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantSchema(&Schema{Name: "public", Privilege: OwnerPrivilege})
+	})
+	desired := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantSchema(&Schema{Name: "public"})
+	})
+	dag := Reconcile(&current, &desired, ReconcilerConfig{RevokeAccessToPublicSchema: false})
+
+	assert.Equal(0, dag.NumTasks())
+}
+
+func buildWithRoles(grant func(role *DatabaseGroup)) Model {
+
+	model := Model{}
+	cluster := model.DeclareCluster("dev")
+	analyst := cluster.DeclareRole("analyst")
+	role := cluster.DeclareRole("creditanalyst")
+	role.Grant(analyst)
+	database := cluster.DeclareDatabase("prod")
+	cluster.DeclareUserWithRole("jwr_creditanalyst", role)
+	database.DeclareUser("jwr_creditanalyst")
+	database.DeclareRole("analyst").GrantSchema(&Schema{Name: "public"})
+	grant(database.DeclareRole("creditanalyst"))
+
+	return model
+}
+
+func Test_Dag_RolesCreated(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := Model{}
+	desired := buildWithRoles(func(role *DatabaseGroup) {
+		role.GrantSchema(&Schema{Name: "credit"})
+	})
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	var createRoleTasks, grantRoleTasks []*Task
+	for _, task := range dag.Tasks() {
+		switch task.Type() {
+		case CreateRole:
+			createRoleTasks = append(createRoleTasks, task)
+		case GrantRole:
+			grantRoleTasks = append(grantRoleTasks, task)
+		}
+	}
+	assert.Len(createRoleTasks, 2)
+	assert.Len(grantRoleTasks, 2, "the user is granted its role and the nested role is granted to the role")
+	assert.Nil(lookupTaskOfType(dag, CreateGroup))
+	assert.Nil(lookupTaskOfType(dag, AddToGroup))
+
+	for _, grantRoleTask := range grantRoleTasks {
+		for _, createRoleTask := range createRoleTasks {
+			if createRoleTask.model.(*RoleModel).Role.Name == grantRoleTask.model.(*RoleGrantModel).RoleName {
+				assert.True(grantRoleTask.isUpstream(createRoleTask), "%s must be granted after the role is created", grantRoleTask.Identifier())
+			}
+		}
+	}
+
+	createUser := lookupTaskOfType(dag, CreateUser)
+	for _, grantRoleTask := range grantRoleTasks {
+		if grantRoleTask.model.(*RoleGrantModel).Grantee == UserGrantee {
+			assert.Equal("jwr_creditanalyst", grantRoleTask.model.(*RoleGrantModel).GranteeName)
+			assert.True(grantRoleTask.isUpstream(createUser))
+		}
+	}
+
+	for _, task := range dag.Tasks() {
+		if task.Type() == GrantAccess {
+			assert.Equal(RoleGrantee, task.model.(*GrantsModel).Grantee)
+		}
+	}
+}
+
+func Test_Dag_NestedRoleRevoked(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithRoles(func(role *DatabaseGroup) {})
+	desired := buildWithRoles(func(role *DatabaseGroup) {})
+	desired.LookupCluster("dev").LookupRole("creditanalyst").Granted = nil
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(1, dag.NumTasks())
+	revoke := lookupTaskOfType(dag, RevokeRole)
+	assert.Equal(&RoleGrantModel{ClusterIdentifier: "dev", RoleName: "analyst", Grantee: RoleGrantee, GranteeName: "creditanalyst"}, revoke.model)
+}
+
+func Test_Dag_GroupMigratedToRole(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {
+		group.GrantSchema(&Schema{Name: "credit"})
+	})
+	desired := buildWithRoles(func(role *DatabaseGroup) {
+		role.GrantSchema(&Schema{Name: "credit"})
+	})
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	var grantRole, grantAccess *Task
+	for _, task := range dag.Tasks() {
+		if task.Type() == GrantRole && task.model.(*RoleGrantModel).Grantee == UserGrantee {
+			grantRole = task
+		}
+		if task.Type() == GrantAccess && task.model.(*GrantsModel).GroupName == "creditanalyst" {
+			grantAccess = task
+		}
+	}
+	assert.NotNil(grantRole)
+	assert.NotNil(grantAccess)
+
+	removeFromGroup := lookupTaskOfType(dag, RemoveFromGroup)
+	revokeAccess := lookupTaskOfType(dag, RevokeAccess)
+	dropGroup := lookupTaskOfType(dag, DropGroup)
+	assert.Equal(GroupGrantee, revokeAccess.model.(*GrantsModel).Grantee)
+
+	for _, task := range []*Task{removeFromGroup, revokeAccess, dropGroup} {
+		assert.True(task.isUpstream(grantRole), "%s must wait until the user has been granted the role", task.Type())
+		assert.True(task.isUpstream(grantAccess), "%s must wait until the role has been granted access", task.Type())
+	}
+	assert.Nil(lookupTaskOfType(dag, DropUser), "the user is kept")
+}
+
+func Test_Dag_AuxiliaryGroups(t *testing.T) {
+
+	assert := assert.New(t)
+
+	current := buildWithGrants(func(group *DatabaseGroup) {})
+	currentCluster := current.LookupCluster("dev")
+	currentCluster.DeclareUser("jwr_creditanalyst", currentCluster.DeclareGroup("auditors"))
+
+	desired := buildWithGrants(func(group *DatabaseGroup) {})
+	desiredCluster := desired.LookupCluster("dev")
+	desiredCluster.DeclareUser("jwr_creditanalyst", desiredCluster.DeclareGroup("readers"))
+	desiredCluster.DeclareUser("nra_creditanalyst", desiredCluster.LookupGroup("creditanalyst"))
+	desiredCluster.DeclareUser("nra_creditanalyst", desiredCluster.LookupGroup("readers"))
+
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	var memberships []MembershipModel
+	for _, task := range dag.Tasks() {
+		if task.Type() == AddToGroup || task.Type() == RemoveFromGroup {
+			memberships = append(memberships, *task.model.(*MembershipModel))
+		}
+	}
+	assert.ElementsMatch([]MembershipModel{
+		{ClusterIdentifier: "dev", Username: "jwr_creditanalyst", GroupName: "auditors"},
+		{ClusterIdentifier: "dev", Username: "jwr_creditanalyst", GroupName: "readers"},
+		{ClusterIdentifier: "dev", Username: "nra_creditanalyst", GroupName: "creditanalyst"},
+		{ClusterIdentifier: "dev", Username: "nra_creditanalyst", GroupName: "readers"},
+	}, memberships, "the user is only added to and removed from the groups that differ")
+
+	removeFromGroup := lookupTaskOfType(dag, RemoveFromGroup)
+	assert.Equal("auditors", removeFromGroup.model.(*MembershipModel).GroupName)
+	assert.True(lookupTaskOfType(dag, DropGroup).isUpstream(removeFromGroup))
+
+	for _, task := range dag.Tasks() {
+		if task.Type() == AddToGroup && task.model.(*MembershipModel).GroupName == "readers" {
+			assert.True(task.isUpstream(lookupTaskOfType(dag, CreateGroup)), "%s waits for the group to be created", task.Identifier())
+		}
+	}
+}
+
+func Test_Dag_ExternalSchemaUnchanged(t *testing.T) {
+
+	assert := assert.New(t)
+
+	lake := func(group *DatabaseGroup) {
+		group.GrantExternalSchema(&ExternalSchema{Name: "lake", GlueDatabaseName: "lake", IamRoles: []string{"arn:aws:iam::123456789012:role/redshift"}, CatalogRegion: "eu-west-1"})
+	}
+	current := buildWithGrants(lake)
+	desired := buildWithGrants(lake)
+	dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+	assert.Equal(0, dag.NumTasks())
+}
+
+func Test_Dag_ExternalSchemaChanged(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		desired *ExternalSchema
+	}{
+		{name: "glue database", desired: &ExternalSchema{Name: "lake", GlueDatabaseName: "datalake", IamRoles: []string{"arn:aws:iam::123456789012:role/redshift"}}},
+		{name: "IAM roles", desired: &ExternalSchema{Name: "lake", GlueDatabaseName: "lake", IamRoles: []string{"arn:aws:iam::123456789012:role/redshift", "arn:aws:iam::210987654321:role/datalake"}}},
+		{name: "catalog region", desired: &ExternalSchema{Name: "lake", GlueDatabaseName: "lake", IamRoles: []string{"arn:aws:iam::123456789012:role/redshift"}, CatalogRegion: "us-east-1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			current := buildWithGrants(func(group *DatabaseGroup) {
+				group.GrantExternalSchema(&ExternalSchema{Name: "lake", GlueDatabaseName: "lake", IamRoles: []string{"arn:aws:iam::123456789012:role/redshift"}})
+			})
+			desired := buildWithGrants(func(group *DatabaseGroup) {
+				group.GrantExternalSchema(tc.desired)
+			})
+			dag := Reconcile(&current, &desired, DefaultReconcilerConfig())
+
+			assert.Equal(2, dag.NumTasks())
+
+			replace := lookupTaskOfType(dag, ReplaceExternalSchema)
+			assert.Equal(tc.desired, replace.model.(*ExternalSchemaModel).Schema)
+
+			grant := lookupTaskOfType(dag, GrantAccess)
+			assert.True(grant.isUpstream(replace), "the grant dropped along with the schema is granted again once it has been created")
+		})
+	}
+}
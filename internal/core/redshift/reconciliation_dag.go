@@ -16,6 +16,10 @@ func (d *ReconciliationDag) NumTasks() int {
 	return len(d.tasks)
 }
 
+func (d *ReconciliationDag) Tasks() []*Task {
+	return d.tasks
+}
+
 func (d *ReconciliationDag) GetWaiting() []*Task {
 	var result []*Task
 
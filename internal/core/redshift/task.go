@@ -19,6 +19,15 @@ const (
 	RevokeAccess
 	AddToGroup
 	RemoveFromGroup
+	GrantTableAccess
+	RevokeTableAccess
+	UpgradeAccess
+	DowngradeAccess
+	CreateRole
+	DropRole
+	GrantRole
+	RevokeRole
+	ReplaceExternalSchema
 )
 
 type TaskState int
@@ -31,9 +40,15 @@ const (
 	Skipped
 )
 
+func (s TaskState) String() string {
+	return [...]string{"Running", "Pending", "Success", "Failed", "Skipped"}[s]
+}
+
 func (t TaskType) String() string {
 	return [...]string{"CreateUser", "DropUser", "CreateGroup", "DropGroup", "CreateSchema",
-		"CreateExternalSchema", "CreateDatabase", "GrantAccess", "RevokeAccess", "AddToGroup", "RemoveFromGroup"}[t]
+		"CreateExternalSchema", "CreateDatabase", "GrantAccess", "RevokeAccess", "AddToGroup", "RemoveFromGroup",
+		"GrantTableAccess", "RevokeTableAccess", "UpgradeAccess", "DowngradeAccess", "CreateRole", "DropRole",
+		"GrantRole", "RevokeRole", "ReplaceExternalSchema"}[t]
 }
 
 type Equatable interface {
@@ -47,6 +62,7 @@ type Task struct {
 	upStream   []*Task
 	downStream []*Task
 	state      TaskState
+	err        error
 }
 
 func NewTask(identifier string, taskType TaskType, model Equatable) *Task {
@@ -61,14 +77,60 @@ func (t *Task) Success() {
 	t.state = Success
 }
 
-func (t *Task) Failed() {
+func (t *Task) Failed(err error) {
 	t.state = Failed
+	t.err = err
 }
 
 func (t *Task) Start() {
 	t.state = Running
 }
 
+func (t *Task) Identifier() string {
+	return t.identifier
+}
+
+func (t *Task) Type() TaskType {
+	return t.taskType
+}
+
+func (t *Task) State() TaskState {
+	return t.state
+}
+
+// The error that caused the task to fail, nil unless the task failed
+func (t *Task) Err() error {
+	return t.err
+}
+
+// The identifier of the cluster the task is executed against
+func (t *Task) ClusterIdentifier() string {
+	switch model := t.model.(type) {
+	case *UserModel:
+		return model.ClusterIdentifier
+	case *GroupModel:
+		return model.ClusterIdentifier
+	case *MembershipModel:
+		return model.ClusterIdentifier
+	case *DatabaseModel:
+		return model.ClusterIdentifier
+	case *SchemaModel:
+		return model.Database.ClusterIdentifier
+	case *ExternalSchemaModel:
+		return model.Database.ClusterIdentifier
+	case *GrantsModel:
+		return model.Database.ClusterIdentifier
+	case *TableGrantsModel:
+		return model.Database.ClusterIdentifier
+	case *RoleModel:
+		return model.ClusterIdentifier
+	case *RoleGrantModel:
+		return model.ClusterIdentifier
+	default:
+		return ""
+	}
+}
+
 func (t *Task) isDone() bool {
 	return t.state == Success || t.state == Failed || t.state == Skipped
 }
@@ -4,6 +4,8 @@ type GrantsModel struct {
 	Database   *Database
 	SchemaName string
 	GroupName  string
+	Grantee    Grantee   //whether the access is granted to a group or a role
+	Privilege  Privilege //the privilege level that is granted, or that is upgraded or downgraded to
 }
 
 func (s *GrantsModel) Equals(rhs Equatable) bool {
@@ -16,9 +18,34 @@ func (s *GrantsModel) Equals(rhs Equatable) bool {
 	}
 	return s.Database.Name == other.Database.Name &&
 		s.GroupName == other.GroupName &&
+		s.Grantee == other.Grantee &&
 		s.SchemaName == other.SchemaName
 }
 
+type TableGrantsModel struct {
+	Database    *Database
+	Table       *Table
+	GroupName   string
+	Grantee     Grantee //whether the access is granted to a group or a role
+	RevokeUsage bool    //set when revoking the last table the group has been granted in the schema, so the usage of the schema is revoked too
+}
+
+func (s *TableGrantsModel) Equals(rhs Equatable) bool {
+	if rhs == nil {
+		return false
+	}
+	other, ok := rhs.(*TableGrantsModel)
+	if !ok {
+		return false
+	}
+	return s.Database.ClusterIdentifier == other.Database.ClusterIdentifier &&
+		s.Database.Name == other.Database.Name &&
+		s.GroupName == other.GroupName &&
+		s.Grantee == other.Grantee &&
+		s.Table.Schema == other.Table.Schema &&
+		s.Table.Name == other.Table.Name
+}
+
 type MembershipModel struct {
 	ClusterIdentifier string
 	Username          string
@@ -38,6 +65,45 @@ func (s *MembershipModel) Equals(rhs Equatable) bool {
 		s.ClusterIdentifier == other.ClusterIdentifier
 }
 
+type RoleModel struct {
+	Role              *Role
+	ClusterIdentifier string
+}
+
+func (s *RoleModel) Equals(rhs Equatable) bool {
+	if rhs == nil {
+		return false
+	}
+	other, ok := rhs.(*RoleModel)
+	if !ok {
+		return false
+	}
+	return s.Role.Name == other.Role.Name &&
+		s.ClusterIdentifier == other.ClusterIdentifier
+}
+
+// A role granted to a user or to another role
+type RoleGrantModel struct {
+	ClusterIdentifier string
+	RoleName          string
+	Grantee           Grantee //either UserGrantee or RoleGrantee
+	GranteeName       string
+}
+
+func (s *RoleGrantModel) Equals(rhs Equatable) bool {
+	if rhs == nil {
+		return false
+	}
+	other, ok := rhs.(*RoleGrantModel)
+	if !ok {
+		return false
+	}
+	return s.RoleName == other.RoleName &&
+		s.Grantee == other.Grantee &&
+		s.GranteeName == other.GranteeName &&
+		s.ClusterIdentifier == other.ClusterIdentifier
+}
+
 type DatabaseModel struct {
 	Database          *Database
 	ClusterIdentifier string
@@ -12,11 +12,20 @@ type TaskRunner interface {
 	DropGroup(model *GroupModel) error
 	CreateSchema(model *SchemaModel) error
 	CreateExternalSchema(model *ExternalSchemaModel) error
+	ReplaceExternalSchema(model *ExternalSchemaModel) error
 	CreateDatabase(model *DatabaseModel) error
 	GrantAccess(model *GrantsModel) error
 	RevokeAccess(model *GrantsModel) error
+	GrantTableAccess(model *TableGrantsModel) error
+	RevokeTableAccess(model *TableGrantsModel) error
+	UpgradeAccess(model *GrantsModel) error
+	DowngradeAccess(model *GrantsModel) error
 	AddToGroup(model *MembershipModel) error
 	RemoveFromGroup(model *MembershipModel) error
+	CreateRole(model *RoleModel) error
+	DropRole(model *RoleModel) error
+	GrantRole(model *RoleGrantModel) error
+	RevokeRole(model *RoleGrantModel) error
 }
 
 func ExecuteTask(taskRunner TaskRunner, task *Task) error {
@@ -35,14 +44,32 @@ func ExecuteTask(taskRunner TaskRunner, task *Task) error {
 		return taskRunner.CreateSchema(task.model.(*SchemaModel))
 	case CreateExternalSchema:
 		return taskRunner.CreateExternalSchema(task.model.(*ExternalSchemaModel))
+	case ReplaceExternalSchema:
+		return taskRunner.ReplaceExternalSchema(task.model.(*ExternalSchemaModel))
 	case GrantAccess:
 		return taskRunner.GrantAccess(task.model.(*GrantsModel))
 	case RevokeAccess:
 		return taskRunner.RevokeAccess(task.model.(*GrantsModel))
+	case GrantTableAccess:
+		return taskRunner.GrantTableAccess(task.model.(*TableGrantsModel))
+	case RevokeTableAccess:
+		return taskRunner.RevokeTableAccess(task.model.(*TableGrantsModel))
+	case UpgradeAccess:
+		return taskRunner.UpgradeAccess(task.model.(*GrantsModel))
+	case DowngradeAccess:
+		return taskRunner.DowngradeAccess(task.model.(*GrantsModel))
 	case AddToGroup:
 		return taskRunner.AddToGroup(task.model.(*MembershipModel))
 	case RemoveFromGroup:
 		return taskRunner.RemoveFromGroup(task.model.(*MembershipModel))
+	case CreateRole:
+		return taskRunner.CreateRole(task.model.(*RoleModel))
+	case DropRole:
+		return taskRunner.DropRole(task.model.(*RoleModel))
+	case GrantRole:
+		return taskRunner.GrantRole(task.model.(*RoleGrantModel))
+	case RevokeRole:
+		return taskRunner.RevokeRole(task.model.(*RoleGrantModel))
 	default:
 		return fmt.Errorf("unexpected task type: %s", task.taskType.String())
 	}
@@ -80,16 +107,36 @@ func (t *TaskPrinter) CreateExternalSchema(model *ExternalSchemaModel) error {
 	t.logger.Info("CreateExternalSchema", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "schemaName", model.Schema.Name)
 	return nil
 }
+func (t *TaskPrinter) ReplaceExternalSchema(model *ExternalSchemaModel) error {
+	t.logger.Info("ReplaceExternalSchema", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "schemaName", model.Schema.Name, "glueDatabaseName", model.Schema.GlueDatabaseName, "iamRoles", model.Schema.IamRoles, "catalogRegion", model.Schema.CatalogRegion)
+	return nil
+}
 func (t *TaskPrinter) CreateDatabase(model *DatabaseModel) error {
 	t.logger.Info("CreateDatabase", "clusterIdentifier", model.ClusterIdentifier, "databaseName", model.Database.Name)
 	return nil
 }
 func (t *TaskPrinter) GrantAccess(model *GrantsModel) error {
-	t.logger.Info("GrantAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "schemaName", model.SchemaName)
+	t.logger.Info("GrantAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "grantee", model.Grantee.String(), "schemaName", model.SchemaName, "privilege", model.Privilege.String())
 	return nil
 }
 func (t *TaskPrinter) RevokeAccess(model *GrantsModel) error {
-	t.logger.Info("RevokeAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "schemaName", model.SchemaName)
+	t.logger.Info("RevokeAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "grantee", model.Grantee.String(), "schemaName", model.SchemaName)
+	return nil
+}
+func (t *TaskPrinter) GrantTableAccess(model *TableGrantsModel) error {
+	t.logger.Info("GrantTableAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "grantee", model.Grantee.String(), "tableName", model.Table.Identifier(), "columns", model.Table.Columns)
+	return nil
+}
+func (t *TaskPrinter) RevokeTableAccess(model *TableGrantsModel) error {
+	t.logger.Info("RevokeTableAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "grantee", model.Grantee.String(), "tableName", model.Table.Identifier(), "columns", model.Table.Columns)
+	return nil
+}
+func (t *TaskPrinter) UpgradeAccess(model *GrantsModel) error {
+	t.logger.Info("UpgradeAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "grantee", model.Grantee.String(), "schemaName", model.SchemaName, "privilege", model.Privilege.String())
+	return nil
+}
+func (t *TaskPrinter) DowngradeAccess(model *GrantsModel) error {
+	t.logger.Info("DowngradeAccess", "clusterIdentifier", model.Database.ClusterIdentifier, "databaseName", model.Database.Name, "groupName", model.GroupName, "grantee", model.Grantee.String(), "schemaName", model.SchemaName, "privilege", model.Privilege.String())
 	return nil
 }
 func (t *TaskPrinter) AddToGroup(model *MembershipModel) error {
@@ -100,3 +147,19 @@ func (t *TaskPrinter) RemoveFromGroup(model *MembershipModel) error {
 	t.logger.Info("RemoveFromGroup", "clusterIdentifier", model.ClusterIdentifier, "username", model.Username, "groupName", model.GroupName)
 	return nil
 }
+func (t *TaskPrinter) CreateRole(model *RoleModel) error {
+	t.logger.Info("CreateRole", "clusterIdentifier", model.ClusterIdentifier, "roleName", model.Role.Name)
+	return nil
+}
+func (t *TaskPrinter) DropRole(model *RoleModel) error {
+	t.logger.Info("DropRole", "clusterIdentifier", model.ClusterIdentifier, "roleName", model.Role.Name)
+	return nil
+}
+func (t *TaskPrinter) GrantRole(model *RoleGrantModel) error {
+	t.logger.Info("GrantRole", "clusterIdentifier", model.ClusterIdentifier, "roleName", model.RoleName, "grantee", model.Grantee.String(), "granteeName", model.GranteeName)
+	return nil
+}
+func (t *TaskPrinter) RevokeRole(model *RoleGrantModel) error {
+	t.logger.Info("RevokeRole", "clusterIdentifier", model.ClusterIdentifier, "roleName", model.RoleName, "grantee", model.Grantee.String(), "granteeName", model.GranteeName)
+	return nil
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The backend (i.e. the system) an action is applied to
+type Backend string
+
+const (
+	Redshift Backend = "Redshift"
+	IAM      Backend = "IAM"
+	Google   Backend = "Google"
+)
+
+var Backends = []Backend{Redshift, IAM, Google}
+
+type ActionState string
+
+const (
+	Planned  ActionState = "Planned"  //the action would have been executed, but the model was applied in dry run mode
+	Executed ActionState = "Executed" //the action was executed successfully
+	Skipped  ActionState = "Skipped"  //the action was not executed because an action it depends on failed
+	Failed   ActionState = "Failed"   //the action was executed but failed
+)
+
+// A single change to one of the backends, e.g. a redshift task or an IAM role that has been created.
+type Action struct {
+	Backend   Backend
+	Type      string //the type of the action, e.g. CreateUser or RoleCreated
+	Name      string //the name of the object the action is applied to
+	State     ActionState
+	Err       error  //the underlying error, only set if the action failed
+	TaskState string //the final state of the task in the reconciliation DAG, only set for redshift actions
+}
+
+type actionDTO struct {
+	Backend Backend     `json:"backend"`
+	Type    string      `json:"type"`
+	Name    string      `json:"name"`
+	State   ActionState `json:"state"`
+	Error   string      `json:"error,omitempty"`
+}
+
+func (a *Action) MarshalJSON() ([]byte, error) {
+	dto := actionDTO{Backend: a.Backend, Type: a.Type, Name: a.Name, State: a.State}
+	if a.Err != nil {
+		dto.Error = a.Err.Error()
+	}
+	return json.Marshal(dto)
+}
+
+func (a *Action) String() string {
+	if a.Err != nil {
+		return fmt.Sprintf("%s %s(%s) %s: %v", a.Backend, a.Type, a.Name, a.State, a.Err)
+	}
+	return fmt.Sprintf("%s %s(%s) %s", a.Backend, a.Type, a.Name, a.State)
+}
+
+// The ApplyReport lists every action that was planned, executed, skipped or failed while applying a model.
+type ApplyReport struct {
+	Actions   []*Action                 `json:"actions"`
+	Durations map[Backend]time.Duration `json:"-"` //the time it took to apply the model to each backend
+}
+
+func New() *ApplyReport {
+	return &ApplyReport{Actions: []*Action{}, Durations: make(map[Backend]time.Duration)}
+}
+
+func (r *ApplyReport) RecordDuration(backend Backend, duration time.Duration) {
+	r.Durations[backend] += duration
+}
+
+func (r *ApplyReport) add(backend Backend, actionType string, name string, state ActionState, err error) *Action {
+	action := &Action{Backend: backend, Type: actionType, Name: name, State: state, Err: err}
+	r.Actions = append(r.Actions, action)
+	return action
+}
+
+func (r *ApplyReport) Planned(backend Backend, actionType string, name string) *Action {
+	return r.add(backend, actionType, name, Planned, nil)
+}
+
+func (r *ApplyReport) Executed(backend Backend, actionType string, name string) *Action {
+	return r.add(backend, actionType, name, Executed, nil)
+}
+
+func (r *ApplyReport) Skipped(backend Backend, actionType string, name string) *Action {
+	return r.add(backend, actionType, name, Skipped, nil)
+}
+
+func (r *ApplyReport) Failed(backend Backend, actionType string, name string, err error) *Action {
+	return r.add(backend, actionType, name, Failed, err)
+}
+
+// Appends the actions of the other report to this report
+func (r *ApplyReport) Merge(other *ApplyReport) {
+	if other == nil {
+		return
+	}
+	r.Actions = append(r.Actions, other.Actions...)
+	for backend, duration := range other.Durations {
+		r.RecordDuration(backend, duration)
+	}
+}
+
+func (r *ApplyReport) ForBackend(backend Backend) []*Action {
+	var result []*Action
+	for _, action := range r.Actions {
+		if action.Backend == backend {
+			result = append(result, action)
+		}
+	}
+	return result
+}
+
+func (r *ApplyReport) InState(state ActionState) []*Action {
+	var result []*Action
+	for _, action := range r.Actions {
+		if action.State == state {
+			result = append(result, action)
+		}
+	}
+	return result
+}
+
+func (r *ApplyReport) Count(backend Backend, state ActionState) int {
+	result := 0
+	for _, action := range r.Actions {
+		if action.Backend == backend && action.State == state {
+			result += 1
+		}
+	}
+	return result
+}
+
+func (r *ApplyReport) HasFailures() bool {
+	return len(r.InState(Failed)) > 0
+}
+
+// Returns an error that lists all the failed actions, or nil if no action failed
+func (r *ApplyReport) Error() error {
+	failed := r.InState(Failed)
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	var messages []string
+	for _, action := range failed {
+		messages = append(messages, action.String())
+	}
+	return fmt.Errorf("%d actions failed: %s", len(failed), strings.Join(messages, "; "))
+}
+
+func (r *ApplyReport) String() string {
+	var result string
+
+	for _, action := range r.Actions {
+		result += fmt.Sprintf("%s\n", action.String())
+	}
+
+	return result
+}
@@ -0,0 +1,48 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_ApplyReport(t *testing.T) {
+
+	assert := assert.New(t)
+
+	redshiftReport := New()
+	redshiftReport.Executed(Redshift, "CreateUser", "dev/jwr_bianalyst")
+	redshiftReport.Failed(Redshift, "CreateGroup", "dev/bianalyst", fmt.Errorf("permission denied"))
+	redshiftReport.Skipped(Redshift, "AddToGroup", "dev/jwr_bianalyst->bianalyst")
+
+	iamReport := New()
+	iamReport.Planned(IAM, "RoleCreated", "BiAnalyst")
+
+	applyReport := New()
+	applyReport.Merge(redshiftReport)
+	applyReport.Merge(iamReport)
+	applyReport.Merge(nil)
+
+	assert.Equal(4, len(applyReport.Actions))
+	assert.Equal(3, len(applyReport.ForBackend(Redshift)))
+	assert.Equal(1, applyReport.Count(IAM, Planned))
+	assert.Equal(0, applyReport.Count(Google, Executed))
+	assert.True(applyReport.HasFailures())
+	assert.EqualError(applyReport.Error(), "1 actions failed: Redshift CreateGroup(dev/bianalyst) Failed: permission denied")
+
+	serialized, err := json.Marshal(applyReport.InState(Failed))
+	assert.NoError(err)
+	assert.JSONEq(`[{"backend":"Redshift","type":"CreateGroup","name":"dev/bianalyst","state":"Failed","error":"permission denied"}]`, string(serialized))
+}
+
+func Test_ApplyReport_NoFailures(t *testing.T) {
+
+	assert := assert.New(t)
+
+	applyReport := New()
+	applyReport.Executed(Google, "UpdateRoles", "jwr@lunar.app")
+
+	assert.False(applyReport.HasFailures())
+	assert.NoError(applyReport.Error())
+}
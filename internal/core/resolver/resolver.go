@@ -6,9 +6,115 @@ import (
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/iam"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"strings"
 )
 
 type Resolver struct {
+	RoleBasedAccessControl bool //map the hubble roles to redshift roles instead of redshift groups
+}
+
+func sessionDuration(role *hubble.Role) int {
+	if role.SessionDuration == 0 {
+		return hubble.DefaultSessionDuration
+	}
+	return role.SessionDuration
+}
+
+func redshiftPrivilege(privilege hubble.Privilege) redshift.Privilege {
+	switch privilege {
+	case hubble.WritePrivilege:
+		return redshift.WritePrivilege
+	case hubble.OwnerPrivilege:
+		return redshift.OwnerPrivilege
+	default:
+		return redshift.ReadPrivilege
+	}
+}
+
+//Grants the access of the role on a regular, i.e. not a dev, database
+func grantAccess(databaseGroup *redshift.DatabaseGroup, role *hubble.Role) {
+	databaseGroup.GrantSchema(&redshift.Schema{Name: "public"})
+	for _, schema := range role.Acl {
+		databaseGroup.GrantSchema(&redshift.Schema{Name: string(schema), Privilege: redshiftPrivilege(role.PrivilegeOn(schema))}) //TODO: is it ok to assume that there is a schema with name = dataset?
+	}
+	for _, grant := range role.TableGrants {
+		//a table in a schema that is granted as a whole is already granted
+		if databaseGroup.LookupGrantedSchema(string(grant.DataSet)) == nil {
+			databaseGroup.GrantTable(&redshift.Table{Schema: string(grant.DataSet), Name: grant.Table, Columns: grant.Columns})
+		}
+	}
+	grantGlueDatabases(databaseGroup, role)
+}
+
+func grantGlueDatabases(databaseGroup *redshift.DatabaseGroup, role *hubble.Role) {
+	for _, glueDb := range role.GrantedGlueDatabases {
+		schema := redshift.ExternalSchema{
+			Name:             glueDb.ShortName,
+			GlueDatabaseName: glueDb.Name,
+			IamRoles:         glueDb.IamRoles,
+			CatalogRegion:    glueDb.CatalogRegion,
+		}
+		databaseGroup.GrantExternalSchema(&schema)
+	}
+}
+
+//Declares a redshift user for the user/role and returns the access of the role on the database.
+//The access is granted to a group the user is member of, or with role based access control to a role granted to the user.
+func (r *Resolver) declareUser(cluster *redshift.Cluster, database *redshift.Database, role *hubble.Role, username string, declaredRoles map[string]*hubble.Role) *redshift.DatabaseGroup {
+	database.DeclareUser(username)
+
+	if r.RoleBasedAccessControl {
+		cluster.DeclareUserWithRole(username, declareRole(cluster, role, declaredRoles))
+		return database.DeclareRole(role.Name)
+	}
+
+	cluster.DeclareUser(username, cluster.DeclareGroup(role.Name))
+	return database.DeclareGroup(role.Name)
+}
+
+//Declares the redshift role for the role, which is granted the roles of the roles the role extends
+func declareRole(cluster *redshift.Cluster, role *hubble.Role, declaredRoles map[string]*hubble.Role) *redshift.Role {
+	redshiftRole := cluster.DeclareRole(role.Name)
+	declaredRoles[redshiftRole.Name] = role
+	for _, base := range role.Bases {
+		redshiftRole.Grant(declareRole(cluster, base, declaredRoles))
+	}
+	return redshiftRole
+}
+
+func grantsOn(role *hubble.Role, database *redshift.Database) bool {
+	for _, db := range role.GrantedDatabases {
+		if db.ClusterIdentifier == database.ClusterIdentifier && strings.EqualFold(db.Name, database.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+//Grants the nested roles their access on the databases and removes the access a role inherits from its nested roles from the role itself
+func nestRoles(redshiftModel *redshift.Model, declaredRoles map[string]*hubble.Role) {
+	for _, cluster := range redshiftModel.Clusters {
+		for _, redshiftRole := range cluster.Roles {
+			role := declaredRoles[redshiftRole.Name]
+			for _, db := range role.GrantedDatabases {
+				if db.ClusterIdentifier == cluster.Identifier {
+					grantAccess(cluster.DeclareDatabase(db.Name).DeclareRole(role.Name), role)
+				}
+			}
+		}
+
+		for _, database := range cluster.Databases {
+			for _, databaseRole := range database.Roles {
+				for _, base := range declaredRoles[databaseRole.Name].Bases {
+					if grantsOn(base, database) {
+						inherited := &redshift.DatabaseGroup{Name: base.Name, Grantee: redshift.RoleGrantee}
+						grantAccess(inherited, base)
+						databaseRole.RevokeInherited(inherited)
+					}
+				}
+			}
+		}
+	}
 }
 
 //transforms the given hubble model into separate models for the 3 systems we want to reconcile
@@ -17,6 +123,7 @@ func (r *Resolver) Resolve(model hubble.Model) (redshift.Model, iam.Model, googl
 	redshiftModel := redshift.Model{}
 	iamModel := iam.Model{}
 	googleModel := google.Model{}
+	declaredRoles := make(map[string]*hubble.Role)
 
 	for _, db := range model.Databases {
 		cluster := redshiftModel.DeclareCluster(db.ClusterIdentifier)
@@ -24,18 +131,26 @@ func (r *Resolver) Resolve(model hubble.Model) (redshift.Model, iam.Model, googl
 	}
 
 	for _, role := range model.Roles {
-		iamModel.DeclareRole(role.Name)
+		iamRole := iamModel.DeclareRole(role.Name)
+		iamRole.MaxSessionDuration = int64(sessionDuration(role))
+		iamRole.TrustedPrincipals = role.TrustedPrincipals
 	}
 
 	for _, user := range model.Users {
 
 		googleLogin := googleModel.DeclareUser(user.Email)
 
-		for _, role := range user.AssignedTo {
+		//a role may be assigned to a user both directly and through teams, it is only resolved once per user
+		for _, role := range model.RolesOf(user) {
 
 			//Allow the user to log in with the role
 			googleLogin.Assign(role.Name)
 
+			//the session duration in google applies to all the roles of the user, so it may not exceed the max session duration of any of them
+			if googleLogin.SessionDuration == 0 || sessionDuration(role) < googleLogin.SessionDuration {
+				googleLogin.SessionDuration = sessionDuration(role)
+			}
+
 			//Declare an AWS role for the given role
 			iamRole := iamModel.DeclareRole(role.Name)
 
@@ -51,25 +166,9 @@ func (r *Resolver) Resolve(model hubble.Model) (redshift.Model, iam.Model, googl
 
 				database := cluster.DeclareDatabase(db.Name)
 
-				//Set needed grants on the user group
-				group := cluster.DeclareGroup(role.Name)
-				databaseGroup := database.DeclareGroup(role.Name)
-				databaseGroup.GrantSchema(&redshift.Schema{Name: "public"})
-				for _, schema := range role.Acl {
-					databaseGroup.GrantSchema(&redshift.Schema{Name: string(schema)}) //TODO: is it ok to assume that there is a schema with name = dataset?
-				}
-
-				//Declare a redshift user for the user/role and add it to the group
-				cluster.DeclareUser(userAndRoleUsername, group)
-				database.DeclareUser(userAndRoleUsername)
-
-				for _, glueDb := range role.GrantedGlueDatabases {
-					schema := redshift.ExternalSchema{
-						Name:             glueDb.ShortName,
-						GlueDatabaseName: glueDb.Name,
-					}
-					databaseGroup.GrantExternalSchema(&schema)
-				}
+				//Declare a redshift user for the user/role and set needed grants on its group or role
+				databaseGroup := r.declareUser(cluster, database, role, userAndRoleUsername, declaredRoles)
+				grantAccess(databaseGroup, role)
 			}
 
 			for _, db := range role.GrantedDevDatabases {
@@ -80,27 +179,24 @@ func (r *Resolver) Resolve(model hubble.Model) (redshift.Model, iam.Model, googl
 				cluster := redshiftModel.DeclareCluster(db.ClusterIdentifier)
 				database := cluster.DeclareDatabaseWithOwner(user.Username, userAndRoleUsername)
 
-				group := cluster.DeclareGroup(role.Name)
-				databaseGroup := database.DeclareGroup(role.Name)
+				//Declare a redshift user for the user/role and set needed grants on its group or role
+				databaseGroup := r.declareUser(cluster, database, role, userAndRoleUsername, declaredRoles)
 				databaseGroup.GrantSchema(&redshift.Schema{Name: "public"})
-
-				//Declare a redshift user for the user/role and add it to the group
-				cluster.DeclareUser(userAndRoleUsername, group)
-				database.DeclareUser(userAndRoleUsername)
-
-				for _, glueDb := range role.GrantedGlueDatabases {
-					schema := redshift.ExternalSchema{
-						Name:             glueDb.ShortName,
-						GlueDatabaseName: glueDb.Name,
-					}
-					databaseGroup.GrantExternalSchema(&schema)
-				}
+				grantGlueDatabases(databaseGroup, role)
 			}
 
 			for _, policy := range role.Policies {
 				iamRole.DeclareReferencedPolicy(policy.Arn)
 			}
 		}
+
+		if googleLogin.SessionDuration == 0 {
+			googleLogin.SessionDuration = hubble.DefaultSessionDuration
+		}
+	}
+
+	if r.RoleBasedAccessControl {
+		nestRoles(&redshiftModel, declaredRoles)
 	}
 
 	return redshiftModel, iamModel, googleModel
@@ -3,6 +3,7 @@ package resolver
 import (
 	"fmt"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -171,3 +172,213 @@ func Test_BiAnalyst(t *testing.T) {
 	access := policy.LookupDatabase(data.unstable.ClusterIdentifier, data.unstable.Name)
 	assert.NotNil(access, "access has been granted for the user to the unstable/prod database")
 }
+
+func Test_Teams(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := generateTestData()
+
+	team := hubble.Team{
+		Name:    "analysts",
+		Members: []*hubble.User{&data.biAnalyst, &data.dbtDeveloper},
+		Roles:   []*hubble.Role{&data.biAnalystRole},
+	}
+
+	model := hubble.Model{
+		Databases:    []*hubble.Database{&data.unstable},
+		DevDatabases: []*hubble.DevDatabase{&data.dev},
+		Users:        []*hubble.User{&data.biAnalyst, &data.dbtDeveloper},
+		Roles:        []*hubble.Role{&data.biAnalystRole, &data.dbtDeveloperRole},
+		Policies:     []*hubble.PolicyReference{&data.allowAccessToTmpBucketPolicy},
+		Teams:        []*hubble.Team{&team},
+	}
+
+	resolver := Resolver{}
+	redshiftModel, iamModel, googleModel := resolver.Resolve(model)
+
+	user := googleModel.LookupUser(data.dbtDeveloper.Email)
+	assert.ElementsMatch([]string{data.dbtDeveloperRole.Name, data.biAnalystRole.Name}, user.AssignedTo(), "the member has been assigned the role of the team")
+
+	user = googleModel.LookupUser(data.biAnalyst.Email)
+	assert.Equal([]string{data.biAnalystRole.Name}, user.AssignedTo(), "the role assigned both directly and through the team is assigned once")
+
+	role := iamModel.LookupRole(data.biAnalystRole.Name)
+	assert.NotNil(role.LookupDatabaseLoginPolicyForUser(data.dbtDeveloper.Email), "policy has been registered for the team member")
+	assert.Len(role.DatabaseLoginPolicies, 2, "a single policy has been registered per user")
+
+	cluster := redshiftModel.LookupCluster(data.unstable.ClusterIdentifier)
+	database := cluster.LookupDatabase(data.unstable.Name)
+	assert.NotNil(database.LookupUser(fmt.Sprintf("%s_%s", data.dbtDeveloper.Username, data.biAnalystRole.Name)), "a redshift user has been registered for the team member")
+}
+
+func Test_SessionDuration(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := hubble.Model{}
+	unstable := model.AddDatabase("hubble-unstable", "prod")
+
+	biAnalystRole := model.AddRole("bi_analyst", []hubble.DataSet{"bi"})
+	biAnalystRole.GrantAccess(unstable)
+	biAnalystRole.SessionDuration = 8 * 60 * 60
+
+	dbtDeveloperRole := model.AddRole("dbt_developer", []hubble.DataSet{"bi"})
+	dbtDeveloperRole.GrantAccess(unstable)
+	dbtDeveloperRole.SessionDuration = 2 * 60 * 60
+
+	defaultRole := model.AddRole("default", []hubble.DataSet{"bi"})
+
+	biAnalyst := model.AddUser("jwr", "jwr@lunar.app")
+	biAnalyst.Assign(biAnalystRole)
+	biAnalyst.Assign(dbtDeveloperRole)
+
+	other := model.AddUser("nra", "nra@lunar.app")
+	other.Assign(defaultRole)
+
+	resolver := Resolver{}
+	_, iamModel, googleModel := resolver.Resolve(model)
+
+	assert.Equal(int64(8*60*60), iamModel.LookupRole(biAnalystRole.Name).MaxSessionDuration)
+	assert.Equal(int64(2*60*60), iamModel.LookupRole(dbtDeveloperRole.Name).MaxSessionDuration)
+	assert.Equal(int64(hubble.DefaultSessionDuration), iamModel.LookupRole(defaultRole.Name).MaxSessionDuration)
+
+	assert.Equal(2*60*60, googleModel.LookupUser(biAnalyst.Email).SessionDuration, "the shortest session duration of the roles of the user is used")
+	assert.Equal(hubble.DefaultSessionDuration, googleModel.LookupUser(other.Email).SessionDuration)
+}
+
+func Test_TableGrants(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := hubble.Model{}
+	unstable := model.AddDatabase("hubble-unstable", "prod")
+
+	creditAnalystRole := model.AddRole("credit_analyst", []hubble.DataSet{"bi"})
+	creditAnalystRole.GrantAccess(unstable)
+	creditAnalystRole.GrantTable("credit", "loans", "id", "amount")
+	creditAnalystRole.GrantTable("credit", "customers")
+	creditAnalystRole.GrantTable("bi", "accounts")
+
+	creditAnalyst := model.AddUser("jwr", "jwr@lunar.app")
+	creditAnalyst.Assign(creditAnalystRole)
+
+	resolver := Resolver{}
+	redshiftModel, _, _ := resolver.Resolve(model)
+
+	database := redshiftModel.LookupCluster(unstable.ClusterIdentifier).LookupDatabase(unstable.Name)
+	group := database.LookupGroup(creditAnalystRole.Name)
+
+	assert.Nil(group.LookupGrantedSchema("credit"), "the schema of the granted tables is not granted as a whole")
+	assert.Equal([]string{"id", "amount"}, group.LookupGrantedTable("credit", "loans").Columns)
+	assert.NotNil(group.LookupGrantedTable("credit", "customers"))
+	assert.Nil(group.LookupGrantedTable("bi", "accounts"), "a table in a schema that is granted as a whole is not granted separately")
+	assert.Len(group.GrantedTables, 2)
+}
+
+func Test_ExternalSchemas(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := generateTestData()
+
+	model := hubble.Model{}
+	unstable := model.AddDatabase("hubble-unstable", "prod")
+
+	amlRole := model.AddRole("aml", []hubble.DataSet{})
+	amlRole.GrantAccess(unstable)
+	amlRole.GrantedGlueDatabases = append(amlRole.GrantedGlueDatabases, &data.lwgoeventsDatabase, &hubble.GlueDatabase{
+		ShortName:     "intercom",
+		Name:          "intercom",
+		IamRoles:      []string{"arn:aws:iam::478824949770:role/redshift", "arn:aws:iam::899945594626:role/intercom-datalake"},
+		CatalogRegion: "eu-west-1",
+	})
+
+	aml := model.AddUser("jwr", "jwr@lunar.app")
+	aml.Assign(amlRole)
+
+	resolver := Resolver{}
+	redshiftModel, _, _ := resolver.Resolve(model)
+
+	group := redshiftModel.LookupCluster(unstable.ClusterIdentifier).LookupDatabase(unstable.Name).LookupGroup(amlRole.Name)
+
+	lwgoevents := group.LookupGrantedExternalSchema("lwgoevents")
+	assert.Equal("lw-go-events", lwgoevents.GlueDatabaseName)
+	assert.Empty(lwgoevents.IamRoles, "the default datalake role is used")
+
+	intercom := group.LookupGrantedExternalSchema("intercom")
+	assert.Equal([]string{"arn:aws:iam::478824949770:role/redshift", "arn:aws:iam::899945594626:role/intercom-datalake"}, intercom.IamRoles)
+	assert.Equal("eu-west-1", intercom.CatalogRegion)
+}
+
+func Test_Privileges(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := hubble.Model{}
+	unstable := model.AddDatabase("hubble-unstable", "prod")
+
+	dataEngineerRole := model.AddRole("data_engineer", []hubble.DataSet{"bi", "staging", "core"})
+	dataEngineerRole.GrantAccess(unstable)
+	dataEngineerRole.GrantPrivilege("staging", hubble.WritePrivilege)
+	dataEngineerRole.GrantPrivilege("core", hubble.OwnerPrivilege)
+
+	dataEngineer := model.AddUser("jwr", "jwr@lunar.app")
+	dataEngineer.Assign(dataEngineerRole)
+
+	resolver := Resolver{}
+	redshiftModel, _, _ := resolver.Resolve(model)
+
+	group := redshiftModel.LookupCluster(unstable.ClusterIdentifier).LookupDatabase(unstable.Name).LookupGroup(dataEngineerRole.Name)
+
+	assert.Equal(redshift.ReadPrivilege, group.LookupGrantedSchema("bi").Privilege)
+	assert.Equal(redshift.WritePrivilege, group.LookupGrantedSchema("staging").Privilege)
+	assert.Equal(redshift.OwnerPrivilege, group.LookupGrantedSchema("core").Privilege)
+	assert.Equal(redshift.ReadPrivilege, group.LookupGrantedSchema("public").Privilege)
+}
+
+func Test_RoleBasedAccessControl(t *testing.T) {
+
+	assert := assert.New(t)
+
+	model := hubble.Model{}
+	unstable := model.AddDatabase("hubble-unstable", "prod")
+
+	analystRole := model.AddRole("analyst", []hubble.DataSet{"bi"})
+	analystRole.GrantAccess(unstable)
+	analystRole.GrantTable("credit", "loans", "id")
+
+	creditAnalystRole := model.AddRole("credit_analyst", []hubble.DataSet{"credit"})
+	creditAnalystRole.GrantPrivilege("bi", hubble.WritePrivilege)
+	creditAnalystRole.Extend(analystRole)
+
+	creditAnalyst := model.AddUser("jwr", "jwr@lunar.app")
+	creditAnalyst.Assign(creditAnalystRole)
+
+	assert.NoError(model.Flatten())
+
+	resolver := Resolver{RoleBasedAccessControl: true}
+	redshiftModel, _, _ := resolver.Resolve(model)
+
+	cluster := redshiftModel.LookupCluster(unstable.ClusterIdentifier)
+	assert.Empty(cluster.Groups)
+	assert.Len(cluster.Roles, 2, "the extended role is declared as well")
+	assert.True(cluster.LookupRole("credit_analyst").IsGranted("analyst"), "the extended role is nested in the role")
+
+	user := cluster.LookupUser("jwr_credit_analyst")
+	assert.Empty(user.MemberOf)
+	assert.True(user.IsGranted("credit_analyst"))
+
+	database := cluster.LookupDatabase(unstable.Name)
+	assert.Empty(database.Groups)
+
+	analyst := database.LookupRole("analyst")
+	assert.Equal([]string{"public", "bi"}, analyst.Granted())
+	assert.NotNil(analyst.LookupGrantedTable("credit", "loans"))
+
+	creditAnalystGrants := database.LookupRole("credit_analyst")
+	assert.Equal(redshift.RoleGrantee, creditAnalystGrants.Grantee)
+	assert.Equal([]string{"credit", "bi"}, creditAnalystGrants.Granted(), "the access inherited from the nested role is only granted to the nested role")
+	assert.Equal(redshift.WritePrivilege, creditAnalystGrants.LookupGrantedSchema("bi").Privilege)
+	assert.Empty(creditAnalystGrants.GrantedTables, "the table is in a schema that is granted as a whole")
+}
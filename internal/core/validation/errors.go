@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A single validation error. The path points to the invalid field, e.g. spec.users[3].roles[1]
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Errors collects all the validation errors found, so they can be reported at once instead of one at a time.
+type Errors []*FieldError
+
+func (e *Errors) Add(path string, format string, args ...interface{}) {
+	*e = append(*e, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Appends the errors of the other error list, prefixing their paths with the given path
+func (e *Errors) AddAll(path string, other Errors) {
+	for _, err := range other {
+		*e = append(*e, &FieldError{Path: Join(path, err.Path), Message: err.Message})
+	}
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Returns nil if there are no errors. This avoids returning a nil Errors value wrapped in a non-nil error interface.
+func (e Errors) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func Index(path string, index int) string {
+	return fmt.Sprintf("%s[%d]", path, index)
+}
+
+func Key(path string, key string) string {
+	return fmt.Sprintf("%s[%s]", path, key)
+}
+
+func Join(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	if field == "" {
+		return path
+	}
+	return path + "." + field
+}
@@ -0,0 +1,22 @@
+package validation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Errors(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var errors Errors
+	assert.NoError(errors.ErrorOrNil())
+
+	errors.Add(Index("spec.users", 3)+".roles[1]", "no such role: %s", "BiAnalyst")
+
+	var redshiftErrors Errors
+	redshiftErrors.Add(Key("clusters", "dev")+".users[jwr]", "user has been excluded")
+	errors.AddAll("redshift", redshiftErrors)
+
+	assert.EqualError(errors.ErrorOrNil(), "2 validation errors: spec.users[3].roles[1]: no such role: BiAnalyst; redshift.clusters[dev].users[jwr]: user has been excluded")
+}
@@ -3,8 +3,11 @@ package google
 import (
 	"fmt"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
 )
 
+const UpdateRoles = google.UpdateRoles
+
 type Applier struct {
 	client *Client
 }
@@ -22,27 +25,88 @@ func (applier *Applier) userByEmail(users []User, email string) *User {
 	return nil
 }
 
-func (applier *Applier) Apply(model google.Model) error {
+// Returns a report of the users whose roles would be updated by applying the model, without changing anything in Google.
+func (applier *Applier) Plan(model google.Model) (*report.ApplyReport, error) {
+
+	applyReport := report.New()
 
 	googleUsers, err := applier.client.Users()
 
 	if err != nil {
-		return fmt.Errorf("Unable to retrieve users: %w", err)
+		return applyReport, fmt.Errorf("Unable to retrieve users: %w", err)
+	}
+
+	for _, user := range model.Users {
+		googleUser := applier.userByEmail(googleUsers, user.Email)
+
+		if googleUser == nil {
+			err := fmt.Errorf("user %s doesn't exist", user.Email)
+			applyReport.Failed(report.Google, UpdateRoles, user.Email, err)
+			return applyReport, err
+		}
+
+		differ, err := applier.client.RolesDiffer(googleUser.Id, user.AssignedTo(), user.SessionDuration)
+
+		if err != nil {
+			err = fmt.Errorf("Unable to retrieve roles: %w", err)
+			applyReport.Failed(report.Google, UpdateRoles, user.Email, err)
+			return applyReport, err
+		}
+
+		if differ {
+			applyReport.Planned(report.Google, UpdateRoles, user.Email)
+		}
+	}
+
+	return applyReport, nil
+}
+
+// Applies the model to Google. In dry run mode nothing is changed and the changes are reported as planned instead.
+func (applier *Applier) Apply(model google.Model, dryRun bool) (*report.ApplyReport, error) {
+
+	if dryRun {
+		return applier.Plan(model)
+	}
+
+	applyReport := report.New()
+
+	googleUsers, err := applier.client.Users()
+
+	if err != nil {
+		return applyReport, fmt.Errorf("Unable to retrieve users: %w", err)
 	}
 
 	for _, user := range model.Users {
 		googleUser := applier.userByEmail(googleUsers, user.Email)
 
 		if googleUser != nil {
-			err := applier.client.UpdateRoles(googleUser.Id, user.AssignedTo())
+			differ, err := applier.client.RolesDiffer(googleUser.Id, user.AssignedTo(), user.SessionDuration)
 
 			if err != nil {
-				return fmt.Errorf("Unable to update roles: %w", err)
+				err = fmt.Errorf("Unable to retrieve roles: %w", err)
+				applyReport.Failed(report.Google, UpdateRoles, user.Email, err)
+				return applyReport, err
 			}
+
+			//users whose roles are unchanged are not updated, so only actual changes are reported and turned into events
+			if !differ {
+				continue
+			}
+
+			err = applier.client.UpdateRoles(googleUser.Id, user.AssignedTo(), user.SessionDuration)
+
+			if err != nil {
+				err = fmt.Errorf("Unable to update roles: %w", err)
+				applyReport.Failed(report.Google, UpdateRoles, user.Email, err)
+				return applyReport, err
+			}
+			applyReport.Executed(report.Google, UpdateRoles, user.Email)
 		} else {
-			return fmt.Errorf("user %s doesn't exist", user.Email)
+			err := fmt.Errorf("user %s doesn't exist", user.Email)
+			applyReport.Failed(report.Google, UpdateRoles, user.Email, err)
+			return applyReport, err
 		}
 	}
 
-	return nil
+	return applyReport, nil
 }
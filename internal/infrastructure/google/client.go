@@ -56,10 +56,10 @@ func (r AwsRoleCustomSchemaDTO) isManaged(accountId string) bool {
 //    user_email: The email of the user. Needs permissions to access the Admin APIs.
 // Returns:
 //    Admin SDK directory service object.
-func createDirectoryService(jsonKey []byte, userEmail string) (*admin.Service, error) {
+func createDirectoryService(jsonKey []byte, userEmail string, scope string) (*admin.Service, error) {
 	ctx := context.Background()
 
-	config, err := google.JWTConfigFromJSON(jsonKey, admin.AdminDirectoryUserScope)
+	config, err := google.JWTConfigFromJSON(jsonKey, scope)
 	if err != nil {
 		return nil, fmt.Errorf("JWTConfigFromJSON: %v", err)
 	}
@@ -76,12 +76,20 @@ func createDirectoryService(jsonKey []byte, userEmail string) (*admin.Service, e
 
 type Client struct {
 	service      *admin.Service
+	groupService *admin.Service //the group members are read with a separate scope, so the controller only needs it granted when roles reference google groups
 	awsAccountId string
+	samlProvider string //the name of the SAML identity provider in IAM the users log in with
 }
 
-func NewGoogleClient(jsonKey []byte, principalEmail string, awsAccountId string) (*Client, error) {
+func NewGoogleClient(jsonKey []byte, principalEmail string, awsAccountId string, samlProvider string) (*Client, error) {
 
-	service, err := createDirectoryService(jsonKey, principalEmail)
+	service, err := createDirectoryService(jsonKey, principalEmail, admin.AdminDirectoryUserScope)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groupService, err := createDirectoryService(jsonKey, principalEmail, admin.AdminDirectoryGroupMemberReadonlyScope)
 
 	if err != nil {
 		return nil, err
@@ -89,7 +97,9 @@ func NewGoogleClient(jsonKey []byte, principalEmail string, awsAccountId string)
 
 	return &Client{
 		service:      service,
+		groupService: groupService,
 		awsAccountId: awsAccountId,
+		samlProvider: samlProvider,
 	}, nil
 }
 
@@ -139,20 +149,25 @@ func (client *Client) update(userKey string, awsRoles AwsRolesCustomSchemaDTO) e
 	return nil
 }
 
-func (client *Client) createDTO(roles []string) AwsRolesCustomSchemaDTO {
+// the value of the SAML attribute that allows the user to assume the given role
+func (client *Client) roleValue(role string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:role/hubble-rbac/%s,arn:aws:iam::%s:saml-provider/%s", client.awsAccountId, role, client.awsAccountId, client.samlProvider)
+}
+
+func (client *Client) createDTO(roles []string, sessionDuration int) AwsRolesCustomSchemaDTO {
 
 	var awsRoles []AwsRoleCustomSchemaDTO
 
 	for _, role := range roles {
 		awsRole := AwsRoleCustomSchemaDTO{
 			Type:  "work",
-			Value: fmt.Sprintf("arn:aws:iam::%s:role/hubble-rbac/%s,arn:aws:iam::%s:saml-provider/GoogleApps", client.awsAccountId, role, client.awsAccountId),
+			Value: client.roleValue(role),
 		}
 		awsRoles = append(awsRoles, awsRole)
 	}
 	return AwsRolesCustomSchemaDTO{
 		Roles:           awsRoles,
-		SessionDuration: 14400,
+		SessionDuration: sessionDuration,
 	}
 }
 
@@ -186,7 +201,32 @@ func (client *Client) Users() ([]User, error) {
 	return result, nil
 }
 
-func (client *Client) UpdateRoles(userId string, roles []string) error {
+// Returns the emails of the users in the group, including the users of nested groups
+func (client *Client) GroupMembers(groupEmail string) ([]string, error) {
+
+	var result []string
+
+	err := client.groupService.Members.
+		List(groupEmail).
+		IncludeDerivedMembership(true).
+		Pages(context.Background(), func(page *admin.Members) error {
+			for _, member := range page.Members {
+				if member.Type == "USER" {
+					result = append(result, member.Email)
+				}
+			}
+			return nil
+		})
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the members of %s: %w", groupEmail, err)
+	}
+
+	return result, nil
+}
+
+// Sets the roles managed by the controller and the session duration of the user, the roles not managed by the controller are kept
+func (client *Client) UpdateRoles(userId string, roles []string, sessionDuration int) error {
 	currentRoles, err := client.get(userId)
 
 	if err != nil {
@@ -195,7 +235,7 @@ func (client *Client) UpdateRoles(userId string, roles []string) error {
 
 	currentRoles = currentRoles.Distinct()
 
-	desiredRoles := client.createDTO(roles)
+	desiredRoles := client.createDTO(roles, sessionDuration)
 
 	for _, r := range currentRoles.Roles {
 		if !r.isManaged(client.awsAccountId) {
@@ -206,6 +246,41 @@ func (client *Client) UpdateRoles(userId string, roles []string) error {
 	return client.update(userId, desiredRoles)
 }
 
+// Returns true if the roles managed by the controller or the session duration differ from the given ones, i.e. if UpdateRoles would change the user.
+func (client *Client) RolesDiffer(userId string, roles []string, sessionDuration int) (bool, error) {
+	currentRoles, err := client.get(userId)
+
+	if err != nil {
+		return false, err
+	}
+
+	if currentRoles.SessionDuration != sessionDuration {
+		return true, nil
+	}
+
+	current := make(map[string]bool)
+	for _, r := range currentRoles.Distinct().Roles {
+		if r.isManaged(client.awsAccountId) {
+			current[r.Value] = true
+		}
+	}
+
+	desired := make(map[string]bool)
+	for _, role := range roles {
+		desired[client.roleValue(role)] = true
+	}
+
+	if len(current) != len(desired) {
+		return true, nil
+	}
+	for value := range desired {
+		if !current[value] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (client *Client) Roles(userId string) ([]string, error) {
 	googleUser, err := client.service.Users.Get(userId).Projection("full").Do()
 
@@ -1,6 +1,9 @@
 package google
 
-import "github.com/lunarway/hubble-rbac-controller/internal/core/google"
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+)
 
 type NoOpApplier struct {
 }
@@ -9,6 +12,6 @@ func NewNoOpApplier() *NoOpApplier {
 	return &NoOpApplier{}
 }
 
-func (applier *NoOpApplier) Apply(model google.Model) error {
-	return nil
+func (applier *NoOpApplier) Apply(model google.Model, dryRun bool) (*report.ApplyReport, error) {
+	return report.New(), nil
 }
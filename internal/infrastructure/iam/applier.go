@@ -5,39 +5,26 @@ import (
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/go-logr/logr"
 	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
 	"strings"
 )
 
-type ApplyEventType int
+// The event types are declared with the core IAM model, so the changes planned without live state are named like the events of the applier
+type ApplyEventType = iamCore.ApplyEventType
 
 const (
-	RoleUpdated ApplyEventType = iota
-	RoleCreated
-	RoleDeleted
-	PolicyUpdated
-	PolicyCreated
-	PolicyDeleted
+	RoleUpdated               = iamCore.RoleUpdated
+	RoleCreated               = iamCore.RoleCreated
+	RoleDeleted               = iamCore.RoleDeleted
+	PolicyUpdated             = iamCore.PolicyUpdated
+	PolicyCreated             = iamCore.PolicyCreated
+	PolicyDeleted             = iamCore.PolicyDeleted
+	PolicyAttached            = iamCore.PolicyAttached
+	PolicyDetached            = iamCore.PolicyDetached
+	MaxSessionDurationUpdated = iamCore.MaxSessionDurationUpdated
+	TrustPolicyUpdated        = iamCore.TrustPolicyUpdated
 )
 
-func (t ApplyEventType) ToString() string {
-	switch t {
-	case RoleUpdated:
-		return "RoleUpdated"
-	case RoleCreated:
-		return "RoleCreated"
-	case RoleDeleted:
-		return "RoleDeleted"
-	case PolicyUpdated:
-		return "PolicyUpdated"
-	case PolicyCreated:
-		return "PolicyCreated"
-	case PolicyDeleted:
-		return "PolicyDeleted"
-	default:
-		return fmt.Sprintf("%d", int(t))
-	}
-}
-
 type ApplyEventLister interface {
 	Handle(eventType ApplyEventType, name string)
 }
@@ -45,21 +32,32 @@ type ApplyEventLister interface {
 type Applier struct {
 	accountId     string
 	region        string
+	saml          SamlConfig
 	client        *Client
 	eventListener ApplyEventLister
 	logger        logr.Logger
 }
 
-func NewApplier(client *Client, accountId string, region string, eventListener ApplyEventLister, logger logr.Logger) *Applier {
+func NewApplier(client *Client, accountId string, region string, saml SamlConfig, eventListener ApplyEventLister, logger logr.Logger) *Applier {
 	return &Applier{
 		accountId:     accountId,
 		region:        region,
+		saml:          saml,
 		client:        client,
 		eventListener: eventListener,
 		logger:        logger,
 	}
 }
 
+func (applier *Applier) handle(applyReport *report.ApplyReport, eventType ApplyEventType, name string) {
+	applier.eventListener.Handle(eventType, name)
+	applyReport.Executed(report.IAM, eventType.ToString(), name)
+}
+
+func (applier *Applier) plan(applyReport *report.ApplyReport, eventType ApplyEventType, name string) {
+	applyReport.Planned(report.IAM, eventType.ToString(), name)
+}
+
 //TODO: replace all this Sprintf'ing with go templating!
 func (applier *Applier) buildDatabaseLoginPolicyDocument(policy *iamCore.DatabaseLoginPolicy) string {
 
@@ -157,11 +155,46 @@ func (applier *Applier) createAndAttachPolicy(role *iam.Role, name string, docum
 	return nil
 }
 
-func (applier *Applier) createRole(name string) (*iam.Role, error) {
-	return applier.client.CreateOrUpdateLoginRole(name, applier.accountId)
+func (applier *Applier) createRole(desiredRole *iamCore.AwsRole) (*iam.Role, error) {
+
+	trustPolicyDocument, err := buildTrustPolicyDocument(applier.accountId, applier.saml, desiredRole.TrustedPrincipals)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return applier.client.CreateOrUpdateLoginRole(desiredRole.Name, trustPolicyDocument, desiredRole.MaxSessionDuration)
+}
+
+func (applier *Applier) maxSessionDurationDiffers(desiredRole *iamCore.AwsRole, currentRole *iam.Role) bool {
+	return currentRole.MaxSessionDuration == nil || *currentRole.MaxSessionDuration != desiredRole.MaxSessionDuration
 }
 
-func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *iam.Role, policyDocuments map[string]string) error {
+func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *iam.Role, policyDocuments map[string]string, applyReport *report.ApplyReport) error {
+
+	if applier.maxSessionDurationDiffers(desiredRole, currentRole) {
+		err := applier.client.UpdateMaxSessionDuration(currentRole, desiredRole.MaxSessionDuration)
+
+		if err != nil {
+			return err
+		}
+		applier.handle(applyReport, MaxSessionDurationUpdated, desiredRole.Name)
+	}
+
+	trustPolicyDocument, err := buildTrustPolicyDocument(applier.accountId, applier.saml, desiredRole.TrustedPrincipals)
+
+	if err != nil {
+		return err
+	}
+
+	if !sameTrustPolicy(currentRole.AssumeRolePolicyDocument, trustPolicyDocument) {
+		err := applier.client.UpdateTrustPolicy(currentRole, trustPolicyDocument)
+
+		if err != nil {
+			return err
+		}
+		applier.handle(applyReport, TrustPolicyUpdated, desiredRole.Name)
+	}
 
 	attachedPolicies, err := applier.client.ListManagedAttachedPolicies(currentRole)
 
@@ -169,8 +202,15 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 		return fmt.Errorf("unable to list attached policies: %w", err)
 	}
 
+	//the referenced policies are not managed by the controller, so they are only listed among the unmanaged policies
+	referencedPolicies, err := applier.client.ListUnmanagedAttachedPolicies(currentRole)
+
+	if err != nil {
+		return fmt.Errorf("unable to list attached policies: %w", err)
+	}
+
 	for _, desiredPolicy := range desiredRole.Policies {
-		attachedPolicy := applier.client.lookupAttachedPolicyByArn(attachedPolicies, desiredPolicy.Arn)
+		attachedPolicy := applier.client.lookupAttachedPolicyByArn(referencedPolicies, desiredPolicy.Arn)
 		if attachedPolicy == nil {
 			policy, err := applier.client.lookupPolicyByArn(desiredPolicy.Arn)
 
@@ -187,6 +227,7 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 			if err != nil {
 				return fmt.Errorf("failed attaching policy %s: %w", desiredPolicy.Arn, err)
 			}
+			applier.handle(applyReport, PolicyAttached, *policy.PolicyName)
 		}
 	}
 
@@ -204,7 +245,7 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 				if err != nil {
 					return fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err)
 				}
-				applier.eventListener.Handle(PolicyDeleted, policyName)
+				applier.handle(applyReport, PolicyDeleted, policyName)
 			}
 		} else {
 			if attachedPolicy != nil {
@@ -222,7 +263,7 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 					if err != nil {
 						return fmt.Errorf("unable to create and attach policy %s: %w", policyName, err)
 					}
-					applier.eventListener.Handle(PolicyUpdated, policyName)
+					applier.handle(applyReport, PolicyUpdated, policyName)
 				}
 			} else {
 				applier.logger.Info(fmt.Sprintf("Creating policy %s and attaching to %s", policyName, *currentRole.RoleName))
@@ -231,7 +272,7 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 				if err != nil {
 					return fmt.Errorf("unable to create and attach policy %s: %w", policyName, err)
 				}
-				applier.eventListener.Handle(PolicyCreated, policyName)
+				applier.handle(applyReport, PolicyCreated, policyName)
 			}
 		}
 	}
@@ -245,12 +286,16 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 			if err != nil {
 				return fmt.Errorf("unable to detach and delete policy %s: %w", *attachedPolicy.PolicyName, err)
 			}
-			applier.eventListener.Handle(PolicyDeleted, *attachedPolicy.PolicyName)
+			applier.handle(applyReport, PolicyDeleted, *attachedPolicy.PolicyName)
 		}
 	}
 
 	unmanagedAttachedPolicies, err := applier.client.ListUnmanagedAttachedPolicies(currentRole)
 
+	if err != nil {
+		return fmt.Errorf("unable to list attached policies: %w", err)
+	}
+
 	for _, attachedPolicy := range unmanagedAttachedPolicies {
 		if desiredRole.LookupReferencedPolicy(*attachedPolicy.PolicyArn) == nil {
 
@@ -259,13 +304,14 @@ func (applier *Applier) updateRole(desiredRole *iamCore.AwsRole, currentRole *ia
 			if err != nil {
 				return fmt.Errorf("failed detaching policy %s: %w", *attachedPolicy.PolicyName, err)
 			}
+			applier.handle(applyReport, PolicyDetached, *attachedPolicy.PolicyName)
 		}
 	}
 
 	return nil
 }
 
-func (applier *Applier) deleteRole(role *iam.Role) error {
+func (applier *Applier) deleteRole(role *iam.Role, applyReport *report.ApplyReport) error {
 
 	attachedPolicies, err := applier.client.ListManagedAttachedPolicies(role)
 
@@ -282,7 +328,7 @@ func (applier *Applier) deleteRole(role *iam.Role) error {
 			return err
 		}
 
-		applier.eventListener.Handle(PolicyDeleted, *attachedPolicy.PolicyName)
+		applier.handle(applyReport, PolicyDeleted, *attachedPolicy.PolicyName)
 	}
 
 	attachedPolicies, err = applier.client.ListUnmanagedAttachedPolicies(role)
@@ -295,24 +341,191 @@ func (applier *Applier) deleteRole(role *iam.Role) error {
 		if err != nil {
 			return fmt.Errorf("failed detaching policy %s: %w", *attachedPolicy.PolicyName, err)
 		}
-		applier.eventListener.Handle(PolicyDeleted, *attachedPolicy.PolicyName)
+		applier.handle(applyReport, PolicyDeleted, *attachedPolicy.PolicyName)
 	}
 
 	return applier.client.DeleteLoginRole(role)
 }
 
-func (applier *Applier) Apply(model iamCore.Model) error {
+// Computes the changes needed to bring the current IAM state in line with the desired role without changing anything.
+// The current role is nil if the role does not exist yet.
+func (applier *Applier) planRole(desiredRole *iamCore.AwsRole, currentRole *iam.Role, policyDocuments map[string]string, applyReport *report.ApplyReport) error {
+
+	var attachedPolicies []*iam.AttachedPolicy
+	var unmanagedAttachedPolicies []*iam.AttachedPolicy
+
+	if currentRole != nil {
+		if applier.maxSessionDurationDiffers(desiredRole, currentRole) {
+			applier.plan(applyReport, MaxSessionDurationUpdated, desiredRole.Name)
+		}
+
+		trustPolicyDocument, err := buildTrustPolicyDocument(applier.accountId, applier.saml, desiredRole.TrustedPrincipals)
+
+		if err != nil {
+			return err
+		}
+
+		if !sameTrustPolicy(currentRole.AssumeRolePolicyDocument, trustPolicyDocument) {
+			applier.plan(applyReport, TrustPolicyUpdated, desiredRole.Name)
+		}
+
+		attachedPolicies, err = applier.client.ListManagedAttachedPolicies(currentRole)
+
+		if err != nil {
+			return fmt.Errorf("unable to list attached policies: %w", err)
+		}
+
+		unmanagedAttachedPolicies, err = applier.client.ListUnmanagedAttachedPolicies(currentRole)
+
+		if err != nil {
+			return fmt.Errorf("unable to list attached policies: %w", err)
+		}
+	}
+
+	for _, desiredPolicy := range desiredRole.Policies {
+		if applier.client.lookupAttachedPolicyByArn(unmanagedAttachedPolicies, desiredPolicy.Arn) == nil {
+			policy, err := applier.client.lookupPolicyByArn(desiredPolicy.Arn)
+
+			if err != nil {
+				return fmt.Errorf("unable to fetch policy: %w", err)
+			}
+
+			if policy == nil {
+				return fmt.Errorf("referenced policy with Arn %s does not exist", desiredPolicy.Arn)
+			}
+			applier.plan(applyReport, PolicyAttached, *policy.PolicyName)
+		}
+	}
+
+	for _, desiredPolicy := range desiredRole.DatabaseLoginPolicies {
+
+		policyName := desiredPolicy.DatabaseUsername
+		attachedPolicy := applier.client.lookupAttachedPolicy(attachedPolicies, policyName)
+
+		if len(desiredPolicy.Databases) == 0 {
+			if attachedPolicy != nil {
+				applier.plan(applyReport, PolicyDeleted, policyName)
+			}
+		} else if attachedPolicy == nil {
+			applier.plan(applyReport, PolicyCreated, policyName)
+		} else if applier.buildDatabaseLoginPolicyDocument(desiredPolicy) != policyDocuments[policyName] {
+			applier.plan(applyReport, PolicyUpdated, policyName)
+		}
+	}
+
+	for _, attachedPolicy := range attachedPolicies {
+		if desiredRole.LookupDatabaseLoginPolicyForUsername(*attachedPolicy.PolicyName) == nil {
+			applier.plan(applyReport, PolicyDeleted, *attachedPolicy.PolicyName)
+		}
+	}
+
+	for _, attachedPolicy := range unmanagedAttachedPolicies {
+		if desiredRole.LookupReferencedPolicy(*attachedPolicy.PolicyArn) == nil {
+			applier.plan(applyReport, PolicyDetached, *attachedPolicy.PolicyName)
+		}
+	}
+
+	return nil
+}
+
+func (applier *Applier) planDeleteRole(role *iam.Role, applyReport *report.ApplyReport) error {
+
+	attachedPolicies, err := applier.client.ListManagedAttachedPolicies(role)
+
+	if err != nil {
+		return err
+	}
+
+	for _, attachedPolicy := range attachedPolicies {
+		applier.plan(applyReport, PolicyDeleted, *attachedPolicy.PolicyName)
+	}
+
+	attachedPolicies, err = applier.client.ListUnmanagedAttachedPolicies(role)
+
+	if err != nil {
+		return err
+	}
+
+	for _, attachedPolicy := range attachedPolicies {
+		applier.plan(applyReport, PolicyDeleted, *attachedPolicy.PolicyName)
+	}
+
+	return nil
+}
+
+// Returns a report of the changes that applying the model would result in, without changing anything in IAM.
+func (applier *Applier) Plan(model iamCore.Model) (*report.ApplyReport, error) {
+
+	applyReport := report.New()
 
 	policyDocuments, err := applier.client.GetPolicyDocuments()
 
 	if err != nil {
-		return fmt.Errorf("unable to list policy documents: %w", err)
+		return applyReport, fmt.Errorf("unable to list policy documents: %w", err)
 	}
 
 	existingRoles, err := applier.client.ListRoles()
 
 	if err != nil {
-		return fmt.Errorf("unable to list roles: %w", err)
+		return applyReport, fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	for _, desiredRole := range model.Roles {
+		existingRole := applier.lookupRole(existingRoles, desiredRole.Name)
+
+		if existingRole == nil {
+			applier.plan(applyReport, RoleCreated, desiredRole.Name)
+		}
+
+		numActions := len(applyReport.Actions)
+		err := applier.planRole(desiredRole, existingRole, policyDocuments, applyReport)
+
+		if err != nil {
+			err = fmt.Errorf("failed when planning role %s: %w", desiredRole.Name, err)
+			applyReport.Failed(report.IAM, RoleUpdated.ToString(), desiredRole.Name, err)
+			return applyReport, err
+		}
+
+		if existingRole != nil && len(applyReport.Actions) > numActions {
+			applier.plan(applyReport, RoleUpdated, desiredRole.Name)
+		}
+	}
+
+	for _, existingRole := range existingRoles {
+		if model.LookupRole(*existingRole.RoleName) == nil {
+			err = applier.planDeleteRole(existingRole, applyReport)
+
+			if err != nil {
+				err = fmt.Errorf("failed when planning deletion of role %s: %w", *existingRole.RoleName, err)
+				applyReport.Failed(report.IAM, RoleDeleted.ToString(), *existingRole.RoleName, err)
+				return applyReport, err
+			}
+			applier.plan(applyReport, RoleDeleted, *existingRole.RoleName)
+		}
+	}
+
+	return applyReport, nil
+}
+
+// Applies the model to IAM. In dry run mode nothing is changed and the changes are reported as planned instead.
+func (applier *Applier) Apply(model iamCore.Model, dryRun bool) (*report.ApplyReport, error) {
+
+	if dryRun {
+		return applier.Plan(model)
+	}
+
+	applyReport := report.New()
+
+	policyDocuments, err := applier.client.GetPolicyDocuments()
+
+	if err != nil {
+		return applyReport, fmt.Errorf("unable to list policy documents: %w", err)
+	}
+
+	existingRoles, err := applier.client.ListRoles()
+
+	if err != nil {
+		return applyReport, fmt.Errorf("unable to list roles: %w", err)
 	}
 
 	for _, desiredRole := range model.Roles {
@@ -322,33 +535,43 @@ func (applier *Applier) Apply(model iamCore.Model) error {
 
 		if existingRole == nil {
 			applier.logger.Info(fmt.Sprintf("Creating role %s", desiredRole.Name))
-			existingRole, err = applier.createRole(desiredRole.Name)
+			existingRole, err = applier.createRole(desiredRole)
 
 			if err != nil {
-				return fmt.Errorf("failed when creating role %s: %w", desiredRole.Name, err)
+				err = fmt.Errorf("failed when creating role %s: %w", desiredRole.Name, err)
+				applyReport.Failed(report.IAM, RoleCreated.ToString(), desiredRole.Name, err)
+				return applyReport, err
 			}
-			applier.eventListener.Handle(RoleCreated, desiredRole.Name)
+			applier.handle(applyReport, RoleCreated, desiredRole.Name)
 		}
 
 		applier.logger.Info(fmt.Sprintf("Updating role %s", desiredRole.Name))
-		err = applier.updateRole(desiredRole, existingRole, policyDocuments)
+		numActions := len(applyReport.Actions)
+		err = applier.updateRole(desiredRole, existingRole, policyDocuments, applyReport)
 		if err != nil {
-			return fmt.Errorf("failed when updating role %s: %w", desiredRole.Name, err)
+			err = fmt.Errorf("failed when updating role %s: %w", desiredRole.Name, err)
+			applyReport.Failed(report.IAM, RoleUpdated.ToString(), desiredRole.Name, err)
+			return applyReport, err
+		}
+		//the role is only reported as updated if its policies were changed, so re-applying an unchanged model reports nothing
+		if len(applyReport.Actions) > numActions {
+			applier.handle(applyReport, RoleUpdated, desiredRole.Name)
 		}
-		applier.eventListener.Handle(RoleUpdated, desiredRole.Name)
 	}
 
 	for _, existingRole := range existingRoles {
 		if model.LookupRole(*existingRole.RoleName) == nil {
 			applier.logger.Info(fmt.Sprintf("Deleting role %s", *existingRole.RoleName))
-			err = applier.deleteRole(existingRole)
+			err = applier.deleteRole(existingRole, applyReport)
 
 			if err != nil {
-				return fmt.Errorf("failed when deleting role %s: %w", *existingRole.RoleName, err)
+				err = fmt.Errorf("failed when deleting role %s: %w", *existingRole.RoleName, err)
+				applyReport.Failed(report.IAM, RoleDeleted.ToString(), *existingRole.RoleName, err)
+				return applyReport, err
 			}
-			applier.eventListener.Handle(RoleDeleted, *existingRole.RoleName)
+			applier.handle(applyReport, RoleDeleted, *existingRole.RoleName)
 		}
 	}
 
-	return nil
+	return applyReport, nil
 }
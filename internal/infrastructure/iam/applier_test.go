@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
 	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -71,13 +72,13 @@ func setUp(t *testing.T) TestContext {
 	iamClient := New(session)
 	eventRecorder := EventRecorder{}
 	logger := infrastructure.NewLogger(t)
-	applier := NewApplier(iamClient, accountId, region, &eventRecorder, logger)
+	applier := NewApplier(iamClient, accountId, region, DefaultSamlConfig(), &eventRecorder, logger)
 
 	roles, err := iamClient.ListRoles()
 	failOnError(err)
 
 	for _, role := range roles {
-		err = applier.deleteRole(role)
+		err = applier.deleteRole(role, report.New())
 		failOnError(err)
 	}
 
@@ -104,7 +105,7 @@ func TestApplier_NoRoles(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{}})
+	_, err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{}}, false)
 	assert.NoError(err)
 
 	actual := FetchIAMState(context.client)
@@ -121,7 +122,7 @@ func TestApplier_SingleRole(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	applyReport, err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -137,7 +138,7 @@ func TestApplier_SingleRole(t *testing.T) {
 				},
 			},
 		},
-	}})
+	}}, false)
 
 	assert.NoError(err)
 
@@ -148,6 +149,9 @@ func TestApplier_SingleRole(t *testing.T) {
 
 	assert.Equal(1, context.eventRecorder.Count(RoleCreated))
 	assert.Equal(1, context.eventRecorder.Count(PolicyCreated))
+
+	assert.Equal(3, applyReport.Count(report.IAM, report.Executed), "role created, policy created and role updated are reported")
+	assert.False(applyReport.HasFailures())
 }
 
 func TestApplier_SingleRoleTwoDatabases(t *testing.T) {
@@ -156,7 +160,7 @@ func TestApplier_SingleRoleTwoDatabases(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -176,7 +180,7 @@ func TestApplier_SingleRoleTwoDatabases(t *testing.T) {
 				},
 			},
 		},
-	}})
+	}}, false)
 
 	assert.NoError(err)
 
@@ -195,7 +199,7 @@ func TestApplier_SingleRoleTwoUsers(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -221,7 +225,7 @@ func TestApplier_SingleRoleTwoUsers(t *testing.T) {
 				},
 			},
 		},
-	}})
+	}}, false)
 
 	assert.NoError(err)
 
@@ -240,7 +244,7 @@ func TestApplier_SingleRoleAddAnotherDatabase(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -256,11 +260,11 @@ func TestApplier_SingleRoleAddAnotherDatabase(t *testing.T) {
 				},
 			},
 		},
-	}})
+	}}, false)
 
 	assert.NoError(err)
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -280,7 +284,7 @@ func TestApplier_SingleRoleAddAnotherDatabase(t *testing.T) {
 				},
 			},
 		},
-	}})
+	}}, false)
 
 	assert.NoError(err)
 
@@ -300,7 +304,7 @@ func TestApplier_RemoveUserWillRemoveAccess(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -316,7 +320,7 @@ func TestApplier_RemoveUserWillRemoveAccess(t *testing.T) {
 				},
 			},
 		},
-	}})
+	}}, false)
 
 	failOnError(err)
 
@@ -325,12 +329,12 @@ func TestApplier_RemoveUserWillRemoveAccess(t *testing.T) {
 	expected.Roles = map[string][]string{"BiAnalyst": {"jwr_bianalyst"}}
 	AssertState(assert, actual, expected, "IAM role has not attached policies")
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name:                  "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{},
 		},
-	}})
+	}}, false)
 	failOnError(err)
 
 	actual = FetchIAMState(context.client)
@@ -344,12 +348,12 @@ func TestApplier_RoleWithNoUsers(t *testing.T) {
 
 	assert := assert.New(t)
 
-	err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err := context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name:                  "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{},
 		},
-	}})
+	}}, false)
 	assert.NoError(err)
 
 	actual := FetchIAMState(context.client)
@@ -386,7 +390,7 @@ func TestApplier_UserWithReferencedUnmanagedPolicies(t *testing.T) {
 	_, err := context.client.createUnmanagedPolicy("access-to-tmp-bucket", policyDocument)
 	failOnError(err)
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -403,7 +407,7 @@ func TestApplier_UserWithReferencedUnmanagedPolicies(t *testing.T) {
 			},
 			Policies: []*iamCore.PolicyReference{{Arn: "arn:aws:iam::000000000000:policy/access-to-tmp-bucket"}},
 		},
-	}})
+	}}, false)
 
 	assert.NoError(err)
 
@@ -412,7 +416,34 @@ func TestApplier_UserWithReferencedUnmanagedPolicies(t *testing.T) {
 	expected.Roles = map[string][]string{"BiAnalyst": {"jwr_bianalyst", "access-to-tmp-bucket"}}
 	AssertState(assert, actual, expected, "IAM role have been created")
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	referencingModel := iamCore.Model{Roles: []*iamCore.AwsRole{
+		{
+			Name: "BiAnalyst",
+			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
+				{
+					Email:            "jwr@lunar.app",
+					DatabaseUsername: "jwr_bianalyst",
+					Databases: []*iamCore.Database{
+						{
+							ClusterIdentifier: "dev",
+							Name:              "jwr",
+						},
+					},
+				},
+			},
+			Policies: []*iamCore.PolicyReference{{Arn: "arn:aws:iam::000000000000:policy/access-to-tmp-bucket"}},
+		},
+	}}
+
+	applyReport, err := context.applier.Apply(referencingModel, false)
+	assert.NoError(err)
+	assert.Empty(applyReport.Actions, "the referenced policy is already attached, so nothing is attached again")
+
+	applyReport, err = context.applier.Apply(referencingModel, true)
+	assert.NoError(err)
+	assert.Empty(applyReport.Actions, "the dry run agrees with the apply")
+
+	_, err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -429,7 +460,7 @@ func TestApplier_UserWithReferencedUnmanagedPolicies(t *testing.T) {
 			},
 			Policies: []*iamCore.PolicyReference{},
 		},
-	}})
+	}}, false)
 	assert.NoError(err)
 
 	actual = FetchIAMState(context.client)
@@ -465,7 +496,7 @@ func TestApplier_UserWithReferencedUnmanagedPoliciesCanBeDeleted(t *testing.T) {
 	_, err := context.client.createUnmanagedPolicy("access-to-tmp-bucket", policyDocument)
 	failOnError(err)
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
+	_, err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{
 		{
 			Name: "BiAnalyst",
 			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
@@ -482,7 +513,7 @@ func TestApplier_UserWithReferencedUnmanagedPoliciesCanBeDeleted(t *testing.T) {
 			},
 			Policies: []*iamCore.PolicyReference{{Arn: "arn:aws:iam::000000000000:policy/access-to-tmp-bucket"}},
 		},
-	}})
+	}}, false)
 
 	assert.NoError(err)
 
@@ -491,10 +522,88 @@ func TestApplier_UserWithReferencedUnmanagedPoliciesCanBeDeleted(t *testing.T) {
 	expected.Roles = map[string][]string{"BiAnalyst": {"jwr_bianalyst", "access-to-tmp-bucket"}}
 	AssertState(assert, actual, expected, "IAM role have been created")
 
-	err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{}})
+	_, err = context.applier.Apply(iamCore.Model{Roles: []*iamCore.AwsRole{}}, false)
 	assert.NoError(err)
 
 	actual = FetchIAMState(context.client)
 	expected.Roles = map[string][]string{}
 	AssertState(assert, actual, expected, "IAM role has been deleted")
 }
+
+func TestApplier_DryRun(t *testing.T) {
+
+	context := setUp(t)
+
+	assert := assert.New(t)
+
+	model := iamCore.Model{Roles: []*iamCore.AwsRole{
+		{
+			Name: "BiAnalyst",
+			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
+				{
+					Email:            "jwr@lunar.app",
+					DatabaseUsername: "jwr_bianalyst",
+					Databases: []*iamCore.Database{
+						{
+							ClusterIdentifier: "dev",
+							Name:              "jwr",
+						},
+					},
+				},
+			},
+		},
+	}}
+
+	applyReport, err := context.applier.Apply(model, true)
+	assert.NoError(err)
+
+	actual := FetchIAMState(context.client)
+	expected := IAMState{}
+	expected.Roles = map[string][]string{}
+	AssertState(assert, actual, expected, "Nothing has been created in a dry run")
+
+	assert.Equal(0, context.eventRecorder.CountAll())
+	assert.Equal(2, applyReport.Count(report.IAM, report.Planned), "role created and policy created are planned")
+
+	_, err = context.applier.Apply(model, false)
+	assert.NoError(err)
+
+	applyReport, err = context.applier.Apply(model, true)
+	assert.NoError(err)
+	assert.Empty(applyReport.Actions, "nothing is planned when the model has been applied")
+}
+
+func TestApplier_ReapplyUnchangedModel(t *testing.T) {
+
+	context := setUp(t)
+
+	assert := assert.New(t)
+
+	model := iamCore.Model{Roles: []*iamCore.AwsRole{
+		{
+			Name: "BiAnalyst",
+			DatabaseLoginPolicies: []*iamCore.DatabaseLoginPolicy{
+				{
+					Email:            "jwr@lunar.app",
+					DatabaseUsername: "jwr_bianalyst",
+					Databases: []*iamCore.Database{
+						{
+							ClusterIdentifier: "dev",
+							Name:              "jwr",
+						},
+					},
+				},
+			},
+		},
+	}}
+
+	_, err := context.applier.Apply(model, false)
+	assert.NoError(err)
+	context.eventRecorder.Reset()
+
+	applyReport, err := context.applier.Apply(model, false)
+	assert.NoError(err)
+
+	assert.Empty(applyReport.Actions, "an unchanged role is not reported as updated")
+	assert.Equal(0, context.eventRecorder.CountAll())
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 	log "github.com/sirupsen/logrus"
+	"net/url"
 	"strings"
 )
 
@@ -356,7 +357,7 @@ func (client *Client) DeleteAttachedPolicy(policy *iam.AttachedPolicy) error {
 	return client.deletePolicy(*policy.PolicyName, *policy.PolicyArn)
 }
 
-func (client *Client) CreateOrUpdateLoginRole(name string, accountId string) (*iam.Role, error) {
+func (client *Client) CreateOrUpdateLoginRole(name string, trustPolicyDocument string, maxSessionDuration int64) (*iam.Role, error) {
 
 	c := iam.New(client.session)
 
@@ -374,30 +375,8 @@ func (client *Client) CreateOrUpdateLoginRole(name string, accountId string) (*i
 		}
 	}
 
-	var maxSessionDuration int64 = 14400
-
-	assumeRolePolicyDocument := `
-{
-  "Version": "2012-10-17",
-  "Statement": [
-    {
-      "Effect": "Allow",
-      "Principal": {
-        "Federated": "arn:aws:iam::%s:saml-provider/GoogleApps"
-      },
-      "Action": "sts:AssumeRoleWithSAML",
-      "Condition": {
-        "StringEquals": {
-          "SAML:aud": "https://signin.aws.amazon.com/saml"
-        }
-      }
-    }
-  ]
-}
-`
-
 	response, err := c.CreateRole(&iam.CreateRoleInput{
-		AssumeRolePolicyDocument: aws.String(strings.TrimSpace(fmt.Sprintf(assumeRolePolicyDocument, accountId))),
+		AssumeRolePolicyDocument: aws.String(trustPolicyDocument),
 		Description:              aws.String("test"),
 		MaxSessionDuration:       &maxSessionDuration,
 		Path:                     &iamPrefix,
@@ -413,6 +392,42 @@ func (client *Client) CreateOrUpdateLoginRole(name string, accountId string) (*i
 	return response.Role, nil
 }
 
+// Changes the maximum duration of the sessions with the role, in seconds
+func (client *Client) UpdateMaxSessionDuration(role *iam.Role, maxSessionDuration int64) error {
+	c := iam.New(client.session)
+
+	_, err := c.UpdateRole(&iam.UpdateRoleInput{
+		RoleName:           role.RoleName,
+		MaxSessionDuration: &maxSessionDuration,
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to update max session duration of role %s: %w", *role.RoleName, err)
+	}
+
+	role.MaxSessionDuration = &maxSessionDuration
+
+	return nil
+}
+
+// Replaces the trust policy of the role, which controls who may assume the role
+func (client *Client) UpdateTrustPolicy(role *iam.Role, trustPolicyDocument string) error {
+	c := iam.New(client.session)
+
+	_, err := c.UpdateAssumeRolePolicy(&iam.UpdateAssumeRolePolicyInput{
+		RoleName:       role.RoleName,
+		PolicyDocument: aws.String(trustPolicyDocument),
+	})
+
+	if err != nil {
+		return fmt.Errorf("unable to update trust policy of role %s: %w", *role.RoleName, err)
+	}
+
+	role.AssumeRolePolicyDocument = aws.String(url.QueryEscape(trustPolicyDocument))
+
+	return nil
+}
+
 func (client *Client) DeleteLoginRole(role *iam.Role) error {
 	c := iam.New(client.session)
 
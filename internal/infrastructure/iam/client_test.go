@@ -21,6 +21,14 @@ func init() {
 	//log.SetFormatter(&log.JSONFormatter{PrettyPrint:true})
 }
 
+func loginRoleTrustPolicy() string {
+	document, err := buildTrustPolicyDocument(accountId, DefaultSamlConfig(), nil)
+	if err != nil {
+		panic(err)
+	}
+	return document
+}
+
 func Test_CreatePolicy_Is_Idempotent(t *testing.T) {
 
 	assert := assert.New(t)
@@ -107,7 +115,7 @@ func Test_AttachPolicy_Is_Idempotent(t *testing.T) {
 	session := LocalStackSessionFactory{}.CreateSession()
 	iamClient := New(session)
 
-	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), accountId)
+	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), loginRoleTrustPolicy(), 14400)
 	assert.NoError(err)
 
 	document := `
@@ -151,7 +159,7 @@ func Test_DetachPolicy_Is_Idempotent(t *testing.T) {
 	session := LocalStackSessionFactory{}.CreateSession()
 	iamClient := New(session)
 
-	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), accountId)
+	role, err := iamClient.CreateOrUpdateLoginRole(utils.GenerateRandomString(10), loginRoleTrustPolicy(), 14400)
 	assert.NoError(err)
 
 	document := `
@@ -201,10 +209,10 @@ func Test_CreateLoginRole_Is_Idempotent(t *testing.T) {
 	iamClient := New(session)
 
 	roleName := utils.GenerateRandomString(10)
-	_, err := iamClient.CreateOrUpdateLoginRole(roleName, accountId)
+	_, err := iamClient.CreateOrUpdateLoginRole(roleName, loginRoleTrustPolicy(), 14400)
 	assert.NoError(err)
 
-	_, err = iamClient.CreateOrUpdateLoginRole(roleName, accountId)
+	_, err = iamClient.CreateOrUpdateLoginRole(roleName, loginRoleTrustPolicy(), 14400)
 	assert.NoError(err)
 }
 
@@ -216,7 +224,7 @@ func Test_DeleteLoginRole_Is_Idempotent(t *testing.T) {
 	iamClient := New(session)
 
 	roleName := utils.GenerateRandomString(10)
-	role, err := iamClient.CreateOrUpdateLoginRole(roleName, accountId)
+	role, err := iamClient.CreateOrUpdateLoginRole(roleName, loginRoleTrustPolicy(), 14400)
 	assert.NoError(err)
 
 	err = iamClient.DeleteLoginRole(role)
@@ -0,0 +1,115 @@
+package iam
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// The SAML identity provider the users log into the managed roles with
+type SamlConfig struct {
+	ProviderName string                            //the name of the SAML identity provider in IAM
+	Audience     string                            //the audience the SAML assertions must be issued for
+	Conditions   map[string]map[string]interface{} //extra conditions of the trust policy by operator and key, e.g. {"StringLike": {"SAML:sub": "*@lunar.app"}}
+}
+
+// Google Workspace registered as the GoogleApps identity provider, logging into the AWS console
+func DefaultSamlConfig() SamlConfig {
+	return SamlConfig{
+		ProviderName: "GoogleApps",
+		Audience:     "https://signin.aws.amazon.com/saml",
+	}
+}
+
+type trustStatement struct {
+	Effect    string                            `json:"Effect"`
+	Principal map[string]interface{}            `json:"Principal"`
+	Action    string                            `json:"Action"`
+	Condition map[string]map[string]interface{} `json:"Condition,omitempty"`
+}
+
+type trustPolicyDocument struct {
+	Version   string           `json:"Version"`
+	Statement []trustStatement `json:"Statement"`
+}
+
+// Builds the trust policy of a login role, which allows the users of the SAML provider and the trusted principals to assume the role
+func buildTrustPolicyDocument(accountId string, saml SamlConfig, trustedPrincipals []string) (string, error) {
+
+	conditions := map[string]map[string]interface{}{
+		"StringEquals": {"SAML:aud": saml.Audience},
+	}
+	for operator, values := range saml.Conditions {
+		if conditions[operator] == nil {
+			conditions[operator] = make(map[string]interface{})
+		}
+		for key, value := range values {
+			conditions[operator][key] = value
+		}
+	}
+
+	document := trustPolicyDocument{
+		Version: "2012-10-17",
+		Statement: []trustStatement{{
+			Effect:    "Allow",
+			Principal: map[string]interface{}{"Federated": fmt.Sprintf("arn:aws:iam::%s:saml-provider/%s", accountId, saml.ProviderName)},
+			Action:    "sts:AssumeRoleWithSAML",
+			Condition: conditions,
+		}},
+	}
+
+	if len(trustedPrincipals) > 0 {
+		document.Statement = append(document.Statement, trustStatement{
+			Effect:    "Allow",
+			Principal: map[string]interface{}{"AWS": trustedPrincipals},
+			Action:    "sts:AssumeRole",
+		})
+	}
+
+	result, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal trust policy: %w", err)
+	}
+	return string(result), nil
+}
+
+// IAM returns a list with a single element as the element itself, so those lists are replaced by their element before the documents are compared
+func normalizePolicy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		if len(v) == 1 {
+			return normalizePolicy(v[0])
+		}
+		for i := range v {
+			v[i] = normalizePolicy(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = normalizePolicy(v[key])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Returns true if the trust policy of an existing role, as returned URL encoded by IAM, is equivalent to the desired one
+func sameTrustPolicy(current *string, desired string) bool {
+	if current == nil {
+		return false
+	}
+
+	decoded, err := url.QueryUnescape(*current)
+	if err != nil {
+		return false
+	}
+
+	var currentValue, desiredValue interface{}
+	if json.Unmarshal([]byte(decoded), &currentValue) != nil || json.Unmarshal([]byte(desired), &desiredValue) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(normalizePolicy(currentValue), normalizePolicy(desiredValue))
+}
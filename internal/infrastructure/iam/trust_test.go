@@ -0,0 +1,53 @@
+package iam
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func Test_BuildTrustPolicyDocument(t *testing.T) {
+
+	assert := assert.New(t)
+
+	saml := DefaultSamlConfig()
+	saml.Conditions = map[string]map[string]interface{}{
+		"StringLike": {"SAML:sub": "*@lunar.app"},
+	}
+
+	document, err := buildTrustPolicyDocument("478824949770", saml, []string{"arn:aws:iam::478824949770:role/ci"})
+	assert.NoError(err)
+
+	var policy trustPolicyDocument
+	assert.NoError(json.Unmarshal([]byte(document), &policy))
+	assert.Len(policy.Statement, 2)
+
+	samlStatement := policy.Statement[0]
+	assert.Equal("sts:AssumeRoleWithSAML", samlStatement.Action)
+	assert.Equal("arn:aws:iam::478824949770:saml-provider/GoogleApps", samlStatement.Principal["Federated"])
+	assert.Equal("https://signin.aws.amazon.com/saml", samlStatement.Condition["StringEquals"]["SAML:aud"])
+	assert.Equal("*@lunar.app", samlStatement.Condition["StringLike"]["SAML:sub"], "the extra conditions are added")
+
+	principalStatement := policy.Statement[1]
+	assert.Equal("sts:AssumeRole", principalStatement.Action)
+	assert.Equal([]interface{}{"arn:aws:iam::478824949770:role/ci"}, principalStatement.Principal["AWS"])
+}
+
+func Test_SameTrustPolicy(t *testing.T) {
+
+	assert := assert.New(t)
+
+	document, err := buildTrustPolicyDocument("478824949770", DefaultSamlConfig(), []string{"arn:aws:iam::478824949770:role/ci"})
+	assert.NoError(err)
+
+	//IAM returns the document URL encoded, compacted and with single element lists replaced by their element
+	current := url.QueryEscape(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::478824949770:saml-provider/GoogleApps"},"Action":"sts:AssumeRoleWithSAML","Condition":{"StringEquals":{"SAML:aud":"https://signin.aws.amazon.com/saml"}}},{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::478824949770:role/ci"},"Action":"sts:AssumeRole"}]}`)
+	assert.True(sameTrustPolicy(&current, document))
+
+	withoutPrincipals, err := buildTrustPolicyDocument("478824949770", DefaultSamlConfig(), nil)
+	assert.NoError(err)
+	assert.False(sameTrustPolicy(&current, withoutPrincipals))
+
+	assert.False(sameTrustPolicy(nil, document))
+}
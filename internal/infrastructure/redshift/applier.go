@@ -4,39 +4,91 @@ import (
 	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
 )
 
 type Applier struct {
-	reconcilerConfig redshift.ReconcilerConfig
-	clientGroup      ClientGroup
-	excluded         *redshift.Exclusions
-	awsAccountId     string
-	logger           logr.Logger
+	reconcilerConfig       redshift.ReconcilerConfig
+	dagRunnerConfig        redshift.DagRunnerConfig
+	clientGroup            ClientGroup
+	excluded               *redshift.Exclusions
+	awsAccountId           string
+	logger                 logr.Logger
+	roleBasedAccessControl bool //access is granted to redshift roles instead of groups
 }
 
-func NewApplier(clientGroup ClientGroup, excluded *redshift.Exclusions, awsAccountId string, logger logr.Logger, reconcilerConfig redshift.ReconcilerConfig) *Applier {
+func NewApplier(clientGroup ClientGroup, excluded *redshift.Exclusions, awsAccountId string, logger logr.Logger, reconcilerConfig redshift.ReconcilerConfig, dagRunnerConfig redshift.DagRunnerConfig, roleBasedAccessControl bool) *Applier {
 	return &Applier{
-		clientGroup:      clientGroup,
-		reconcilerConfig: reconcilerConfig,
-		excluded:         excluded,
-		awsAccountId:     awsAccountId,
-		logger:           logger,
+		clientGroup:            clientGroup,
+		reconcilerConfig:       reconcilerConfig,
+		dagRunnerConfig:        dagRunnerConfig,
+		excluded:               excluded,
+		awsAccountId:           awsAccountId,
+		logger:                 logger,
+		roleBasedAccessControl: roleBasedAccessControl,
 	}
 }
 
-func (applier *Applier) Apply(model redshift.Model, dryRun bool) error {
+func (applier *Applier) buildReport(dag *redshift.ReconciliationDag, dryRun bool) *report.ApplyReport {
+
+	result := report.New()
+
+	for _, task := range dag.Tasks() {
+		name := fmt.Sprintf("%s/%s", task.ClusterIdentifier(), task.Identifier())
+
+		var action *report.Action
+		switch task.State() {
+		case redshift.Success:
+			if dryRun {
+				action = result.Planned(report.Redshift, task.Type().String(), name)
+			} else {
+				action = result.Executed(report.Redshift, task.Type().String(), name)
+			}
+		case redshift.Failed:
+			action = result.Failed(report.Redshift, task.Type().String(), name, task.Err())
+		default:
+			action = result.Skipped(report.Redshift, task.Type().String(), name)
+		}
+		action.TaskState = task.State().String()
+	}
+	return result
+}
+
+// The IAM roles an external schema is created with when none are given
+func defaultDatalakeRoles(awsAccountId string) []string {
+	return []string{fmt.Sprintf("arn:aws:iam::%s:role/redshift-datalake", awsAccountId)}
+}
+
+// The IAM roles of the external schemas are read back, so the default role is filled in before the desired model is compared to the current one
+func (applier *Applier) defaultIamRoles(model redshift.Model) {
+	for _, cluster := range model.Clusters {
+		for _, database := range cluster.Databases {
+			for _, grantee := range database.Grantees() {
+				for _, schema := range grantee.GrantedExternalSchemas {
+					if len(schema.IamRoles) == 0 {
+						schema.IamRoles = defaultDatalakeRoles(applier.awsAccountId)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (applier *Applier) Apply(model redshift.Model, dryRun bool) (*report.ApplyReport, error) {
 
 	err := model.Validate(applier.excluded)
 
 	if err != nil {
-		return err
+		return report.New(), err
 	}
 
+	applier.defaultIamRoles(model)
+
 	clientPool := NewClientPool(applier.clientGroup)
 
 	defer clientPool.Close()
 
-	resolver := NewModelResolver(applier.clientGroup, applier.excluded)
+	resolver := NewModelResolver(applier.clientGroup, applier.excluded, applier.roleBasedAccessControl)
 	var taskRunner redshift.TaskRunner
 	if dryRun {
 		taskRunner = redshift.NewTaskPrinter(applier.logger)
@@ -44,7 +96,7 @@ func (applier *Applier) Apply(model redshift.Model, dryRun bool) error {
 		taskRunner = NewTaskRunnerImpl(clientPool, applier.awsAccountId, applier.logger)
 	}
 
-	dagRunner := redshift.NewSequentialDagRunner(taskRunner, applier.logger)
+	dagRunner := redshift.NewDagRunner(applier.dagRunnerConfig, taskRunner, applier.logger)
 
 	var clusterIdentifiers []string
 	for _, cluster := range model.Clusters {
@@ -56,7 +108,7 @@ func (applier *Applier) Apply(model redshift.Model, dryRun bool) error {
 	currentModel, err := resolver.Resolve(clusterIdentifiers)
 
 	if err != nil {
-		return err
+		return report.New(), err
 	}
 
 	applier.logger.Info("Current model fetched", "model", currentModel)
@@ -67,9 +119,11 @@ func (applier *Applier) Apply(model redshift.Model, dryRun bool) error {
 
 	dagRunner.Run(dag)
 
+	applyReport := applier.buildReport(dag, dryRun)
+
 	if len(dag.GetFailed()) > 0 {
-		return fmt.Errorf("apply failed, %d tasks failed", len(dag.GetFailed()))
+		return applyReport, fmt.Errorf("apply failed, %d tasks failed", len(dag.GetFailed()))
 	}
 
-	return nil
+	return applyReport, nil
 }
@@ -45,13 +45,13 @@ func TestApplier_ManageResources(t *testing.T) {
 	excludedDatabases := []string{"template0", "postgres"}
 
 	clientGroup := NewClientGroupForTest(&localhostCredentials)
-	applier := NewApplier(clientGroup, redshift.NewExclusions(excludedDatabases, excludedUsers), "478824949770", logger, redshift.DefaultReconcilerConfig())
+	applier := NewApplier(clientGroup, redshift.NewExclusions(excludedDatabases, excludedUsers), "478824949770", logger, redshift.DefaultReconcilerConfig(), redshift.DefaultDagRunnerConfig(), false)
 
 	//Create empty model
 	model := redshift.Model{}
 	cluster := model.DeclareCluster("dev")
 
-	err := applier.Apply(model, false)
+	_, err := applier.Apply(model, false)
 	assert.NoError(err)
 
 	//Create a database with a BI user
@@ -61,7 +61,7 @@ func TestApplier_ManageResources(t *testing.T) {
 	biDatabaseGroup := database.DeclareGroup("bianalyst")
 	database.DeclareUser("jwr_bianalyst")
 
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	assert.NoError(err)
 
 	redshiftClient, err := clientGroup.ForDatabase(database)
@@ -78,7 +78,7 @@ func TestApplier_ManageResources(t *testing.T) {
 	//Grant access to "bi"
 	biDatabaseGroup.GrantSchema(&redshift.Schema{Name: "bi"})
 
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	assert.NoError(err)
 
 	actual = FetchState(redshiftClient)
@@ -92,7 +92,7 @@ func TestApplier_ManageResources(t *testing.T) {
 	//Grant access to "test"
 	biDatabaseGroup.GrantSchema(&redshift.Schema{Name: "test"})
 
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	assert.NoError(err)
 
 	actual = FetchState(redshiftClient)
@@ -107,7 +107,7 @@ func TestApplier_ManageResources(t *testing.T) {
 	cluster.DeclareUser("nra_bianalyst", biGroup)
 	database.DeclareUser("nra_bianalyst")
 
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	assert.NoError(err)
 
 	actual = FetchState(redshiftClient)
@@ -125,7 +125,7 @@ func TestApplier_ManageResources(t *testing.T) {
 	cluster.DeclareUser("jwr_aml", amlGroup)
 	database.DeclareUser("jwr_aml")
 
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	assert.NoError(err)
 
 	actual = FetchState(redshiftClient)
@@ -146,7 +146,7 @@ func TestApplier_FailsOnExcludedUser(t *testing.T) {
 	excludedDatabases := []string{"template0", "postgres"}
 
 	clientGroup := NewClientGroupForTest(&localhostCredentials)
-	applier := NewApplier(clientGroup, redshift.NewExclusions(excludedDatabases, excludedUsers), "478824949770", logger, redshift.DefaultReconcilerConfig())
+	applier := NewApplier(clientGroup, redshift.NewExclusions(excludedDatabases, excludedUsers), "478824949770", logger, redshift.DefaultReconcilerConfig(), redshift.DefaultDagRunnerConfig(), false)
 
 	model := redshift.Model{}
 	cluster := model.DeclareCluster("dev")
@@ -156,6 +156,53 @@ func TestApplier_FailsOnExcludedUser(t *testing.T) {
 	cluster.DeclareUser("lunarway", biGroup)
 	database.DeclareUser("lunarway")
 
-	err := applier.Apply(model, false)
+	_, err := applier.Apply(model, false)
 	assert.Error(err)
 }
+
+func TestApplier_ExternalSchemas(t *testing.T) {
+
+	assert := assert.New(t)
+
+	logger := infrastructure.NewLogger(t)
+	excludedUsers := []string{"lunarway"}
+	//the databases of the other tests are excluded, as the access of the group to them would be revoked by every apply
+	excludedDatabases := []string{"template0", "template1", "postgres", "lunarway", "jwr"}
+
+	clientGroup := NewClientGroupForTest(&localhostCredentials)
+	applier := NewApplier(clientGroup, redshift.NewExclusions(excludedDatabases, excludedUsers), "478824949770", logger, redshift.DefaultReconcilerConfig(), redshift.DefaultDagRunnerConfig(), false)
+
+	model := redshift.Model{}
+	cluster := model.DeclareCluster("dev")
+	amlGroup := cluster.DeclareGroup("aml")
+	cluster.DeclareUser("kni_aml", amlGroup)
+	database := cluster.DeclareDatabase("kni")
+	database.DeclareUser("kni_aml")
+	amlDatabaseGroup := database.DeclareGroup("aml")
+	amlDatabaseGroup.GrantSchema(&redshift.Schema{Name: "public"})
+	lake := &redshift.ExternalSchema{Name: "intercom", GlueDatabaseName: "intercom"}
+	amlDatabaseGroup.GrantExternalSchema(lake)
+
+	_, err := applier.Apply(model, false)
+	assert.NoError(err)
+
+	applyReport, err := applier.Apply(model, true)
+	assert.NoError(err)
+	assert.Empty(applyReport.Actions, "the schema created as an external schema is read back as one, so applying the model again plans no tasks")
+
+	//Change the IAM roles and the region of the schema
+	lake.IamRoles = []string{"arn:aws:iam::478824949770:role/redshift", "arn:aws:iam::210987654321:role/datalake"}
+	lake.CatalogRegion = "eu-west-1"
+
+	applyReport, err = applier.Apply(model, false)
+	assert.NoError(err)
+	assert.Len(applyReport.Actions, 2, "the schema is replaced and the group is granted the new schema")
+
+	currentModel, err := NewModelResolver(clientGroup, redshift.NewExclusions(excludedDatabases, excludedUsers), false).Resolve([]string{"dev"})
+	assert.NoError(err)
+	assert.Equal(lake, currentModel.LookupCluster("dev").LookupDatabase("kni").LookupGroup("aml").LookupGrantedExternalSchema("intercom"))
+
+	applyReport, err = applier.Apply(model, true)
+	assert.NoError(err)
+	assert.Empty(applyReport.Actions)
+}
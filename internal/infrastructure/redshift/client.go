@@ -2,11 +2,14 @@ package redshift
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/utils"
 	"net/url"
+	"strings"
 )
 
 type Client struct {
@@ -104,16 +107,26 @@ func (c *Client) stringRows(sql string) ([]Row, error) {
 		return nil, err
 	}
 	defer rows.Close()
+
+	columns, err := rows.Columns()
+
+	if err != nil {
+		return nil, err
+	}
+
 	var result []Row
 	for rows.Next() {
-		var key string
-		var value string
-		err = rows.Scan(&key, &value)
+		cells := make([]string, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range cells {
+			pointers[i] = &cells[i]
+		}
+		err = rows.Scan(pointers...)
 
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, Row{Cells: []string{key, value}})
+		result = append(result, Row{Cells: cells})
 	}
 
 	return result, nil
@@ -132,6 +145,21 @@ func (c *Client) Groups() ([]string, error) {
 	return c.stringList("SELECT groname FROM pg_group WHERE groname !~ '^pg_' and groname !~'_datashare_roles'")
 }
 
+//Returns the roles that are not system roles. Roles only exist on redshift, so this and the other role queries must only be used with role based access control
+func (c *Client) Roles() ([]string, error) {
+	return c.stringList("select role_name from svv_roles where role_name !~ '^sys:'")
+}
+
+//Returns the name of the users and the roles granted to them
+func (c *Client) UsersAndRoles() ([]Row, error) {
+	return c.stringRows("select user_name, role_name from svv_user_grants")
+}
+
+//Returns the name of the roles and the roles granted to them
+func (c *Client) NestedRoles() ([]Row, error) {
+	return c.stringRows("select role_name, granted_role_name from svv_role_grants")
+}
+
 func (c *Client) Users() ([]string, error) {
 	return c.stringList("select usename from pg_user")
 }
@@ -205,7 +233,35 @@ func (c *Client) DeleteGroup(groupName string) error {
 	}
 
 	for _, schema := range grants {
-		err = c.Revoke(groupName, schema)
+		err = c.Revoke(redshift.GroupGrantee.Clause(groupName), schema)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	tableGrants, err := c.TableGrants(groupName)
+
+	if err != nil {
+		return err
+	}
+
+	for _, row := range tableGrants {
+		err = c.RevokeTable(redshift.GroupGrantee.Clause(groupName), row.Cells[0], row.Cells[1], nil, true)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	columnGrants, err := c.ColumnGrants(groupName)
+
+	if err != nil {
+		return err
+	}
+
+	for _, row := range columnGrants {
+		err = c.RevokeTable(redshift.GroupGrantee.Clause(groupName), row.Cells[0], row.Cells[1], []string{row.Cells[2]}, true)
 
 		if err != nil {
 			return err
@@ -224,6 +280,52 @@ func (c *Client) DeleteGroup(groupName string) error {
 	return err
 }
 
+func (c *Client) CreateRole(roleName string) error {
+
+	roles, err := c.Roles()
+
+	if err != nil {
+		return err
+	}
+
+	if c.contains(roles, roleName) {
+		return nil
+	}
+
+	_, err = c.db.Exec(fmt.Sprintf("CREATE ROLE %s", roleName))
+
+	return err
+}
+
+//Drops the role. The reconciler revokes the privileges of the role, and the role from its grantees, before the role is dropped
+func (c *Client) DeleteRole(roleName string) error {
+
+	roles, err := c.Roles()
+
+	if err != nil {
+		return err
+	}
+
+	if !c.contains(roles, roleName) {
+		return nil
+	}
+
+	_, err = c.db.Exec(fmt.Sprintf("DROP ROLE %s", roleName))
+
+	return err
+}
+
+//Grants the role to the grantee, which is written as in GRANT statements, e.g. jwr_bianalyst or ROLE analyst
+func (c *Client) GrantRole(roleName string, grantee string) error {
+	_, err := c.db.Exec(fmt.Sprintf("GRANT ROLE %s TO %s", roleName, grantee))
+	return err
+}
+
+func (c *Client) RevokeRole(roleName string, grantee string) error {
+	_, err := c.db.Exec(fmt.Sprintf("REVOKE ROLE %s FROM %s", roleName, grantee))
+	return err
+}
+
 func (c *Client) CreateSchema(name string) error {
 
 	schemas, err := c.Schemas()
@@ -240,11 +342,17 @@ func (c *Client) CreateSchema(name string) error {
 	return err
 }
 
-func (c *Client) CreateExternalSchema(name string, externalDatabaseName string, awsAccountId string) error {
+//The options of an external schema as redshift reports them in svv_external_schemas, e.g. {"IAM_ROLE":"arn:aws:iam::123456789012:role/redshift-datalake","REGION":"eu-west-1"}.
+//When external schemas are not supported the glue database is kept in the options too, as they are stored in the comment of the regular schema created instead
+type externalSchemaOptions struct {
+	Database string `json:"DATABASE,omitempty"`
+	IamRole  string `json:"IAM_ROLE"`
+	Region   string `json:"REGION,omitempty"`
+}
 
-	if !c.externalSchemasSupported {
-		return c.CreateSchema(name)
-	}
+//Creates an external schema for the glue database. The IAM roles are chained in the given order, and the region of the data catalog defaults to the region of the cluster if it is empty.
+//When external schemas are not supported a regular schema is created, which is read back as an external schema from its comment
+func (c *Client) CreateExternalSchema(name string, externalDatabaseName string, iamRoles []string, catalogRegion string) error {
 
 	schemas, err := c.Schemas()
 
@@ -256,14 +364,55 @@ func (c *Client) CreateExternalSchema(name string, externalDatabaseName string,
 		return nil
 	}
 
+	if !c.externalSchemasSupported {
+		options, err := json.Marshal(externalSchemaOptions{Database: externalDatabaseName, IamRole: strings.Join(iamRoles, ","), Region: catalogRegion})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.Exec(fmt.Sprintf("CREATE SCHEMA %s", name))
+
+		if err != nil {
+			return err
+		}
+
+		_, err = c.db.Exec(fmt.Sprintf("COMMENT ON SCHEMA %s IS %s", name, pq.QuoteLiteral(string(options))))
+		return err
+	}
+
 	sql := `
             create external schema if not exists %s
             from data catalog
             database '%s'
-            iam_role 'arn:aws:iam::%s:role/redshift-datalake'
+            iam_role '%s'
+`
+	sql = fmt.Sprintf(sql, name, externalDatabaseName, strings.Join(iamRoles, ","))
+
+	if catalogRegion != "" {
+		sql += fmt.Sprintf("            region '%s'\n", catalogRegion)
+	}
+
+	_, err = c.db.Exec(sql)
+	return err
+}
+
+//Returns the name of the external schemas in the database, the glue databases they reference and their options, e.g. the IAM roles.
+//External schemas are created as regular schemas with the options in their comment when they are not supported, so those are returned in that case
+func (c *Client) ExternalSchemas() ([]Row, error) {
+	if !c.externalSchemasSupported {
+		sql := `
+select nspname, obj_description(oid, 'pg_namespace')::json->>'DATABASE', obj_description(oid, 'pg_namespace') from pg_catalog.pg_namespace where
+obj_description(oid, 'pg_namespace') LIKE '{"DATABASE":%'
 `
+		return c.stringRows(sql)
+	}
+	return c.stringRows("select schemaname, databasename, esoptions from svv_external_schemas")
+}
 
-	_, err = c.db.Exec(fmt.Sprintf(sql, name, externalDatabaseName, awsAccountId))
+//Drops the schema, which for an external schema leaves the glue database it references alone
+func (c *Client) DropSchema(name string) error {
+	_, err := c.db.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s", name))
 	return err
 }
 
@@ -336,7 +485,23 @@ func (c *Client) SetSchemaOwner(username string, schema string) error {
 	return err
 }
 
-func (c *Client) Grants(groupName string) ([]string, error) {
+//The privilege functions, e.g. has_schema_privilege, only work on users (not groups), therefore a dummy user is created in the group while the privileges are queried
+func (c *Client) asGroupMember(groupName string, query func(username string) error) error {
+
+	_, err := c.db.Exec(fmt.Sprintf("CREATE USER dummy_%s PASSWORD '%s' IN GROUP %s", groupName, generateRedshiftPassword(), groupName))
+
+	if err != nil && err.(*pq.Error).Code != duplicateObjectErrorCode {
+		return err
+	}
+
+	//drop the dummy user
+	defer c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS dummy_%s", groupName))
+
+	return query(fmt.Sprintf("dummy_%s", groupName))
+}
+
+//Returns the schemas the group has the given privilege on
+func (c *Client) schemasWithPrivilege(groupName string, privilege string) ([]string, error) {
 
 	schemas, err := c.Schemas()
 
@@ -344,60 +509,227 @@ func (c *Client) Grants(groupName string) ([]string, error) {
 		return nil, err
 	}
 
-	//The has_schema_privilege function only works on users (not groups), therefore we need to create a dummy user
-	_, err = c.db.Exec(fmt.Sprintf("CREATE USER dummy_%s PASSWORD '%s' IN GROUP %s", groupName, generateRedshiftPassword(), groupName))
+	var result []string
 
-	if err != nil && err.(*pq.Error).Code != duplicateObjectErrorCode {
-		return nil, err
+	err = c.asGroupMember(groupName, func(username string) error {
+		for _, schema := range schemas {
+			isGranted, err := c.bool(fmt.Sprintf("select pg_catalog.has_schema_privilege('%s', '%s', '%s')", username, schema, privilege))
+
+			if err != nil {
+				return err
+			}
+			if isGranted {
+				result = append(result, schema)
+			}
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+func (c *Client) Grants(groupName string) ([]string, error) {
+	return c.schemasWithPrivilege(groupName, "USAGE")
+}
+
+//The privileges granted on a schema per privilege level
+func schemaPrivileges(privilege redshift.Privilege) string {
+	if privilege == redshift.OwnerPrivilege {
+		return "ALL"
 	}
+	return "USAGE"
+}
 
-	//drop the dummy user
-	defer c.db.Exec(fmt.Sprintf("DROP USER IF EXISTS dummy_%s", groupName))
+//The privileges granted on the tables of a schema per privilege level
+func tablePrivileges(privilege redshift.Privilege) string {
+	switch privilege {
+	case redshift.WritePrivilege:
+		return "SELECT, INSERT, UPDATE, DELETE"
+	case redshift.OwnerPrivilege:
+		return "ALL"
+	default:
+		return "SELECT"
+	}
+}
 
-	var result []string
+//Grants the privilege level on the schema to the grantee, which is written as in GRANT statements, e.g. GROUP bianalyst or ROLE bianalyst
+func (c *Client) Grant(grantee string, schemaName string, privilege redshift.Privilege) error {
+	_, err := c.db.Exec(fmt.Sprintf("GRANT %s ON SCHEMA %s TO %s", schemaPrivileges(privilege), schemaName, grantee))
+
+	if err != nil {
+		return err
+	}
+	_, err = c.db.Exec(fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA %s TO %s", tablePrivileges(privilege), schemaName, grantee))
+
+	if err != nil {
+		return err
+	}
+	_, err = c.db.Exec(fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT %s ON TABLES TO %s", schemaName, tablePrivileges(privilege), grantee))
+
+	return err
+}
+
+//Lowers the privilege level of the grantee on the schema. The privileges are revoked and the lower level is granted in a single transaction, so the grantee never loses access in between
+func (c *Client) Downgrade(grantee string, schemaName string, privilege redshift.Privilege) error {
+
+	tx, err := c.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		fmt.Sprintf("REVOKE ALL ON ALL TABLES IN SCHEMA %s FROM %s", schemaName, grantee),
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s REVOKE ALL ON TABLES FROM %s", schemaName, grantee),
+		fmt.Sprintf("REVOKE CREATE ON SCHEMA %s FROM %s", schemaName, grantee),
+		fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA %s TO %s", tablePrivileges(privilege), schemaName, grantee),
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT %s ON TABLES TO %s", schemaName, tablePrivileges(privilege), grantee),
+	}
 
-	for _, schema := range schemas {
-		isGranted, err := c.bool(fmt.Sprintf("select pg_catalog.has_schema_privilege('dummy_%s', '%s', 'USAGE')", groupName, schema))
+	for _, statement := range statements {
+		_, err = tx.Exec(statement)
 
 		if err != nil {
-			return nil, err
-		}
-		if isGranted {
-			result = append(result, schema)
+			tx.Rollback()
+			return err
 		}
 	}
 
-	return result, nil
+	return tx.Commit()
 }
 
-func (c *Client) Grant(groupName string, schemaName string) error {
-	_, err := c.db.Exec(fmt.Sprintf("GRANT ALL ON SCHEMA %s TO GROUP %s", schemaName, groupName))
+func (c *Client) Revoke(grantee string, schemaName string) error {
 
+	_, err := c.db.Exec(fmt.Sprintf("REVOKE ALL ON ALL TABLES IN SCHEMA %s FROM %s", schemaName, grantee))
 	if err != nil {
 		return err
 	}
-	_, err = c.db.Exec(fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO GROUP %s", schemaName, groupName))
 
+	_, err = c.db.Exec(fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s REVOKE ALL ON TABLES FROM %s", schemaName, grantee))
 	if err != nil {
 		return err
 	}
-	_, err = c.db.Exec(fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s GRANT SELECT ON TABLES TO GROUP %s", schemaName, groupName))
 
+	_, err = c.db.Exec(fmt.Sprintf("REVOKE ALL ON SCHEMA %s FROM %s", schemaName, grantee))
 	return err
 }
 
-func (c *Client) Revoke(groupName string, schemaName string) error {
+//Returns the schema and the access control list of the default privileges on the tables created in the schema
+func (c *Client) DefaultPrivileges() ([]Row, error) {
+	sql := `
+select n.nspname, coalesce(array_to_string(d.defaclacl, ','), '') from pg_default_acl d, pg_namespace n where
+d.defaclnamespace = n.oid AND d.defaclobjtype = 'r'
+`
+	return c.stringRows(sql)
+}
+
+//Returns the schemas and their access control lists. Unlike has_schema_privilege, the access control lists tell the privileges granted to a group apart from those granted to PUBLIC, e.g. CREATE on the public schema
+func (c *Client) SchemaAcls() ([]Row, error) {
+	sql := `
+select nspname, coalesce(array_to_string(nspacl, ','), '') from pg_catalog.pg_namespace where
+nspname !~ '^pg_' AND nspname <> 'information_schema'
+`
+	return c.stringRows(sql)
+}
+
+//Returns the schema and the name of the tables the group may select from
+func (c *Client) TableGrants(groupName string) ([]Row, error) {
+	sql := `
+select schemaname, tablename from pg_tables where
+schemaname !~ '^pg_' AND schemaname <> 'information_schema' AND
+pg_catalog.has_table_privilege('%s', quote_ident(schemaname) || '.' || quote_ident(tablename), 'SELECT')
+`
+	var result []Row
+	err := c.asGroupMember(groupName, func(username string) error {
+		var err error
+		result, err = c.stringRows(fmt.Sprintf(sql, username))
+		return err
+	})
+	return result, err
+}
+
+//Returns the schema, the table and the name of the columns the group may select from tables it may not select from as a whole
+func (c *Client) ColumnGrants(groupName string) ([]Row, error) {
+	sql := `
+select table_schema, table_name, column_name from information_schema.columns where
+table_schema !~ '^pg_' AND table_schema <> 'information_schema' AND
+NOT pg_catalog.has_table_privilege('%[1]s', quote_ident(table_schema) || '.' || quote_ident(table_name), 'SELECT') AND
+pg_catalog.has_column_privilege('%[1]s', quote_ident(table_schema) || '.' || quote_ident(table_name), column_name, 'SELECT')
+`
+	var result []Row
+	err := c.asGroupMember(groupName, func(username string) error {
+		var err error
+		result, err = c.stringRows(fmt.Sprintf(sql, username))
+		return err
+	})
+	return result, err
+}
+
+func selectPrivilege(columns []string) string {
+	if len(columns) == 0 {
+		return "SELECT"
+	}
+	return fmt.Sprintf("SELECT (%s)", strings.Join(columns, ", "))
+}
+
+//Grants select on the table, or only on the given columns of it, without granting the rest of the schema
+func (c *Client) GrantTable(grantee string, schemaName string, tableName string, columns []string) error {
+	_, err := c.db.Exec(fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", schemaName, grantee))
 
-	_, err := c.db.Exec(fmt.Sprintf("REVOKE SELECT ON ALL TABLES IN SCHEMA %s FROM GROUP %s", schemaName, groupName))
 	if err != nil {
 		return err
 	}
+	_, err = c.db.Exec(fmt.Sprintf("GRANT %s ON TABLE %s.%s TO %s", selectPrivilege(columns), schemaName, tableName, grantee))
+
+	return err
+}
+
+func (c *Client) RevokeTable(grantee string, schemaName string, tableName string, columns []string, revokeUsage bool) error {
+	_, err := c.db.Exec(fmt.Sprintf("REVOKE %s ON TABLE %s.%s FROM %s", selectPrivilege(columns), schemaName, tableName, grantee))
 
-	_, err = c.db.Exec(fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA %s REVOKE SELECT ON TABLES FROM GROUP %s", schemaName, groupName))
 	if err != nil {
 		return err
 	}
 
-	_, err = c.db.Exec(fmt.Sprintf("REVOKE ALL ON SCHEMA %s FROM GROUP %s", schemaName, groupName))
+	if revokeUsage {
+		_, err = c.db.Exec(fmt.Sprintf("REVOKE USAGE ON SCHEMA %s FROM %s", schemaName, grantee))
+	}
 	return err
 }
+
+//Returns the schema and the privilege type, e.g. USAGE or CREATE, of the privileges granted to the role on schemas.
+//The privilege functions used for groups would include the privileges the role inherits from its nested roles, the system views only contain the privileges granted to the role itself.
+func (c *Client) RoleSchemaPrivileges(roleName string) ([]Row, error) {
+	sql := `
+select namespace_name, privilege_type from svv_schema_privileges where
+identity_type = 'role' AND identity_name = '%s'
+`
+	return c.stringRows(fmt.Sprintf(sql, roleName))
+}
+
+//Returns the schema and the privilege type, e.g. SELECT or INSERT, of the default privileges granted to the role on the tables created in schemas
+func (c *Client) RoleDefaultPrivileges(roleName string) ([]Row, error) {
+	sql := `
+select schema_name, privilege_type from svv_default_privileges where
+object_type = 'RELATION' AND grantee_type = 'role' AND grantee_name = '%s'
+`
+	return c.stringRows(fmt.Sprintf(sql, roleName))
+}
+
+//Returns the schema and the name of the tables the role has been granted select on
+func (c *Client) RoleTableGrants(roleName string) ([]Row, error) {
+	sql := `
+select namespace_name, relation_name from svv_relation_privileges where
+privilege_type = 'SELECT' AND identity_type = 'role' AND identity_name = '%s'
+`
+	return c.stringRows(fmt.Sprintf(sql, roleName))
+}
+
+//Returns the schema, the table and the name of the columns the role has been granted select on
+func (c *Client) RoleColumnGrants(roleName string) ([]Row, error) {
+	sql := `
+select namespace_name, relation_name, column_name from svv_column_privileges where
+privilege_type = 'SELECT' AND identity_type = 'role' AND identity_name = '%s'
+`
+	return c.stringRows(fmt.Sprintf(sql, roleName))
+}
@@ -1,6 +1,10 @@
 package redshift
 
+import "sync"
+
+// The client pool is shared by the tasks of a DAG, which may run concurrently
 type ClientPool struct {
+	mutex         sync.Mutex
 	clientGroup   ClientGroup
 	masterClients map[string]*Client
 	clients       map[string]*Client
@@ -15,6 +19,8 @@ func NewClientPool(clientGroup ClientGroup) *ClientPool {
 }
 
 func (c *ClientPool) GetClusterClient(clusterIdentifier string) (*Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	client, ok := c.masterClients[clusterIdentifier]
 
@@ -31,6 +37,8 @@ func (c *ClientPool) GetClusterClient(clusterIdentifier string) (*Client, error)
 }
 
 func (c *ClientPool) GetDatabaseClient(clusterIdentifier string, databaseName string) (*Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
 	identifier := clusterIdentifier + "." + databaseName
 	client, ok := c.clients[identifier]
@@ -48,6 +56,8 @@ func (c *ClientPool) GetDatabaseClient(clusterIdentifier string, databaseName st
 }
 
 func (c *ClientPool) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	for _, client := range c.clients {
 		client.Close()
 	}
@@ -5,6 +5,7 @@ package redshift
 //YOU MUST RUN docker-compose up PRIOR TO RUNNING THIS TEST
 
 import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/utils"
 	"github.com/stretchr/testify/assert"
 	"strings"
@@ -38,14 +39,14 @@ func TestClient_CreateUser(t *testing.T) {
 	assert.NoError(err)
 	assert.Contains(groups, groupName)
 
-	err = client.Grant(groupName, schema)
+	err = client.Grant(redshift.GroupGrantee.Clause(groupName), schema, redshift.ReadPrivilege)
 	assert.NoError(err)
 
 	grants, err := client.Grants(groupName)
 	assert.NoError(err)
 	assert.Contains(grants, schema)
 
-	err = client.Revoke(groupName, schema)
+	err = client.Revoke(redshift.GroupGrantee.Clause(groupName), schema)
 	assert.NoError(err)
 
 	err = client.RemoveUserFromGroup(username, groupName)
@@ -57,3 +58,46 @@ func TestClient_CreateUser(t *testing.T) {
 	err = client.DeleteUser(username)
 	assert.NoError(err)
 }
+
+func TestClient_GrantTable(t *testing.T) {
+
+	assert := assert.New(t)
+
+	schema := "clienttest"
+	groupName := "clienttest"
+
+	client, _ := NewClient("lunarway", "lunarway", "localhost", "lunarway", "disable", 5432, false)
+
+	err := client.CreateGroup(groupName)
+	assert.NoError(err)
+
+	err = client.CreateSchema(schema)
+	assert.NoError(err)
+
+	_, err = client.db.Exec("CREATE TABLE IF NOT EXISTS clienttest.loans (id int, amount int)")
+	assert.NoError(err)
+	_, err = client.db.Exec("CREATE TABLE IF NOT EXISTS clienttest.customers (id int, segment varchar(16))")
+	assert.NoError(err)
+
+	err = client.GrantTable(redshift.GroupGrantee.Clause(groupName), schema, "loans", nil)
+	assert.NoError(err)
+
+	err = client.GrantTable(redshift.GroupGrantee.Clause(groupName), schema, "customers", []string{"id"})
+	assert.NoError(err)
+
+	tableGrants, err := client.TableGrants(groupName)
+	assert.NoError(err)
+	assert.Equal([]Row{{Cells: []string{schema, "loans"}}}, tableGrants)
+
+	columnGrants, err := client.ColumnGrants(groupName)
+	assert.NoError(err)
+	assert.Equal([]Row{{Cells: []string{schema, "customers", "id"}}}, columnGrants)
+
+	schemaAcls, err := client.SchemaAcls()
+	assert.NoError(err)
+	assert.NotContains(groupSchemasWithPrivilege(schemaAcls, groupName, "C"), schema, "the group may only use the schema of the granted tables")
+	assert.NotContains(groupSchemasWithPrivilege(schemaAcls, groupName, "C"), "public", "the group may only create tables in public through PUBLIC")
+
+	err = client.DeleteGroup(groupName)
+	assert.NoError(err)
+}
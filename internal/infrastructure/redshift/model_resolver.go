@@ -1,29 +1,26 @@
 package redshift
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	"golang.org/x/sync/errgroup"
+	"strings"
 )
 
 // The ModelResolver can query the clusters and resolve the current state and return it as a redshift.Model.
 type ModelResolver struct {
-	clientGroup ClientGroup
-	excluded    *redshift.Exclusions
+	clientGroup            ClientGroup
+	excluded               *redshift.Exclusions
+	roleBasedAccessControl bool //read back the roles as well. Roles only exist on redshift, so they are only read when they are used
 }
 
-func NewModelResolver(clientGroup ClientGroup, excluded *redshift.Exclusions) *ModelResolver {
-	return &ModelResolver{clientGroup: clientGroup, excluded: excluded}
+func NewModelResolver(clientGroup ClientGroup, excluded *redshift.Exclusions, roleBasedAccessControl bool) *ModelResolver {
+	return &ModelResolver{clientGroup: clientGroup, excluded: excluded, roleBasedAccessControl: roleBasedAccessControl}
 }
 
 func (m *ModelResolver) resolveCluster(clusterIdentifier string, cluster *redshift.Cluster) error {
 
-	//TODO: find a solution that does not require hardcoding of the external schemas!
-	externalSchemas := map[string]string{
-		"lwgoevents":   "lw-go-events",
-		"eventstreams": "eventstreams",
-		"intercom":     "intercom",
-		"googlesheets": "google-sheets"}
-
 	clientPool := NewClientPool(m.clientGroup)
 
 	defer clientPool.Close()
@@ -60,11 +57,22 @@ func (m *ModelResolver) resolveCluster(clusterIdentifier string, cluster *redshi
 		return err
 	}
 
+	//a user is declared with all the groups it is member of, the system groups are not managed though
 	for _, row := range usersAndGroups {
 		user := row.Cells[0]
-		group := row.Cells[1]
-		if !m.excluded.IsUserExcluded(user) {
-			cluster.DeclareUser(user, cluster.LookupGroup(group))
+		group := cluster.LookupGroup(row.Cells[1])
+		if !m.excluded.IsUserExcluded(user) && group != nil {
+			cluster.DeclareUser(user, group)
+		}
+	}
+
+	var roles []string
+
+	if m.roleBasedAccessControl {
+		roles, err = m.resolveRoles(c, cluster)
+
+		if err != nil {
+			return err
 		}
 	}
 
@@ -94,15 +102,31 @@ func (m *ModelResolver) resolveCluster(clusterIdentifier string, cluster *redshi
 		if err != nil {
 			return err
 		}
-		for _, row := range usersAndGroups {
-			user := row.Cells[0]
-			if !m.excluded.IsUserExcluded(user) {
-				database.DeclareUser(user)
-			}
+		for _, user := range cluster.Users {
+			database.DeclareUser(user.Name)
+		}
+
+		externalSchemas, err := resolveExternalSchemas(databaseClient)
+
+		if err != nil {
+			return err
+		}
+
+		defaultPrivileges, err := databaseClient.DefaultPrivileges()
+
+		if err != nil {
+			return err
+		}
+
+		schemaAcls, err := databaseClient.SchemaAcls()
+
+		if err != nil {
+			return err
 		}
 
 		for _, group := range groups {
 			databaseGroup := database.DeclareGroup(group)
+			groupDefaults := groupDefaultPrivileges(defaultPrivileges, group)
 
 			grants, err := databaseClient.Grants(group)
 
@@ -110,21 +134,307 @@ func (m *ModelResolver) resolveCluster(clusterIdentifier string, cluster *redshi
 				return err
 			}
 
-			for _, schema := range grants {
+			tables, err := resolveTableGrants(databaseClient, group)
+
+			if err != nil {
+				return err
+			}
 
-				glueDatabase, ok := externalSchemas[schema]
+			//schemas granted as a whole are granted with ALL, whereas a group that is only granted some tables in a schema may just use it
+			creatableSchemas := groupSchemasWithPrivilege(schemaAcls, group, "C")
 
-				if ok {
-					databaseGroup.GrantExternalSchema(&redshift.ExternalSchema{Name: schema, GlueDatabaseName: glueDatabase})
-				} else {
-					databaseGroup.GrantSchema(&redshift.Schema{Name: schema})
-				}
+			resolveDatabaseGroup(databaseGroup, grants, creatableSchemas, groupDefaults, tables, externalSchemas)
+		}
+
+		for _, role := range roles {
+			databaseRole := database.DeclareRole(role)
+
+			privileges, err := databaseClient.RoleSchemaPrivileges(role)
+
+			if err != nil {
+				return err
+			}
+
+			defaultPrivileges, err := databaseClient.RoleDefaultPrivileges(role)
+
+			if err != nil {
+				return err
+			}
+
+			tables, err := resolveRoleTableGrants(databaseClient, role)
+
+			if err != nil {
+				return err
 			}
+
+			resolveDatabaseGroup(databaseRole, schemasWithPrivilege(privileges, "USAGE"), schemasWithPrivilege(privileges, "CREATE"), roleDefaultPrivileges(defaultPrivileges), tables, externalSchemas)
 		}
 	}
 	return nil
 }
 
+// Reads back the roles, the roles nested in them and the users that have been granted them
+func (m *ModelResolver) resolveRoles(c *Client, cluster *redshift.Cluster) ([]string, error) {
+
+	roles, err := c.Roles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		cluster.DeclareRole(role)
+	}
+
+	nestedRoles, err := c.NestedRoles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range nestedRoles {
+		role := cluster.LookupRole(row.Cells[0])
+		granted := cluster.LookupRole(row.Cells[1])
+		if role != nil && granted != nil {
+			role.Grant(granted)
+		}
+	}
+
+	usersAndRoles, err := c.UsersAndRoles()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range usersAndRoles {
+		user := row.Cells[0]
+		role := cluster.LookupRole(row.Cells[1])
+		if !m.excluded.IsUserExcluded(user) && role != nil {
+			cluster.DeclareUserWithRole(user, role)
+		}
+	}
+
+	return roles, nil
+}
+
+// Reads back the glue database, IAM roles and region of every external schema in the database by the name of the schema
+func resolveExternalSchemas(databaseClient *Client) (map[string]*redshift.ExternalSchema, error) {
+
+	rows, err := databaseClient.ExternalSchemas()
+
+	if err != nil {
+		return nil, err
+	}
+
+	externalSchemas := make(map[string]*redshift.ExternalSchema)
+	for _, row := range rows {
+		schema, err := externalSchemaOf(row)
+
+		if err != nil {
+			return nil, err
+		}
+		externalSchemas[schema.Name] = schema
+	}
+	return externalSchemas, nil
+}
+
+// Builds the external schema from its name, glue database and options, e.g. {"IAM_ROLE":"arn:aws:iam::123456789012:role/redshift-datalake","REGION":"eu-west-1"}
+func externalSchemaOf(row Row) (*redshift.ExternalSchema, error) {
+
+	var options externalSchemaOptions
+	if err := json.Unmarshal([]byte(row.Cells[2]), &options); err != nil {
+		return nil, fmt.Errorf("unable to parse the options of external schema %s: %w", row.Cells[0], err)
+	}
+
+	schema := &redshift.ExternalSchema{Name: row.Cells[0], GlueDatabaseName: row.Cells[1], CatalogRegion: options.Region}
+	if options.IamRole != "" {
+		schema.IamRoles = strings.Split(options.IamRole, ",")
+	}
+	return schema, nil
+}
+
+// Resolves the access of the group, or role, from the schemas it may use and create objects in, its default privileges and the tables it has been granted
+func resolveDatabaseGroup(databaseGroup *redshift.DatabaseGroup, grants []string, creatableSchemas []string, defaults map[string]string, tables []*redshift.Table, externalSchemas map[string]*redshift.ExternalSchema) {
+
+	for _, schema := range grants {
+
+		//the group may use a schema it has only been granted some tables in, such a schema is not granted as a whole
+		_, hasDefaults := defaults[schema]
+		if hasTablesIn(tables, schema) && !hasDefaults && !contains(creatableSchemas, schema) {
+			continue
+		}
+
+		externalSchema, ok := externalSchemas[schema]
+
+		if ok {
+			granted := *externalSchema
+			databaseGroup.GrantExternalSchema(&granted)
+		} else {
+			databaseGroup.GrantSchema(&redshift.Schema{Name: schema, Privilege: resolvePrivilege(contains(creatableSchemas, schema), defaults[schema])})
+		}
+	}
+
+	//the tables of a schema granted as a whole are covered by the schema grant
+	for _, table := range tables {
+		if databaseGroup.LookupGrantedSchema(table.Schema) == nil && databaseGroup.LookupGrantedExternalSchema(table.Schema) == nil {
+			databaseGroup.GrantTable(table)
+		}
+	}
+}
+
+// Reads back the tables, and the columns of tables, the role has been granted select on
+func resolveRoleTableGrants(databaseClient *Client, role string) ([]*redshift.Table, error) {
+
+	tableGrants, err := databaseClient.RoleTableGrants(role)
+
+	if err != nil {
+		return nil, err
+	}
+
+	columnGrants, err := databaseClient.RoleColumnGrants(role)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tablesOf(tableGrants, columnGrants), nil
+}
+
+// Returns the schemas of the privileges of the given type, e.g. USAGE
+func schemasWithPrivilege(privileges []Row, privilegeType string) []string {
+	var result []string
+	for _, row := range privileges {
+		if strings.EqualFold(row.Cells[1], privilegeType) && !contains(result, row.Cells[0]) {
+			result = append(result, row.Cells[0])
+		}
+	}
+	return result
+}
+
+// The letters of the privileges in access control lists, so the default privileges of roles can be resolved like those of groups
+var aclPrivilegeLetters = map[string]string{
+	"SELECT":     "r",
+	"INSERT":     "a",
+	"UPDATE":     "w",
+	"DELETE":     "d",
+	"REFERENCES": "x",
+	"DROP":       "D",
+	"ALTER":      "U",
+	"TRUNCATE":   "t",
+}
+
+// Returns the default privileges of the role on the tables created in each schema, as the privilege letters of the access control lists, e.g. arwd
+func roleDefaultPrivileges(defaultPrivileges []Row) map[string]string {
+	result := make(map[string]string)
+	for _, row := range defaultPrivileges {
+		result[row.Cells[0]] += aclPrivilegeLetters[strings.ToUpper(row.Cells[1])]
+	}
+	return result
+}
+
+// Reads back the tables, and the columns of tables, the group has been granted select on
+func resolveTableGrants(databaseClient *Client, group string) ([]*redshift.Table, error) {
+
+	tableGrants, err := databaseClient.TableGrants(group)
+
+	if err != nil {
+		return nil, err
+	}
+
+	columnGrants, err := databaseClient.ColumnGrants(group)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tablesOf(tableGrants, columnGrants), nil
+}
+
+// Merges the granted tables and the granted columns of tables into tables
+func tablesOf(tableGrants []Row, columnGrants []Row) []*redshift.Table {
+
+	databaseGroup := &redshift.DatabaseGroup{}
+
+	for _, row := range tableGrants {
+		databaseGroup.GrantTable(&redshift.Table{Schema: row.Cells[0], Name: row.Cells[1]})
+	}
+	for _, row := range columnGrants {
+		databaseGroup.GrantTable(&redshift.Table{Schema: row.Cells[0], Name: row.Cells[1], Columns: []string{row.Cells[2]}})
+	}
+
+	return databaseGroup.GrantedTables
+}
+
+// Returns the default privileges of the group on the tables created in each schema, as the privilege letters of the access control lists, e.g. arwd
+func groupDefaultPrivileges(defaultPrivileges []Row, group string) map[string]string {
+	result := make(map[string]string)
+	for _, row := range defaultPrivileges {
+		for _, entry := range strings.Split(row.Cells[1], ",") {
+			grantee, privileges := parseAclEntry(entry)
+			if grantee == group {
+				result[row.Cells[0]] += privileges
+			}
+		}
+	}
+	return result
+}
+
+// Returns the schemas the group itself has been granted the privilege on, given as the letter of the access control lists, e.g. C for CREATE.
+// Privileges the group only has through PUBLIC are ignored, as they are not managed by the controller
+func groupSchemasWithPrivilege(schemaAcls []Row, group string, privilege string) []string {
+	var result []string
+	for _, row := range schemaAcls {
+		for _, entry := range strings.Split(row.Cells[1], ",") {
+			grantee, privileges := parseAclEntry(entry)
+			if grantee == group && strings.Contains(privileges, privilege) {
+				result = append(result, row.Cells[0])
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Splits an entry of an access control list, e.g. "group bianalyst=r/lunarway", into the grantee and the privilege letters. Redshift prefixes groups with "group ", postgres doesn't
+func parseAclEntry(entry string) (string, string) {
+	entry = strings.TrimPrefix(strings.TrimSpace(entry), "group ")
+	parts := strings.SplitN(entry, "=", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	privileges := strings.SplitN(parts[1], "/", 2)[0]
+	return strings.Trim(parts[0], `"`), privileges
+}
+
+// The privilege level of a schema granted as a whole. Only owners may create tables in the schema, and only writers and owners may insert into its tables
+func resolvePrivilege(creatable bool, defaultPrivileges string) redshift.Privilege {
+	if creatable {
+		return redshift.OwnerPrivilege
+	}
+	if strings.Contains(defaultPrivileges, "a") {
+		return redshift.WritePrivilege
+	}
+	return redshift.ReadPrivilege
+}
+
+func hasTablesIn(tables []*redshift.Table, schema string) bool {
+	for _, table := range tables {
+		if table.Schema == schema {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, item string) bool {
+	for _, x := range list {
+		if x == item {
+			return true
+		}
+	}
+	return false
+}
+
 // Queries the given clusters for their state and builds up a model representing the current state
 func (m *ModelResolver) Resolve(clusterIdentifiers []string) (*redshift.Model, error) {
 
@@ -1 +1,108 @@
 package redshift
+
+import (
+	"github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_GroupDefaultPrivileges(t *testing.T) {
+
+	assert := assert.New(t)
+
+	defaultPrivileges := []Row{
+		{Cells: []string{"bi", "group bianalyst=r/lunarway,group dataengineer=arwd/lunarway"}},
+		{Cells: []string{"credit", "dataengineer=r/lunarway"}},
+		{Cells: []string{"core", ""}},
+	}
+
+	assert.Equal(map[string]string{"bi": "arwd", "credit": "r"}, groupDefaultPrivileges(defaultPrivileges, "dataengineer"), "both the redshift and the postgres format are parsed")
+	assert.Equal(map[string]string{"bi": "r"}, groupDefaultPrivileges(defaultPrivileges, "bianalyst"))
+}
+
+func Test_GroupSchemasWithPrivilege(t *testing.T) {
+
+	assert := assert.New(t)
+
+	schemaAcls := []Row{
+		{Cells: []string{"public", "lunarway=UC/lunarway,=UC/lunarway,group bianalyst=U/lunarway"}},
+		{Cells: []string{"bi", "lunarway=UC/lunarway,group dataengineer=UC/lunarway,group bianalyst=U/lunarway"}},
+		{Cells: []string{"credit", ""}},
+	}
+
+	assert.Equal([]string{"bi"}, groupSchemasWithPrivilege(schemaAcls, "dataengineer", "C"), "CREATE on public is only granted through PUBLIC")
+	assert.Empty(groupSchemasWithPrivilege(schemaAcls, "bianalyst", "C"))
+	assert.Equal([]string{"public", "bi"}, groupSchemasWithPrivilege(schemaAcls, "bianalyst", "U"))
+}
+
+func Test_ResolvePrivilege(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.Equal(redshift.ReadPrivilege, resolvePrivilege(false, "r"))
+	assert.Equal(redshift.WritePrivilege, resolvePrivilege(false, "arwd"))
+	assert.Equal(redshift.OwnerPrivilege, resolvePrivilege(true, "r"), "a group that may create tables in the schema owns it")
+}
+
+func Test_ResolveDatabaseGroup_Role(t *testing.T) {
+
+	assert := assert.New(t)
+
+	privileges := []Row{
+		{Cells: []string{"public", "USAGE"}},
+		{Cells: []string{"bi", "USAGE"}},
+		{Cells: []string{"core", "USAGE"}},
+		{Cells: []string{"core", "CREATE"}},
+		{Cells: []string{"credit", "USAGE"}},
+	}
+	defaultPrivileges := []Row{
+		{Cells: []string{"bi", "SELECT"}},
+		{Cells: []string{"bi", "INSERT"}},
+	}
+	tables := tablesOf([]Row{{Cells: []string{"credit", "loans"}}}, []Row{{Cells: []string{"credit", "customers", "id"}}})
+
+	role := &redshift.DatabaseGroup{Name: "bianalyst", Grantee: redshift.RoleGrantee}
+	resolveDatabaseGroup(role, schemasWithPrivilege(privileges, "USAGE"), schemasWithPrivilege(privileges, "CREATE"), roleDefaultPrivileges(defaultPrivileges), tables, map[string]*redshift.ExternalSchema{})
+
+	assert.Equal([]string{"public", "bi", "core"}, role.Granted(), "the schema the role is only granted tables in is not granted as a whole")
+	assert.Equal(redshift.WritePrivilege, role.LookupGrantedSchema("bi").Privilege)
+	assert.Equal(redshift.OwnerPrivilege, role.LookupGrantedSchema("core").Privilege)
+	assert.Equal(2, len(role.GrantedTables))
+	assert.Equal([]string{"id"}, role.LookupGrantedTable("credit", "customers").Columns)
+}
+
+func Test_ResolveDatabaseGroup_ExternalSchemas(t *testing.T) {
+
+	assert := assert.New(t)
+
+	externalSchemas := map[string]*redshift.ExternalSchema{"lwgoevents": {Name: "lwgoevents", GlueDatabaseName: "lw-go-events"}}
+
+	group := &redshift.DatabaseGroup{Name: "aml"}
+	resolveDatabaseGroup(group, []string{"public", "lwgoevents", "lwgoevents_archive"}, []string{}, map[string]string{}, nil, externalSchemas)
+
+	assert.Equal("lw-go-events", group.LookupGrantedExternalSchema("lwgoevents").GlueDatabaseName)
+	assert.Nil(group.LookupGrantedSchema("lwgoevents"))
+	assert.NotNil(group.LookupGrantedSchema("lwgoevents_archive"), "only the schemas read back as external schemas are external")
+}
+
+func Test_ExternalSchemaOf(t *testing.T) {
+
+	assert := assert.New(t)
+
+	schema, err := externalSchemaOf(Row{Cells: []string{"lwgoevents", "lw-go-events", `{"IAM_ROLE":"arn:aws:iam::123456789012:role/redshift,arn:aws:iam::210987654321:role/datalake","REGION":"eu-west-1"}`}})
+	assert.NoError(err)
+	assert.Equal(&redshift.ExternalSchema{
+		Name:             "lwgoevents",
+		GlueDatabaseName: "lw-go-events",
+		IamRoles:         []string{"arn:aws:iam::123456789012:role/redshift", "arn:aws:iam::210987654321:role/datalake"},
+		CatalogRegion:    "eu-west-1",
+	}, schema, "the chained roles are read back in order")
+
+	schema, err = externalSchemaOf(Row{Cells: []string{"intercom", "intercom", `{"DATABASE":"intercom","IAM_ROLE":"arn:aws:iam::123456789012:role/redshift-datalake"}`}})
+	assert.NoError(err)
+	assert.Equal("", schema.CatalogRegion, "the region of the cluster is used when no region is given")
+	assert.Equal([]string{"arn:aws:iam::123456789012:role/redshift-datalake"}, schema.IamRoles)
+
+	_, err = externalSchemaOf(Row{Cells: []string{"intercom", "intercom", "not json"}})
+	assert.Error(err)
+}
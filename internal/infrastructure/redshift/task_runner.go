@@ -113,7 +113,12 @@ func (t *TaskRunnerImpl) CreateExternalSchema(model *redshift.ExternalSchemaMode
 	if err != nil {
 		return err
 	}
-	err = client.CreateExternalSchema(model.Schema.Name, model.Schema.GlueDatabaseName, t.awsAccountId)
+	iamRoles := model.Schema.IamRoles
+	if len(iamRoles) == 0 {
+		iamRoles = defaultDatalakeRoles(t.awsAccountId)
+	}
+
+	err = client.CreateExternalSchema(model.Schema.Name, model.Schema.GlueDatabaseName, iamRoles, model.Schema.CatalogRegion)
 
 	if err != nil {
 		return fmt.Errorf("failed to create schema %s on database %s: %w", model.Schema.Name, model.Database.Identifier(), err)
@@ -121,6 +126,24 @@ func (t *TaskRunnerImpl) CreateExternalSchema(model *redshift.ExternalSchemaMode
 	return nil
 }
 
+func (t *TaskRunnerImpl) ReplaceExternalSchema(model *redshift.ExternalSchemaModel) error {
+	t.log.Info(fmt.Sprintf("ReplaceExternalSchema (%s.%s) %s", model.Database.ClusterIdentifier, model.Database.Name, model.Schema.Name))
+
+	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
+
+	if err != nil {
+		return err
+	}
+
+	err = client.DropSchema(model.Schema.Name)
+
+	if err != nil {
+		return fmt.Errorf("failed to drop schema %s on database %s: %w", model.Schema.Name, model.Database.Identifier(), err)
+	}
+
+	return t.CreateExternalSchema(model)
+}
+
 func (t *TaskRunnerImpl) CreateDatabase(model *redshift.DatabaseModel) error {
 	t.log.Info(fmt.Sprintf("CreateDatabase %s.%s\n", model.ClusterIdentifier, model.Database.Name))
 
@@ -155,10 +178,10 @@ func (t *TaskRunnerImpl) GrantAccess(model *redshift.GrantsModel) error {
 	if err != nil {
 		return err
 	}
-	err = client.Grant(model.GroupName, model.SchemaName)
+	err = client.Grant(model.Grantee.Clause(model.GroupName), model.SchemaName, model.Privilege)
 
 	if err != nil {
-		return fmt.Errorf("failed to grant acccess to schema %s for group %s on database %s: %w", model.SchemaName, model.GroupName, model.Database.Identifier(), err)
+		return fmt.Errorf("failed to grant acccess to schema %s for %s %s on database %s: %w", model.SchemaName, model.Grantee, model.GroupName, model.Database.Identifier(), err)
 	}
 	return nil
 }
@@ -171,10 +194,75 @@ func (t *TaskRunnerImpl) RevokeAccess(model *redshift.GrantsModel) error {
 	if err != nil {
 		return err
 	}
-	err = client.Revoke(model.GroupName, model.SchemaName)
+	err = client.Revoke(model.Grantee.Clause(model.GroupName), model.SchemaName)
 
 	if err != nil {
-		return fmt.Errorf("unable to revoke grants for group %s in cluster %s %w", model.GroupName, model.Database.ClusterIdentifier, err)
+		return fmt.Errorf("unable to revoke grants for %s %s in cluster %s %w", model.Grantee, model.GroupName, model.Database.ClusterIdentifier, err)
+	}
+	return nil
+}
+
+func (t *TaskRunnerImpl) GrantTableAccess(model *redshift.TableGrantsModel) error {
+	t.log.Info(fmt.Sprintf("GrantTableAccess (%s.%s) %s->%s %v", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.Table.Identifier(), model.Table.Columns))
+
+	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
+
+	if err != nil {
+		return err
+	}
+	err = client.GrantTable(model.Grantee.Clause(model.GroupName), model.Table.Schema, model.Table.Name, model.Table.Columns)
+
+	if err != nil {
+		return fmt.Errorf("failed to grant acccess to table %s for %s %s on database %s: %w", model.Table.Identifier(), model.Grantee, model.GroupName, model.Database.Identifier(), err)
+	}
+	return nil
+}
+
+func (t *TaskRunnerImpl) RevokeTableAccess(model *redshift.TableGrantsModel) error {
+	t.log.Info(fmt.Sprintf("RevokeTableAccess (%s.%s) %s->%s %v", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.Table.Identifier(), model.Table.Columns))
+
+	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
+
+	if err != nil {
+		return err
+	}
+	err = client.RevokeTable(model.Grantee.Clause(model.GroupName), model.Table.Schema, model.Table.Name, model.Table.Columns, model.RevokeUsage)
+
+	if err != nil {
+		return fmt.Errorf("unable to revoke access to table %s for %s %s on database %s: %w", model.Table.Identifier(), model.Grantee, model.GroupName, model.Database.Identifier(), err)
+	}
+	return nil
+}
+
+func (t *TaskRunnerImpl) UpgradeAccess(model *redshift.GrantsModel) error {
+	t.log.Info(fmt.Sprintf("UpgradeAccess (%s.%s) %s->%s %s", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.SchemaName, model.Privilege))
+
+	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
+
+	if err != nil {
+		return err
+	}
+	//the privileges are additive, so the higher level is just granted on top of the current
+	err = client.Grant(model.Grantee.Clause(model.GroupName), model.SchemaName, model.Privilege)
+
+	if err != nil {
+		return fmt.Errorf("failed to upgrade acccess to schema %s for %s %s on database %s to %s: %w", model.SchemaName, model.Grantee, model.GroupName, model.Database.Identifier(), model.Privilege, err)
+	}
+	return nil
+}
+
+func (t *TaskRunnerImpl) DowngradeAccess(model *redshift.GrantsModel) error {
+	t.log.Info(fmt.Sprintf("DowngradeAccess (%s.%s) %s->%s %s", model.Database.ClusterIdentifier, model.Database.Name, model.GroupName, model.SchemaName, model.Privilege))
+
+	client, err := t.clientPool.GetDatabaseClient(model.Database.ClusterIdentifier, model.Database.Name)
+
+	if err != nil {
+		return err
+	}
+	err = client.Downgrade(model.Grantee.Clause(model.GroupName), model.SchemaName, model.Privilege)
+
+	if err != nil {
+		return fmt.Errorf("failed to downgrade acccess to schema %s for %s %s on database %s to %s: %w", model.SchemaName, model.Grantee, model.GroupName, model.Database.Identifier(), model.Privilege, err)
 	}
 	return nil
 }
@@ -210,3 +298,69 @@ func (t *TaskRunnerImpl) RemoveFromGroup(model *redshift.MembershipModel) error
 	}
 	return nil
 }
+
+func (t *TaskRunnerImpl) CreateRole(model *redshift.RoleModel) error {
+	t.log.Info(fmt.Sprintf("CreateRole (%s) %s", model.ClusterIdentifier, model.Role.Name))
+
+	client, err := t.clientPool.GetClusterClient(model.ClusterIdentifier)
+
+	if err != nil {
+		return err
+	}
+
+	err = client.CreateRole(model.Role.Name)
+
+	if err != nil {
+		return fmt.Errorf("failed to create role %s in %s: %w", model.Role.Name, model.ClusterIdentifier, err)
+	}
+
+	return nil
+}
+
+func (t *TaskRunnerImpl) DropRole(model *redshift.RoleModel) error {
+	t.log.Info(fmt.Sprintf("DropRole (%s) %s", model.ClusterIdentifier, model.Role.Name))
+
+	client, err := t.clientPool.GetClusterClient(model.ClusterIdentifier)
+
+	if err != nil {
+		return err
+	}
+	err = client.DeleteRole(model.Role.Name)
+
+	if err != nil {
+		return fmt.Errorf("unable to delete role %s in %s: %w", model.Role.Name, model.ClusterIdentifier, err)
+	}
+	return nil
+}
+
+func (t *TaskRunnerImpl) GrantRole(model *redshift.RoleGrantModel) error {
+	t.log.Info(fmt.Sprintf("GrantRole (%s) %s->%s", model.ClusterIdentifier, model.GranteeName, model.RoleName))
+
+	client, err := t.clientPool.GetClusterClient(model.ClusterIdentifier)
+
+	if err != nil {
+		return err
+	}
+	err = client.GrantRole(model.RoleName, model.Grantee.Clause(model.GranteeName))
+
+	if err != nil {
+		return fmt.Errorf("unable to grant role %s to %s %s in %s: %w", model.RoleName, model.Grantee, model.GranteeName, model.ClusterIdentifier, err)
+	}
+	return nil
+}
+
+func (t *TaskRunnerImpl) RevokeRole(model *redshift.RoleGrantModel) error {
+	t.log.Info(fmt.Sprintf("RevokeRole (%s) %s->%s", model.ClusterIdentifier, model.GranteeName, model.RoleName))
+
+	client, err := t.clientPool.GetClusterClient(model.ClusterIdentifier)
+
+	if err != nil {
+		return err
+	}
+	err = client.RevokeRole(model.RoleName, model.Grantee.Clause(model.GranteeName))
+
+	if err != nil {
+		return fmt.Errorf("unable to revoke role %s from %s %s in %s: %w", model.RoleName, model.Grantee, model.GranteeName, model.ClusterIdentifier, err)
+	}
+	return nil
+}
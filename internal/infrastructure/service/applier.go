@@ -1,10 +1,17 @@
 package service
 
 import (
+	"fmt"
 	"github.com/go-logr/logr"
+	googleCore "github.com/lunarway/hubble-rbac-controller/internal/core/google"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	iamCore "github.com/lunarway/hubble-rbac-controller/internal/core/iam"
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
 	"github.com/lunarway/hubble-rbac-controller/internal/core/resolver"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"sync"
+	"time"
 )
 
 type IamEventRecorder struct {
@@ -19,61 +26,180 @@ func NewIamLogger(logger logr.Logger) *IamEventRecorder {
 	return &IamEventRecorder{logger: logger}
 }
 
+// BackendError is returned by the Applier when the model could not be applied to one of the backends.
+// The backends are applied in order (Redshift, IAM, Google) so the backends following the failed one have not been applied.
+type BackendError struct {
+	Backend report.Backend
+	Err     error
+}
+
+func (e *BackendError) Error() string {
+	return fmt.Sprintf("failed to apply %s model: %v", e.Backend, e.Err)
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
 type Applier struct {
 	resolver        *resolver.Resolver
 	googleApplier   GoogleApplier
 	redshiftApplier RedshiftApplier
 	iamApplier      *iam.Applier
+	groupDirectory  hubble.GroupDirectory
+	usernameRule    *hubble.UsernameRule
 	logger          logr.Logger
+	lock            sync.Mutex //the applier is shared by the controllers of all the kinds, the applies are serialized so they don't race on the backends
 }
 
 func NewApplier(
 	iamApplier *iam.Applier,
 	googleApplier GoogleApplier,
 	redshiftApplier RedshiftApplier,
+	groupDirectory hubble.GroupDirectory,
+	usernameRule *hubble.UsernameRule,
+	roleBasedAccessControl bool,
 	logger logr.Logger) *Applier {
 
 	return &Applier{
-		resolver:        &resolver.Resolver{},
+		resolver:        &resolver.Resolver{RoleBasedAccessControl: roleBasedAccessControl},
 		redshiftApplier: redshiftApplier,
 		iamApplier:      iamApplier,
 		googleApplier:   googleApplier,
+		groupDirectory:  groupDirectory,
+		usernameRule:    usernameRule,
 		logger:          logger,
 	}
 }
 
-func (applier *Applier) Apply(model hubble.Model, dryRun bool) error {
+var errNoGroupDirectory = fmt.Errorf("the roles reference google groups, but no google directory has been configured")
+
+func referencesGroups(model hubble.Model) bool {
+	for _, role := range model.Roles {
+		if len(role.GoogleGroups) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the members of the google groups referenced by the roles of the model, by the email of the group, or nil if no groups are referenced.
+// The errors are not BackendErrors, as the groups are looked up before any backend is applied.
+func (applier *Applier) GroupMembers(model hubble.Model) (map[string][]string, error) {
+
+	if !referencesGroups(model) {
+		return nil, nil
+	}
+
+	if applier.groupDirectory == nil {
+		return nil, errNoGroupDirectory
+	}
+
+	return model.GroupMembers(applier.groupDirectory)
+}
+
+// Adds the members of the google groups referenced by the roles to the model.
+// The errors are not BackendErrors, as the groups are expanded before any backend is applied.
+func (applier *Applier) expandGroups(model *hubble.Model) error {
+
+	if !referencesGroups(*model) {
+		return nil
+	}
+
+	if applier.groupDirectory == nil {
+		return errNoGroupDirectory
+	}
+
+	err := model.ExpandGroups(applier.groupDirectory, applier.usernameRule)
+	if err != nil {
+		return fmt.Errorf("unable to expand the google groups: %w", err)
+	}
+	return nil
+}
+
+// Applies the hubble model to all backends and returns a report of all the actions that were planned or executed.
+// The report is returned even if the apply fails, so the caller can see how far it got.
+func (applier *Applier) Apply(model hubble.Model, dryRun bool) (*report.ApplyReport, error) {
 
 	applier.logger.Info("Received hubble model", "model", model)
 
+	err := applier.expandGroups(&model)
+	if err != nil {
+		return report.New(), err
+	}
+
+	redshiftModel, iamModel, googleModel := applier.resolver.Resolve(model)
+
+	return applier.apply(redshiftModel, iamModel, googleModel, dryRun)
+}
+
+// Applies the hubble model and removes what the removed model has granted but the model doesn't, e.g. when one of several merged models is deleted.
+// The clusters and google users of the removed model are reconciled even when the model no longer mentions them, so their managed users and roles are revoked.
+func (applier *Applier) ApplyRemoving(model hubble.Model, removed hubble.Model, dryRun bool) (*report.ApplyReport, error) {
+
+	applier.logger.Info("Removing hubble model", "model", removed)
+
+	err := applier.expandGroups(&model)
+	if err != nil {
+		return report.New(), err
+	}
+	err = applier.expandGroups(&removed)
+	if err != nil {
+		return report.New(), err
+	}
+
 	redshiftModel, iamModel, googleModel := applier.resolver.Resolve(model)
+	removedRedshiftModel, _, removedGoogleModel := applier.resolver.Resolve(removed)
+
+	for _, cluster := range removedRedshiftModel.Clusters {
+		redshiftModel.DeclareCluster(cluster.Identifier)
+	}
+
+	for _, user := range removedGoogleModel.Users {
+		googleModel.DeclareUser(user.Email)
+	}
+
+	return applier.apply(redshiftModel, iamModel, googleModel, dryRun)
+}
+
+func (applier *Applier) apply(redshiftModel redshiftCore.Model, iamModel iamCore.Model, googleModel googleCore.Model, dryRun bool) (*report.ApplyReport, error) {
+
+	applier.lock.Lock()
+	defer applier.lock.Unlock()
+
+	applyReport := report.New()
 
 	applier.logger.Info("Applying redshift model", "model", redshiftModel)
-	err := applier.redshiftApplier.Apply(redshiftModel, dryRun)
+	start := time.Now()
+	redshiftReport, err := applier.redshiftApplier.Apply(redshiftModel, dryRun)
+	applyReport.RecordDuration(report.Redshift, time.Since(start))
+	applyReport.Merge(redshiftReport)
 
 	if err != nil {
-		return err
+		return applyReport, &BackendError{Backend: report.Redshift, Err: err}
 	}
 
 	applier.logger.Info("Applying IAM model", "model", iamModel)
-	if !dryRun {
-		err = applier.iamApplier.Apply(iamModel)
+	start = time.Now()
+	iamReport, err := applier.iamApplier.Apply(iamModel, dryRun)
+	applyReport.RecordDuration(report.IAM, time.Since(start))
+	applyReport.Merge(iamReport)
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return applyReport, &BackendError{Backend: report.IAM, Err: err}
 	}
 
 	applier.logger.Info("Applying Google model", "model", googleModel)
-	if !dryRun {
-		err = applier.googleApplier.Apply(googleModel)
+	start = time.Now()
+	googleReport, err := applier.googleApplier.Apply(googleModel, dryRun)
+	applyReport.RecordDuration(report.Google, time.Since(start))
+	applyReport.Merge(googleReport)
 
-		if err != nil {
-			return err
-		}
+	if err != nil {
+		return applyReport, &BackendError{Backend: report.Google, Err: err}
 	}
 
 	applier.logger.Info("All changes have been applied")
 
-	return nil
+	return applyReport, nil
 }
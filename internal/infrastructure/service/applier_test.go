@@ -5,6 +5,7 @@ package service
 import (
 	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
 	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
@@ -41,6 +42,16 @@ func failOnError(err error) {
 	}
 }
 
+func iamEvents(applyReport *report.ApplyReport, eventType iam.ApplyEventType) int {
+	result := 0
+	for _, action := range applyReport.ForBackend(report.IAM) {
+		if action.Type == eventType.ToString() && action.State == report.Executed {
+			result += 1
+		}
+	}
+	return result
+}
+
 func setUp() {
 
 	session := iam.LocalStackSessionFactory{}.CreateSession()
@@ -86,13 +97,13 @@ func TestApplier_Apply(t *testing.T) {
 	excludedUsers := []string{"lunarway"}
 	excludedDatabases := []string{"template0", "template1", "postgres", "padb_harvest"}
 	clientGroup := redshift.NewClientGroupForTest(&localhostCredentials)
-	redshiftApplier := redshift.NewApplier(clientGroup, redshiftCore.NewExclusions(excludedDatabases, excludedUsers), accountId, logger, redshiftCore.DefaultReconcilerConfig())
+	redshiftApplier := redshift.NewApplier(clientGroup, redshiftCore.NewExclusions(excludedDatabases, excludedUsers), accountId, logger, redshiftCore.DefaultReconcilerConfig(), redshiftCore.DefaultDagRunnerConfig(), false)
 
 	googleApplier := google.NewNoOpApplier()
 
 	session := iam.LocalStackSessionFactory{}.CreateSession()
 	iamClient := iam.New(session)
-	iamApplier := iam.NewApplier(iamClient, accountId, region, &IamEventRecorder{logger: logger}, logger)
+	iamApplier := iam.NewApplier(iamClient, accountId, region, iam.DefaultSamlConfig(), &IamEventRecorder{logger: logger}, logger)
 
 	redshiftExpected := redshift.NewRedshiftState()
 	redshiftExpected.Users = []string{"lunarway"}
@@ -100,21 +111,21 @@ func TestApplier_Apply(t *testing.T) {
 
 	iamExpected := iam.IAMState{}
 
-	applier := NewApplier(iamApplier, googleApplier, redshiftApplier, logger)
+	applier := NewApplier(iamApplier, googleApplier, redshiftApplier, nil, nil, false, logger)
 
 	redshiftModel := redshiftCore.Model{}
 	redshiftModel.DeclareCluster("hubble")
-	err := redshiftApplier.Apply(redshiftModel, false)
+	_, err := redshiftApplier.Apply(redshiftModel, false)
 	failOnError(err)
 
 	model := hubble.Model{}
 	user := model.AddUser("jwr", "jwr@lunar.app")
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	log.Info("Create database")
 	database := model.AddDatabase("hubble", "prod")
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftClient, err := redshift.NewClient(
@@ -135,7 +146,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Create role")
 	role := model.AddRole("BiAnalyst", []hubble.DataSet{"public_bi"})
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftActual = redshift.FetchState(redshiftClient)
@@ -146,7 +157,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Grant role access to database")
 	role.GrantAccess(database)
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftActual = redshift.FetchState(redshiftClient)
@@ -156,9 +167,13 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Assign user to role")
 	user.Assign(role)
-	err = applier.Apply(model, false)
+	applyReport, err := applier.Apply(model, false)
 	failOnError(err)
 
+	assert.False(applyReport.HasFailures())
+	assert.NotEmpty(applyReport.ForBackend(report.Redshift), "redshift tasks are reported")
+	assert.Equal(1, iamEvents(applyReport, iam.PolicyCreated), "the login policy is reported as created")
+
 	redshiftExpected.Users = []string{"lunarway", "jwr_bianalyst"}
 	redshiftExpected.Groups = []string{"bianalyst"}
 	redshiftExpected.GroupMemberships = map[string][]string{"lunarway": {}, "jwr_bianalyst": {"bianalyst"}}
@@ -172,7 +187,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Revoke access")
 	role.RevokeAccess(database)
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftExpected = redshift.NewRedshiftState()
@@ -189,7 +204,7 @@ func TestApplier_Apply(t *testing.T) {
 
 	log.Info("Unassign user from role")
 	user.Unassign(role)
-	err = applier.Apply(model, false)
+	_, err = applier.Apply(model, false)
 	failOnError(err)
 
 	redshiftActual = redshift.FetchState(redshiftClient)
@@ -197,3 +212,65 @@ func TestApplier_Apply(t *testing.T) {
 	iamActual = iam.FetchIAMState(iamClient)
 	iam.AssertState(assert, iamActual, iamExpected, "IAM policy for jwr is still detached from role")
 }
+
+func TestApplier_Remove(t *testing.T) {
+
+	setUp()
+
+	assert := assert.New(t)
+
+	logger := infrastructure.NewLogger(t)
+
+	excludedUsers := []string{"lunarway"}
+	excludedDatabases := []string{"template0", "template1", "postgres", "padb_harvest"}
+	clientGroup := redshift.NewClientGroupForTest(&localhostCredentials)
+	redshiftApplier := redshift.NewApplier(clientGroup, redshiftCore.NewExclusions(excludedDatabases, excludedUsers), accountId, logger, redshiftCore.DefaultReconcilerConfig(), redshiftCore.DefaultDagRunnerConfig(), false)
+
+	session := iam.LocalStackSessionFactory{}.CreateSession()
+	iamClient := iam.New(session)
+	iamApplier := iam.NewApplier(iamClient, accountId, region, iam.DefaultSamlConfig(), &IamEventRecorder{logger: logger}, logger)
+
+	applier := NewApplier(iamApplier, google.NewNoOpApplier(), redshiftApplier, nil, nil, false, logger)
+
+	model := hubble.Model{}
+	user := model.AddUser("jwr", "jwr@lunar.app")
+	database := model.AddDatabase("hubble", "prod")
+	role := model.AddRole("BiAnalyst", []hubble.DataSet{"public_bi"})
+	role.GrantAccess(database)
+	user.Assign(role)
+
+	_, err := applier.Apply(model, false)
+	failOnError(err)
+
+	log.Info("Remove model")
+	//removing the only model, like when the last CR is deleted, removes everything it has granted
+	applyReport, err := applier.ApplyRemoving(hubble.Model{}, model, false)
+	failOnError(err)
+
+	assert.False(applyReport.HasFailures())
+	assert.Equal(1, iamEvents(applyReport, iam.RoleDeleted), "the role is reported as deleted")
+
+	redshiftClient, err := redshift.NewClient(
+		localhostCredentials.Username,
+		localhostCredentials.Password,
+		localhostCredentials.Host,
+		"prod",
+		localhostCredentials.Sslmode,
+		localhostCredentials.Port,
+		localhostCredentials.ExternalSchemasSupported,
+	)
+	failOnError(err)
+
+	redshiftExpected := redshift.NewRedshiftState()
+	redshiftExpected.Users = []string{"lunarway"}
+	redshiftExpected.GroupMemberships = map[string][]string{"lunarway": {}}
+	redshiftExpected.Groups = []string{}
+	redshiftExpected.Grants = map[string][]string{}
+	redshiftActual := redshift.FetchState(redshiftClient)
+	redshift.AssertState(assert, redshiftActual, redshiftExpected, "the managed users, groups and grants have been removed, the excluded user is left")
+
+	iamExpected := iam.IAMState{}
+	iamExpected.Roles = map[string][]string{}
+	iamActual := iam.FetchIAMState(iamClient)
+	iam.AssertState(assert, iamActual, iamExpected, "the IAM role has been deleted")
+}
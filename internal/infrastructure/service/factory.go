@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
+	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/redshift"
+	"github.com/lunarway/hubble-rbac-controller/pkg/configuration"
+	"io/ioutil"
+)
+
+// The redshift users and databases that are never managed by the controller
+func Exclusions() *redshiftCore.Exclusions {
+
+	excludedUsers := []string{
+		"produser",
+		"devuser",
+		"dev",
+		"inspari",
+		"looker",
+		"rdsdb",
+	}
+	//these databases come baked into a redshift cluster, and we don't want to manage those
+	excludedDatabases := []string{"template0", "template1", "postgres", "padb_harvest"}
+
+	return redshiftCore.NewExclusions(excludedDatabases, excludedUsers)
+}
+
+// The config the redshift model is reconciled with
+func ReconcilerConfig() redshiftCore.ReconcilerConfig {
+	//for some reason revoking access to the public schema in Redshift has no effect, so every reconcile would try to revoke access to all public schemas (so we skip it)
+	return redshiftCore.ReconcilerConfig{RevokeAccessToPublicSchema: false}
+}
+
+// Creates an Applier that applies the model to the Redshift clusters, the AWS account and the Google directory given by the configuration
+func CreateApplier(conf configuration.Configuration, logger logr.Logger) (*Applier, error) {
+
+	redshiftCredentials := redshift.ClusterCredentials{
+		Username:                 conf.RedshiftUsername,
+		Password:                 conf.RedshiftPassword,
+		MasterDatabase:           conf.RedshiftMasterDatabase,
+		Host:                     conf.RedshiftHostTemplate,
+		Sslmode:                  "require",
+		Port:                     5439,
+		ExternalSchemasSupported: true,
+	}
+
+	clientGroup := redshift.NewClientGroup(&redshiftCredentials)
+
+	config := ReconcilerConfig()
+	dagRunnerConfig := redshiftCore.DagRunnerConfig{
+		MaxConcurrency:           conf.RedshiftMaxConcurrency,
+		MaxConcurrencyPerCluster: conf.RedshiftMaxConcurrencyPerCluster,
+	}
+	redshiftApplier := redshift.NewApplier(clientGroup, Exclusions(), conf.AwsAccountId, logger, config, dagRunnerConfig, conf.RedshiftRoleBasedAccessControl)
+
+	session := iam.AwsSessionFactory{}.CreateSession()
+	iamClient := iam.New(session)
+	saml := iam.SamlConfig{
+		ProviderName: conf.SamlProviderName,
+		Audience:     conf.SamlAudience,
+	}
+	if conf.SamlTrustConditions != "" {
+		err := json.Unmarshal([]byte(conf.SamlTrustConditions), &saml.Conditions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SAML trust conditions: %w", err)
+		}
+	}
+	iamApplier := iam.NewApplier(iamClient, conf.AwsAccountId, conf.Region, saml, NewIamLogger(logger), logger)
+
+	jsonCredentials, err := ioutil.ReadFile(conf.GoogleCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load google credentials: %v", err)
+	}
+	googleClient, err := google.NewGoogleClient(jsonCredentials, conf.GoogleAdminPrincipalEmail, conf.AwsAccountId, conf.SamlProviderName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize google client: %v", err)
+	}
+	googleApplier := google.NewApplier(googleClient)
+
+	usernameRule, err := hubble.NewUsernameRule(conf.GoogleGroupUsernamePattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewApplier(iamApplier, googleApplier, redshiftApplier, googleClient, usernameRule, conf.RedshiftRoleBasedAccessControl, logger), nil
+}
@@ -1,7 +1,10 @@
 package service
 
-import googleCore "github.com/lunarway/hubble-rbac-controller/internal/core/google"
+import (
+	googleCore "github.com/lunarway/hubble-rbac-controller/internal/core/google"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+)
 
 type GoogleApplier interface {
-	Apply(model googleCore.Model) error
+	Apply(model googleCore.Model, dryRun bool) (*report.ApplyReport, error)
 }
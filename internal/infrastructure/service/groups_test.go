@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeDirectory map[string][]string
+
+func (d fakeDirectory) GroupMembers(groupEmail string) ([]string, error) {
+	members, ok := d[groupEmail]
+	if !ok {
+		return nil, fmt.Errorf("no such group: %s", groupEmail)
+	}
+	return members, nil
+}
+
+func modelWithGroup(group string) hubble.Model {
+	model := hubble.Model{}
+	model.AddRole("bianalyst", []hubble.DataSet{"bi"}).GoogleGroups = []string{group}
+	model.AddUser("jwr", "jwr@lunar.app")
+	return model
+}
+
+func TestApplier_ExpandGroups_Errors(t *testing.T) {
+
+	rule, _ := hubble.NewUsernameRule(hubble.DefaultUsernamePattern)
+	directory := fakeDirectory{"bi@lunar.app": {"jwr@other.com"}}
+
+	testCases := []struct {
+		name      string
+		directory hubble.GroupDirectory
+		group     string
+	}{
+		{name: "no directory", group: "bi@lunar.app"},
+		{name: "failed lookup", directory: directory, group: "missing@lunar.app"},
+		{name: "username collision", directory: directory, group: "bi@lunar.app"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			applier := &Applier{groupDirectory: tc.directory, usernameRule: rule}
+			model := modelWithGroup(tc.group)
+
+			err := applier.expandGroups(&model)
+
+			var backendError *BackendError
+			assert.Error(t, err)
+			assert.False(t, errors.As(err, &backendError), "the groups are expanded before any backend is applied")
+		})
+	}
+}
+
+func TestApplier_GroupMembers(t *testing.T) {
+
+	applier := &Applier{groupDirectory: fakeDirectory{"bi@lunar.app": {"kni@lunar.app", "jwr@lunar.app"}}}
+
+	members, err := applier.GroupMembers(modelWithGroup("bi@lunar.app"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"bi@lunar.app": {"jwr@lunar.app", "kni@lunar.app"}}, members)
+
+	members, err = (&Applier{}).GroupMembers(hubble.Model{})
+	assert.NoError(t, err)
+	assert.Nil(t, members, "the directory isn't needed if no groups are referenced")
+}
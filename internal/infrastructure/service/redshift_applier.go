@@ -1,7 +1,10 @@
 package service
 
-import redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+import (
+	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/report"
+)
 
 type RedshiftApplier interface {
-	Apply(model redshiftCore.Model, dryRun bool) error
+	Apply(model redshiftCore.Model, dryRun bool) (*report.ApplyReport, error)
 }
@@ -18,13 +18,8 @@ package main
 
 import (
 	"flag"
-	"fmt"
-	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/google"
-	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/iam"
-	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/redshift"
 	"github.com/lunarway/hubble-rbac-controller/internal/infrastructure/service"
 	"github.com/lunarway/hubble-rbac-controller/pkg/configuration"
-	"io/ioutil"
 	"os"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -35,10 +30,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	hubblev1alpha1 "github.com/lunarway/hubble-rbac-controller/api/v1alpha1"
+	hubblev1beta1 "github.com/lunarway/hubble-rbac-controller/api/v1beta1"
 	"github.com/lunarway/hubble-rbac-controller/controllers"
 	// +kubebuilder:scaffold:imports
 
-	redshiftCore "github.com/lunarway/hubble-rbac-controller/internal/core/redshift"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -51,59 +46,12 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(hubblev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(hubblev1beta1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 var log = logf.Log.WithName("controller_hubblerbac")
 
-func createApplier(conf configuration.Configuration) (*service.Applier, error) {
-
-	excludedUsers := []string{
-		"produser",
-		"devuser",
-		"dev",
-		"inspari",
-		"looker",
-		"rdsdb",
-	}
-	//these databases come baked into a redshift cluster, and we don't want to manage those
-	excludedDatabases := []string{"template0", "template1", "postgres", "padb_harvest"}
-
-	redshiftCredentials := redshift.ClusterCredentials{
-		Username:                 conf.RedshiftUsername,
-		Password:                 conf.RedshiftPassword,
-		MasterDatabase:           conf.RedshiftMasterDatabase,
-		Host:                     conf.RedshiftHostTemplate,
-		Sslmode:                  "require",
-		Port:                     5439,
-		ExternalSchemasSupported: true,
-	}
-
-	clientGroup := redshift.NewClientGroup(&redshiftCredentials)
-
-	//for some reason revoking access to the public schema in Redshift has no effect, so every reconcile would try to revoke access to all public schemas (so we skip it)
-	config := redshiftCore.ReconcilerConfig{RevokeAccessToPublicSchema: false}
-	redshiftApplier := redshift.NewApplier(clientGroup, redshiftCore.NewExclusions(excludedDatabases, excludedUsers), conf.AwsAccountId, log, config)
-
-	session := iam.AwsSessionFactory{}.CreateSession()
-	iamClient := iam.New(session)
-	iamApplier := iam.NewApplier(iamClient, conf.AwsAccountId, conf.Region, service.NewIamLogger(log), log)
-
-	jsonCredentials, err := ioutil.ReadFile(conf.GoogleCredentials)
-	if err != nil {
-		return nil, fmt.Errorf("unable to load google credentials: %v", err)
-	}
-	googleClient, err := google.NewGoogleClient(jsonCredentials, conf.GoogleAdminPrincipalEmail, conf.AwsAccountId)
-	if err != nil {
-		return nil, fmt.Errorf("unable to initialize google client: %v", err)
-	}
-	googleApplier := google.NewApplier(googleClient)
-
-	applier := service.NewApplier(iamApplier, googleApplier, redshiftApplier, log)
-
-	return applier, nil
-}
-
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
@@ -133,22 +81,65 @@ func main() {
 		setupLog.Error(err, "unable to load configuration")
 	}
 
-	applier, err := createApplier(conf)
+	applier, err := service.CreateApplier(conf, log)
 
 	if err != nil {
 		setupLog.Error(err, "unable to create applier")
 	}
 
 	if err = (&controllers.HubbleRbacReconciler{
-		Client:  mgr.GetClient(),
-		Log:     ctrl.Log.WithName("controllers").WithName("HubbleRbac"),
-		Scheme:  mgr.GetScheme(),
-		Applier: applier,
-		DryRun:  conf.DryRun,
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("HubbleRbac"),
+		Scheme:   mgr.GetScheme(),
+		Applier:  applier,
+		Recorder: mgr.GetEventRecorderFor("hubble-rbac-controller"),
+		DryRun:   conf.DryRun,
+
+		ResyncInterval:       conf.ResyncInterval,
+		DriftReportOnly:      conf.DriftReportOnly,
+		GroupRefreshInterval: conf.GoogleGroupRefreshInterval,
+
+		RoleBasedAccessControl: conf.RedshiftRoleBasedAccessControl,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HubbleRbac")
 		os.Exit(1)
 	}
+	for _, kind := range []string{"HubbleUser", "HubbleRole", "HubbleDatabase", "HubblePolicy"} {
+		if err = (&controllers.HubbleObjectReconciler{
+			Client:   mgr.GetClient(),
+			Log:      ctrl.Log.WithName("controllers").WithName(kind),
+			Scheme:   mgr.GetScheme(),
+			Applier:  applier,
+			Recorder: mgr.GetEventRecorderFor("hubble-rbac-controller"),
+			DryRun:   conf.DryRun,
+			Kind:     kind,
+
+			ResyncInterval:       conf.ResyncInterval,
+			DriftReportOnly:      conf.DriftReportOnly,
+			GroupRefreshInterval: conf.GoogleGroupRefreshInterval,
+
+			RoleBasedAccessControl: conf.RedshiftRoleBasedAccessControl,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", kind)
+			os.Exit(1)
+		}
+	}
+	if conf.EnableWebhooks {
+		if err = (&controllers.HubbleRbacValidator{
+			Client:   mgr.GetClient(),
+			Excluded: service.Exclusions(),
+			Log:      ctrl.Log.WithName("webhooks").WithName("HubbleRbac"),
+
+			RoleBasedAccessControl: conf.RedshiftRoleBasedAccessControl,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "HubbleRbac")
+			os.Exit(1)
+		}
+		if err = hubblev1beta1.SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create conversion webhook")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
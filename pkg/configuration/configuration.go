@@ -2,8 +2,10 @@ package configuration
 
 import (
 	"fmt"
+	"github.com/lunarway/hubble-rbac-controller/internal/core/hubble"
 	"os"
 	"strconv"
+	"time"
 )
 
 type ErrorCollector struct {
@@ -26,15 +28,26 @@ func (e *ErrorCollector) Error() error {
 }
 
 type Configuration struct {
-	GoogleCredentials         string
-	RedshiftHostTemplate      string
-	RedshiftUsername          string
-	RedshiftPassword          string
-	RedshiftMasterDatabase    string
-	AwsAccountId              string
-	Region                    string
-	GoogleAdminPrincipalEmail string
-	DryRun                    bool
+	GoogleCredentials                string
+	RedshiftHostTemplate             string
+	RedshiftUsername                 string
+	RedshiftPassword                 string
+	RedshiftMasterDatabase           string
+	AwsAccountId                     string
+	Region                           string
+	GoogleAdminPrincipalEmail        string
+	DryRun                           bool
+	RedshiftMaxConcurrency           int
+	RedshiftMaxConcurrencyPerCluster int
+	EnableWebhooks                   bool
+	ResyncInterval                   time.Duration
+	DriftReportOnly                  bool
+	GoogleGroupUsernamePattern       string
+	GoogleGroupRefreshInterval       time.Duration
+	SamlProviderName                 string
+	SamlAudience                     string
+	SamlTrustConditions              string
+	RedshiftRoleBasedAccessControl   bool
 }
 
 func loadVariable(name string, errorCollector *ErrorCollector) string {
@@ -46,6 +59,14 @@ func loadVariable(name string, errorCollector *ErrorCollector) string {
 	return value
 }
 
+func loadStringWithDefault(name string, defaultValue string) string {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	return value
+}
+
 func loadBool(name string, errorCollector *ErrorCollector) bool {
 	value, ok := os.LookupEnv(name)
 	if !ok {
@@ -60,21 +81,81 @@ func loadBool(name string, errorCollector *ErrorCollector) bool {
 	return result
 }
 
+func loadBoolWithDefault(name string, defaultValue bool, errorCollector *ErrorCollector) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		errorCollector.Register(name)
+		return defaultValue
+	}
+	return result
+}
+
+func loadDurationWithDefault(name string, defaultValue time.Duration, errorCollector *ErrorCollector) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	result, err := time.ParseDuration(value)
+	if err != nil {
+		errorCollector.Register(name)
+		return defaultValue
+	}
+	return result
+}
+
+func loadIntWithDefault(name string, defaultValue int, errorCollector *ErrorCollector) int {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		errorCollector.Register(name)
+		return defaultValue
+	}
+	return result
+}
+
 func LoadConfiguration() (Configuration, error) {
 
 	errorCollector := &ErrorCollector{}
 
 	result := Configuration{
-		GoogleCredentials:         loadVariable("GOOGLE_CREDENTIALS_FILE_PATH", errorCollector),
-		RedshiftHostTemplate:      "%s." + loadVariable("REDSHIFT_HOST", errorCollector),
-		RedshiftUsername:          loadVariable("REDSHIFT_USERNAME", errorCollector),
-		RedshiftPassword:          loadVariable("REDSHIFT_PASSWORD", errorCollector),
-		RedshiftMasterDatabase:    loadVariable("REDSHIFT_MASTER_DATABASE", errorCollector),
-		AwsAccountId:              loadVariable("AWS_ACCOUNT_ID", errorCollector),
-		GoogleAdminPrincipalEmail: loadVariable("GOOGLE_ADMIN_PRINCIPAL_EMAIL", errorCollector),
-		Region:                    "eu-west-1",
-		DryRun:                    loadBool("DRYRUN", errorCollector),
+		GoogleCredentials:                loadVariable("GOOGLE_CREDENTIALS_FILE_PATH", errorCollector),
+		RedshiftHostTemplate:             "%s." + loadVariable("REDSHIFT_HOST", errorCollector),
+		RedshiftUsername:                 loadVariable("REDSHIFT_USERNAME", errorCollector),
+		RedshiftPassword:                 loadVariable("REDSHIFT_PASSWORD", errorCollector),
+		RedshiftMasterDatabase:           loadVariable("REDSHIFT_MASTER_DATABASE", errorCollector),
+		AwsAccountId:                     loadVariable("AWS_ACCOUNT_ID", errorCollector),
+		GoogleAdminPrincipalEmail:        loadVariable("GOOGLE_ADMIN_PRINCIPAL_EMAIL", errorCollector),
+		Region:                           "eu-west-1",
+		DryRun:                           loadBool("DRYRUN", errorCollector),
+		RedshiftMaxConcurrency:           loadIntWithDefault("REDSHIFT_MAX_CONCURRENCY", 1, errorCollector),
+		RedshiftMaxConcurrencyPerCluster: loadIntWithDefault("REDSHIFT_MAX_CONCURRENCY_PER_CLUSTER", 0, errorCollector),
+		EnableWebhooks:                   loadBoolWithDefault("ENABLE_WEBHOOKS", false, errorCollector),
+		ResyncInterval:                   loadDurationWithDefault("RESYNC_INTERVAL", 0, errorCollector),
+		DriftReportOnly:                  loadBoolWithDefault("DRIFT_REPORT_ONLY", false, errorCollector),
+		GoogleGroupUsernamePattern:       loadStringWithDefault("GOOGLE_GROUP_USERNAME_PATTERN", hubble.DefaultUsernamePattern),
+		GoogleGroupRefreshInterval:       loadDurationWithDefault("GOOGLE_GROUP_REFRESH_INTERVAL", 15*time.Minute, errorCollector),
+		SamlProviderName:                 loadStringWithDefault("SAML_PROVIDER_NAME", "GoogleApps"),
+		SamlAudience:                     loadStringWithDefault("SAML_AUDIENCE", "https://signin.aws.amazon.com/saml"),
+		SamlTrustConditions:              loadStringWithDefault("SAML_TRUST_CONDITIONS", ""),
+		RedshiftRoleBasedAccessControl:   loadBoolWithDefault("REDSHIFT_ROLE_BASED_ACCESS_CONTROL", false, errorCollector),
 	}
 
 	return result, errorCollector.Error()
 }
+
+// Loads the configuration that changes how the model is resolved, which is all an offline plan needs, so it doesn't require the credentials of the controller
+func LoadRoleBasedAccessControl() (bool, error) {
+
+	errorCollector := &ErrorCollector{}
+
+	result := loadBoolWithDefault("REDSHIFT_ROLE_BASED_ACCESS_CONTROL", false, errorCollector)
+
+	return result, errorCollector.Error()
+}